@@ -1,7 +1,35 @@
 package main
 
-import "forum_app/internal/app"
+import (
+	"flag"
+	"forum_app/internal/app"
+	"forum_app/internal/config"
+	"log"
+	"os"
+)
 
 func main() {
-	app.Run()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+	if cfg.PrintConfig {
+		if err = cfg.Print(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			app.Migrate(cfg, args[1:])
+			return
+		case "role":
+			app.SetRole(cfg, args[1:])
+			return
+		}
+	}
+	app.Run(cfg)
 }
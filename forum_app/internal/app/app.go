@@ -1,24 +1,27 @@
 package app
 
 import (
+	"context"
+	"forum_app/internal/config"
+	"forum_app/pkg/logger"
+	"forum_app/pkg/metrics"
 	"io"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Run() {
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Llongfile)
-	f, err := os.OpenFile("log.txt", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		errLog.Println("Log file doesn't open")
+func Run(cfg *config.Config) {
+	logs, file := newLogger(cfg)
+	if file != nil {
+		defer file.Close()
 	}
-	defer f.Close()
-	wrt := io.MultiWriter(os.Stderr, f)
-	errLog.SetOutput(wrt)
-	infoLog.SetOutput(wrt)
-	h := NewHandler(errLog, infoLog)
+	infoLog, errLog := logs.Std(logger.LevelInfo), logs.Std(logger.LevelError)
+	duration = time.Duration(cfg.Timeout)
+	reg := metrics.NewRegistry()
+	h := NewHandler(cfg, logs, reg)
 	mux := http.NewServeMux()
 	// get
 	mux.HandleFunc("/users", h.UsersAllHandler)
@@ -30,6 +33,10 @@ func Run() {
 	mux.HandleFunc("/comment_reactions", h.CommentReactionHandler)
 	mux.HandleFunc("/category", h.CategoryPostsHandler)
 	mux.HandleFunc("/categories", h.CategoriesHandler)
+	mux.HandleFunc("/search", h.SearchHandler)
+	mux.HandleFunc("/moderation/queue", h.ModerationQueueHandler)
+	mux.HandleFunc("/notifications", h.NotificationsHandler)
+	mux.HandleFunc("/notifications/unread", h.UnreadNotificationsHandler)
 
 	// post
 	mux.HandleFunc("/user/save", h.StoreUserHandler)
@@ -37,21 +44,67 @@ func Run() {
 	mux.HandleFunc("/post_reactions/save", h.StorePostReactionHandler)
 	mux.HandleFunc("/comments/save", h.StoreCommentHandler)
 	mux.HandleFunc("/comment_reactions/save", h.StoreCommentReactionHandler)
+	mux.HandleFunc("/moderation/action", h.ModerationActionHandler)
+	mux.HandleFunc("/reports/save", h.StoreReportHandler)
+	mux.HandleFunc("/category/save", h.StoreCategoryHandler)
 
 	// put
+	mux.HandleFunc("/post/update", h.UpdatePostHandler)
+	mux.HandleFunc("/user/role", h.UpdateRoleHandler)
+	mux.HandleFunc("/user/password", h.UpdatePasswordHandler)
+	mux.HandleFunc("/user/verify", h.VerifyEmailHandler)
+	mux.HandleFunc("/category/update", h.UpdateCategoryHandler)
+	mux.HandleFunc("/category/reorder", h.ReorderCategoriesHandler)
+	mux.HandleFunc("/category/archive", h.ArchiveCategoryHandler)
+	mux.HandleFunc("/notifications/read", h.ReadNotificationHandler)
+	mux.HandleFunc("/notifications/read_all", h.ReadAllNotificationsHandler)
 	mux.HandleFunc("/post_reactions/update", h.UpdatePostReactionHandler)
 	mux.HandleFunc("/comment_reactions/update", h.UpdateCommentReactionHandler)
 
 	// delete
+	mux.HandleFunc("/post/delete", h.DeletePostHandler)
 	mux.HandleFunc("/comment_reactions/delete", h.DeleteCommentReactionHandler)
 	mux.HandleFunc("/post_reactions/delete", h.DeletePostReactionHandler)
+
+	// probes and metrics
+	mux.HandleFunc("/healthz", h.HealthzHandler)
+	mux.HandleFunc("/readyz", h.ReadyzHandler)
+	mux.Handle("/metrics", reg.Handler())
 	srv := &http.Server{
-		Addr:     ":8080",
+		Addr:     cfg.Addr,
 		ErrorLog: errLog,
-		Handler:  mux,
+		Handler:  logs.Middleware(reg.InstrumentMux(mux)),
+	}
+	go func() {
+		infoLog.Println("Listening on " + cfg.Addr)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			errLog.Fatal(err)
+		}
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	infoLog.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		errLog.Println(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	if err := h.Close(ctx); err != nil {
+		errLog.Println(err)
 	}
+}
 
-	infoLog.Println("Listening on localhost:8080")
-	err = srv.ListenAndServe()
-	errLog.Fatal(err)
+// newLogger logs to stderr and to the log file, which is rotated, or only to stderr when the file can't be opened
+func newLogger(cfg *config.Config) (*logger.Logger, io.Closer) {
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	file, err := logger.OpenRotatingFile(cfg.LogFile, int64(cfg.LogMaxSize)<<20, cfg.LogMaxBackups)
+	if err != nil {
+		logs := logger.New(os.Stderr, level).With("service", "forum_app")
+		logs.Error(context.Background(), "Log file doesn't open", "error", err)
+		return logs, nil
+	}
+	return logger.New(io.MultiWriter(os.Stderr, file), level).With("service", "forum_app"), file
 }
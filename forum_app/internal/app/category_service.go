@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_app/internal/entity"
+	"net/http"
+	"strings"
+)
+
+func (h *Handler) StoreCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var change entity.CategoryChange
+	err := json.NewDecoder(r.Body).Decode(&change)
+	change.Title = strings.TrimSpace(change.Title)
+	if err != nil || change.AdminId == 0 || change.Title == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	resChan := make(chan entity.Result, 1)
+	var res entity.Result
+	h.background(func() { h.kcase.Store(ctx, change, resChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case res = <-resChan:
+		if err = res.Err; err != nil {
+			h.logError(ctx, err)
+			h.categoryErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusCreated, entity.Response{Body: entity.Category{Id: int(res.Id)}})
+}
+
+func (h *Handler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.kcase.Update, func(change entity.CategoryChange) bool {
+		return change.Id != 0 && change.Title != ""
+	})
+}
+
+func (h *Handler) ReorderCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.kcase.Reorder, func(change entity.CategoryChange) bool {
+		return len(change.Order) != 0
+	})
+}
+
+func (h *Handler) ArchiveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.kcase.Archive, func(change entity.CategoryChange) bool {
+		return change.Id != 0
+	})
+}
+
+// changeCategory serves the PUT endpoints that modify existing categories
+func (h *Handler) changeCategory(w http.ResponseWriter, r *http.Request, apply func(context.Context, entity.CategoryChange, chan error), valid func(entity.CategoryChange) bool) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPut {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var change entity.CategoryChange
+	err := json.NewDecoder(r.Body).Decode(&change)
+	change.Title = strings.TrimSpace(change.Title)
+	if err != nil || change.AdminId == 0 || !valid(change) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, change, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.categoryErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func (h *Handler) categoryErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrCategoryNotFound:
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+	case entity.ErrForbidden:
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
+	case entity.ErrCategoryExists:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Category with a given title already exists"})
+	case entity.ErrCategoryParent:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Category can't be nested under itself"})
+	case entity.ErrCategoryOrder:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Order must list every subcategory once"})
+	default:
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+	}
+}
@@ -13,32 +13,39 @@ func (h *Handler) StoreCommentHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var comment entity.Comment
 	err := json.NewDecoder(r.Body).Decode(&comment)
 	if err != nil || !validateCommentData(comment) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	resChan := make(chan entity.Result)
+	resChan := make(chan entity.Result, 1)
 	var result entity.Result
-	go h.ccase.Store(ctx, comment, resChan)
+	h.background(func() { h.ccase.Store(ctx, comment, resChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case result = <-resChan:
-		if result.Err != nil {
-			h.errLog.Println(err)
-			if isConstraintError(err) {
+		if err = result.Err; err != nil {
+			h.logError(ctx, err)
+			switch {
+			case isConstraintError(err) || err == entity.ErrInvalidParent:
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
+			case err == entity.ErrPostNotFound:
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+				return
+			case err == entity.ErrPostLocked:
+				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Post is locked"})
+				return
 			}
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
@@ -62,28 +69,28 @@ func (h *Handler) StoreCommentReactionHandler(w http.ResponseWriter, r *http.Req
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var comment_reaction entity.CommentReaction
 	err := json.NewDecoder(r.Body).Decode(&comment_reaction)
 	if err != nil || !validateCommentReactionData(comment_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{})
 		return
 	}
-	errChan := make(chan error)
-	go h.ccase.StoreCommentReaction(ctx, comment_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.ccase.StoreCommentReaction(ctx, comment_reaction, errChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -100,23 +107,23 @@ func (h *Handler) UpdateCommentReactionHandler(w http.ResponseWriter, r *http.Re
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPut {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var comment_reaction entity.CommentReaction
 	err := json.NewDecoder(r.Body).Decode(&comment_reaction)
 	if err != nil || !validateCommentReactionData(comment_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	errChan := make(chan error)
-	go h.ccase.UpdateCommentReaction(ctx, comment_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.ccase.UpdateCommentReaction(ctx, comment_reaction, errChan) })
 	select {
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) || isNoRowAffectedError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -126,7 +133,7 @@ func (h *Handler) UpdateCommentReactionHandler(w http.ResponseWriter, r *http.Re
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	}
@@ -137,28 +144,28 @@ func (h *Handler) DeleteCommentReactionHandler(w http.ResponseWriter, r *http.Re
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodDelete {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var comment_reaction entity.CommentReaction
 	err := json.NewDecoder(r.Body).Decode(&comment_reaction)
 	if err != nil || !validateCommentReactionData(comment_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{})
 		return
 	}
-	errChan := make(chan error)
-	go h.ccase.DeleteCommentReaction(ctx, comment_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.ccase.DeleteCommentReaction(ctx, comment_reaction, errChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) || isNoRowAffectedError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -174,33 +181,33 @@ func (h *Handler) CommentReactionHandler(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 	id, err := strconv.Atoi(r.Form.Get("id"))
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	reactionsChan := make(chan entity.ReactionsResult)
+	reactionsChan := make(chan entity.ReactionsResult, 1)
 	var reactionsRes entity.ReactionsResult
-	go h.ccase.FetchReactions(ctx, id, reactionsChan)
+	h.background(func() { h.ccase.FetchReactions(ctx, id, reactionsChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case reactionsRes = <-reactionsChan:
 		if err = reactionsRes.Err; err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
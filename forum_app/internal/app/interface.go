@@ -10,20 +10,32 @@ type UserUsecase interface {
 	FetchAll(context.Context, chan entity.UsersResult)
 	FetchByEmail(context.Context, string, chan entity.UserResult)
 	Store(context.Context, entity.User, chan entity.Result)
+	UpdateRole(context.Context, entity.RoleUpdate, chan error)
+	UpdatePassword(context.Context, entity.User, chan error)
+	VerifyEmail(context.Context, entity.User, chan error)
 }
 
 type PostUsecase interface {
 	FetchById(context.Context, int, chan entity.PostResult)
-	FetchAll(context.Context, chan entity.PostsResult)
-	FetchCategories(context.Context, chan entity.CategoriesResult)
-	FetchCategoryPosts(context.Context, int, chan entity.CatResult)
+	FetchPage(context.Context, entity.PageRequest, chan entity.PageResult)
+	FetchCategories(context.Context, bool, chan entity.CategoriesResult)
+	FetchCategoryPosts(context.Context, entity.PageRequest, chan entity.CatResult)
 	FetchReactions(context.Context, int, chan entity.ReactionsResult)
 	Store(context.Context, entity.Post, chan entity.Result)
+	Update(context.Context, entity.Post, chan error)
+	Delete(context.Context, entity.Post, chan error)
 	StorePostReaction(context.Context, entity.PostReaction, chan error)
 	UpdatePostReaction(context.Context, entity.PostReaction, chan error)
 	DeletePostReaction(context.Context, entity.PostReaction, chan error)
 }
 
+type CategoryUsecase interface {
+	Store(context.Context, entity.CategoryChange, chan entity.Result)
+	Update(context.Context, entity.CategoryChange, chan error)
+	Reorder(context.Context, entity.CategoryChange, chan error)
+	Archive(context.Context, entity.CategoryChange, chan error)
+}
+
 type CommentUsecase interface {
 	FetchReactions(context.Context, int, chan entity.ReactionsResult)
 	Store(context.Context, entity.Comment, chan entity.Result)
@@ -31,3 +43,22 @@ type CommentUsecase interface {
 	UpdateCommentReaction(context.Context, entity.CommentReaction, chan error)
 	DeleteCommentReaction(context.Context, entity.CommentReaction, chan error)
 }
+
+type SearchUsecase interface {
+	Search(context.Context, entity.SearchRequest, chan entity.SearchResult)
+}
+
+type ModerationUsecase interface {
+	Apply(context.Context, entity.ModerationAction, chan error)
+	FetchQueue(context.Context, int, chan entity.ModerationQueueResult)
+}
+
+type ReportUsecase interface {
+	Store(context.Context, entity.Report, chan entity.ReportResult)
+}
+
+type NotificationUsecase interface {
+	Fetch(context.Context, entity.NotificationsRequest, chan entity.NotificationsResult)
+	CountUnread(context.Context, int, chan entity.UnreadResult)
+	MarkRead(context.Context, entity.NotificationRead, chan error)
+}
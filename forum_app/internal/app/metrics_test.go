@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"forum_app/internal/config"
+	"forum_app/pkg/logger"
+	"forum_app/pkg/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *metrics.Registry) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DB = filepath.Join(t.TempDir(), "forum.db")
+	reg := metrics.NewRegistry()
+	h := NewHandler(cfg, logger.New(io.Discard, logger.LevelError), reg)
+	t.Cleanup(func() { h.Close(context.Background()) })
+	return h, reg
+}
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+// A request is timed by its route and the queries it makes by op, the pool is read at the scrape
+func TestRequestMetrics(t *testing.T) {
+	h, reg := newTestHandler(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/categories", h.CategoriesHandler)
+	handler := reg.InstrumentMux(mux)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/categories", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /categories = %d: %s", rec.Code, rec.Body)
+	}
+
+	body := scrape(t, reg)
+	for _, line := range []string{
+		`http_request_duration_seconds_count{route="/categories",method="GET",status="200"} 1` + "\n",
+		`sqlite_query_duration_seconds_count{op="query"}`,
+		`sqlite_query_duration_seconds_count{op="exec"}`,
+		"db_open_connections ",
+		"db_wait_count_total 0\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"forum_app/internal/entity"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (h *Handler) ModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	moderatorId, err := strconv.Atoi(r.Form.Get("moderator_id"))
+	if err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	queueChan := make(chan entity.ModerationQueueResult, 1)
+	var queueRes entity.ModerationQueueResult
+	h.background(func() { h.mcase.FetchQueue(ctx, moderatorId, queueChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case queueRes = <-queueChan:
+		if err = queueRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.moderationErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: queueRes.Queue})
+}
+
+func (h *Handler) ModerationActionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var action entity.ModerationAction
+	err := json.NewDecoder(r.Body).Decode(&action)
+	if err != nil || !validateModerationData(action) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.mcase.Apply(ctx, action, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.moderationErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func validateModerationData(action entity.ModerationAction) bool {
+	return action.Moderator.Id != 0 && action.TargetId != 0 && strings.TrimSpace(action.Reason) != "" &&
+		entity.ValidModeration(action.Target, action.Action)
+}
+
+func (h *Handler) moderationErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrPostNotFound, entity.ErrCommentNotFound, entity.ErrUserNotFound:
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+	case entity.ErrForbidden:
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
+	case entity.ErrInvalidRole:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+	default:
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+	}
+}
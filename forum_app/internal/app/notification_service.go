@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"forum_app/internal/entity"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	req, err := getNotificationsRequest(r)
+	if err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	notificationsChan := make(chan entity.NotificationsResult, 1)
+	var notificationsRes entity.NotificationsResult
+	h.background(func() { h.ncase.Fetch(ctx, req, notificationsChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case notificationsRes = <-notificationsChan:
+		if err = notificationsRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: notificationsRes.Notifications})
+}
+
+func (h *Handler) UnreadNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	userId, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userId <= 0 {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	unreadChan := make(chan entity.UnreadResult, 1)
+	var unreadRes entity.UnreadResult
+	h.background(func() { h.ncase.CountUnread(ctx, userId, unreadChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case unreadRes = <-unreadChan:
+		if err = unreadRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: entity.Notifications{Unread: unreadRes.Unread}})
+}
+
+func (h *Handler) ReadNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	h.markNotificationsRead(w, r, false)
+}
+
+func (h *Handler) ReadAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	h.markNotificationsRead(w, r, true)
+}
+
+// markNotificationsRead serves both read endpoints, all ignores the id of the request
+func (h *Handler) markNotificationsRead(w http.ResponseWriter, r *http.Request, all bool) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPut {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var read entity.NotificationRead
+	err := json.NewDecoder(r.Body).Decode(&read)
+	if all {
+		read.Id = 0
+	}
+	if err != nil || read.UserId <= 0 || read.Id < 0 || (!all && read.Id == 0) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.ncase.MarkRead(ctx, read, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			if err == entity.ErrNotificationNotFound {
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+			} else {
+				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			}
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func getNotificationsRequest(r *http.Request) (entity.NotificationsRequest, error) {
+	req := entity.NotificationsRequest{Limit: entity.DefaultPageLimit}
+	var err error
+	query := r.URL.Query()
+	if req.UserId, err = strconv.Atoi(query.Get("user_id")); err != nil || req.UserId <= 0 {
+		return entity.NotificationsRequest{}, fmt.Errorf("invalid user id: %q", query.Get("user_id"))
+	}
+	if offset := query.Get("offset"); offset != "" {
+		if req.Offset, err = strconv.Atoi(offset); err != nil || req.Offset < 0 {
+			return entity.NotificationsRequest{}, fmt.Errorf("invalid offset: %q", offset)
+		}
+	}
+	return req, nil
+}
@@ -13,28 +13,28 @@ func (h *Handler) PostDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 	id, err := strconv.Atoi(r.Form.Get("id"))
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	postChan := make(chan entity.PostResult)
+	postChan := make(chan entity.PostResult, 1)
 	var postResult entity.PostResult
-	go h.pcase.FetchById(ctx, id, postChan)
+	h.background(func() { h.pcase.FetchById(ctx, id, postChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case postResult = <-postChan:
@@ -55,66 +55,100 @@ func (h *Handler) PostsAllHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	postsChan := make(chan entity.PostsResult)
-	var (
-		postsRes entity.PostsResult
-		err      error
-	)
-	go h.pcase.FetchAll(ctx, postsChan)
+	page, err := getPageRequest(r)
+	if err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	pageChan := make(chan entity.PageResult, 1)
+	var pageRes entity.PageResult
+	h.background(func() { h.pcase.FetchPage(ctx, page, pageChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
-	case postsRes = <-postsChan:
-		if err = postsRes.Err; err != nil {
-			h.errLog.Println(err)
+	case pageRes = <-pageChan:
+		if err = pageRes.Err; err != nil {
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
 	}
-	h.APIResponse(w, http.StatusOK, entity.Response{Body: postsRes.Posts})
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: pageRes.Page})
+}
+
+// getPageRequest reads the limit, cursor and sort query parameters of post listings
+func getPageRequest(r *http.Request) (entity.PageRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return entity.PageRequest{}, err
+	}
+	page := entity.PageRequest{Limit: entity.DefaultPageLimit, Sort: entity.SortNewest}
+	if limit := r.Form.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return entity.PageRequest{}, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if n > entity.MaxPageLimit {
+			n = entity.MaxPageLimit
+		}
+		page.Limit = n
+	}
+	if sort := r.Form.Get("sort"); sort != "" {
+		if !entity.ValidSort(sort) {
+			return entity.PageRequest{}, fmt.Errorf("invalid sort: %s", sort)
+		}
+		page.Sort = sort
+	}
+	cursor, err := entity.DecodeCursor(r.Form.Get("cursor"))
+	if err != nil {
+		return entity.PageRequest{}, err
+	}
+	page.Cursor = cursor
+	return page, nil
 }
 
 func (h *Handler) CategoryPostsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 
-	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+	page, err := getPageRequest(r)
+	if err != nil {
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 
-	id, err := strconv.Atoi(r.Form.Get("id"))
+	page.CategoryId, err = strconv.Atoi(r.Form.Get("id"))
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	catChan := make(chan entity.CatResult)
+	catChan := make(chan entity.CatResult, 1)
 	var catResult entity.CatResult
-	go h.pcase.FetchCategoryPosts(ctx, id, catChan)
+	h.background(func() { h.pcase.FetchCategoryPosts(ctx, page, catChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case catResult = <-catChan:
 		err = catResult.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if err == entity.ErrCategoryNotFound {
 				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
 				return
@@ -130,34 +164,38 @@ func (h *Handler) StorePostHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var post entity.Post
 	err := json.NewDecoder(r.Body).Decode(&post)
 	if err != nil || !validatePostData(post) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	resChan := make(chan entity.Result)
+	resChan := make(chan entity.Result, 1)
 	var res entity.Result
-	go h.pcase.Store(ctx, post, resChan)
+	h.background(func() { h.pcase.Store(ctx, post, resChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case res = <-resChan:
 		err = res.Err
 		if err != nil {
-			h.errLog.Println(err)
-			if isConstraintError(err) {
+			h.logError(ctx, err)
+			if isConstraintError(err) || err == entity.ErrCategoryNotFound {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
 			}
+			if err == entity.ErrCategoryArchived {
+				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Category is archived"})
+				return
+			}
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
@@ -165,6 +203,83 @@ func (h *Handler) StorePostHandler(w http.ResponseWriter, r *http.Request) {
 	h.APIResponse(w, http.StatusCreated, entity.Response{Body: entity.Post{Id: int(res.Id)}})
 }
 
+func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPut {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var post entity.Post
+	err := json.NewDecoder(r.Body).Decode(&post)
+	if err != nil || post.Id == 0 || post.User.Id == 0 || strings.TrimSpace(post.Title) == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.pcase.Update(ctx, post, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.postErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodDelete {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var post entity.Post
+	err := json.NewDecoder(r.Body).Decode(&post)
+	if err != nil || post.Id == 0 || post.User.Id == 0 {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.pcase.Delete(ctx, post, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.postErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func (h *Handler) postErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrPostNotFound:
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+	case entity.ErrForbidden:
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
+	default:
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+	}
+}
+
 func validatePostData(post entity.Post) bool {
 	if post.Title == "" {
 		return false
@@ -180,28 +295,28 @@ func (h *Handler) StorePostReactionHandler(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var post_reaction entity.PostReaction
 	err := json.NewDecoder(r.Body).Decode(&post_reaction)
 	if err != nil || !validatePostReactionData(post_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{})
 		return
 	}
-	errChan := make(chan error)
-	go h.pcase.StorePostReaction(ctx, post_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.pcase.StorePostReaction(ctx, post_reaction, errChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -217,33 +332,33 @@ func (h *Handler) PostReactionsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 	id, err := strconv.Atoi(r.Form.Get("id"))
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	reactionsChan := make(chan entity.ReactionsResult)
+	reactionsChan := make(chan entity.ReactionsResult, 1)
 	var reactionsRes entity.ReactionsResult
-	go h.pcase.FetchReactions(ctx, id, reactionsChan)
+	h.background(func() { h.pcase.FetchReactions(ctx, id, reactionsChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case reactionsRes = <-reactionsChan:
 		if err = reactionsRes.Err; err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
@@ -263,28 +378,28 @@ func (h *Handler) UpdatePostReactionHandler(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPut {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var post_reaction entity.PostReaction
 	err := json.NewDecoder(r.Body).Decode(&post_reaction)
 	if err != nil || !validatePostReactionData(post_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	errChan := make(chan error)
-	go h.pcase.UpdatePostReaction(ctx, post_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.pcase.UpdatePostReaction(ctx, post_reaction, errChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) || isNoRowAffectedError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -300,29 +415,29 @@ func (h *Handler) DeletePostReactionHandler(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodDelete {
-		h.errLog.Printf("invalid method: %s\n", r.Method)
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	var post_reaction entity.PostReaction
 	err := json.NewDecoder(r.Body).Decode(&post_reaction)
 	if err != nil || !validatePostReactionData(post_reaction) {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{})
 		return
 	}
-	errChan := make(chan error)
-	go h.pcase.DeletePostReaction(ctx, post_reaction, errChan)
+	errChan := make(chan error, 1)
+	h.background(func() { h.pcase.DeletePostReaction(ctx, post_reaction, errChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) || isNoRowAffectedError(err) {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 				return
@@ -342,25 +457,32 @@ func (h *Handler) CategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	catsChan := make(chan entity.CategoriesResult)
+	if err := r.ParseForm(); err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	catsChan := make(chan entity.CategoriesResult, 1)
 	var (
 		catsRes entity.CategoriesResult
 		err     error
 	)
-	go h.pcase.FetchCategories(ctx, catsChan)
+	// archived categories are listed only on request, for the admins managing them
+	archived := r.Form.Get("archived") == "1"
+	h.background(func() { h.pcase.FetchCategories(ctx, archived, catsChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case catsRes = <-catsChan:
 		if err = catsRes.Error; err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
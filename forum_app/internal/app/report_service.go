@@ -0,0 +1,62 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"forum_app/internal/entity"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxReportDetails = 500
+
+func (h *Handler) StoreReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var report entity.Report
+	err := json.NewDecoder(r.Body).Decode(&report)
+	report.Details = strings.TrimSpace(report.Details)
+	if err != nil || !validateReportData(report) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	reportChan := make(chan entity.ReportResult, 1)
+	var reportRes entity.ReportResult
+	h.background(func() { h.rcase.Store(ctx, report, reportChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case reportRes = <-reportChan:
+		if err = reportRes.Err; err != nil {
+			h.logError(ctx, err)
+			switch err {
+			case entity.ErrPostNotFound, entity.ErrCommentNotFound:
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+			case entity.ErrForbidden:
+				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "You can't report your own content"})
+			case entity.ErrReportExists:
+				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Already reported"})
+			default:
+				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			}
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusCreated, entity.Response{Body: reportRes.Outcome})
+}
+
+func validateReportData(report entity.Report) bool {
+	return report.Reporter.Id != 0 && report.TargetId != 0 &&
+		(report.Target == entity.TargetPost || report.Target == entity.TargetComment) &&
+		entity.ValidReportReason(report.Reason) && utf8.RuneCountInString(report.Details) <= maxReportDetails
+}
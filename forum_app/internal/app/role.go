@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"forum_app/internal/config"
+	"forum_app/internal/entity"
+	ur "forum_app/internal/user/repository"
+	"forum_app/pkg/sqlite3"
+	"log"
+	"os"
+	"time"
+)
+
+const roleUsage = `usage: main role <email> <user|moderator|admin>`
+
+// SetRole runs the role subcommand, it is how the first administrator gets appointed
+func SetRole(cfg *config.Config, args []string) {
+	errLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime)
+	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
+	if len(args) != 2 || !entity.ValidRole(args[1]) {
+		errLog.Fatalln(roleUsage)
+	}
+	db, err := sqlite3.New(cfg.DB, nil)
+	if err != nil {
+		errLog.Fatalln(err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeout))
+	defer cancel()
+	usersRepo := ur.NewUsersRepository(db, errLog)
+	user, err := usersRepo.FetchByEmail(ctx, args[0])
+	if err != nil {
+		errLog.Fatalln(err)
+	}
+	if user.Id == 0 {
+		errLog.Fatalln(entity.ErrUserNotFound)
+	}
+	if err = usersRepo.UpdateRole(ctx, user.Id, args[1]); err != nil {
+		errLog.Fatalln(err)
+	}
+	infoLog.Printf("%s is now %s\n", user.Email, args[1])
+}
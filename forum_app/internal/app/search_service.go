@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"forum_app/internal/entity"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	req, err := getSearchRequest(r)
+	if err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	searchChan := make(chan entity.SearchResult, 1)
+	var searchRes entity.SearchResult
+	h.background(func() { h.scase.Search(ctx, req, searchChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case searchRes = <-searchChan:
+		if err = searchRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: searchRes.Search})
+}
+
+func getSearchRequest(r *http.Request) (entity.SearchRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return entity.SearchRequest{}, err
+	}
+	req := entity.SearchRequest{
+		Query:  strings.TrimSpace(r.Form.Get("q")),
+		Author: strings.TrimSpace(r.Form.Get("author")),
+		From:   r.Form.Get("from"),
+		To:     r.Form.Get("to"),
+		Limit:  entity.DefaultPageLimit,
+	}
+	if req.MatchQuery() == "" {
+		return entity.SearchRequest{}, fmt.Errorf("empty search query")
+	}
+	for _, date := range []string{req.From, req.To} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			return entity.SearchRequest{}, err
+		}
+	}
+	var err error
+	if category := r.Form.Get("category"); category != "" {
+		if req.CategoryId, err = strconv.Atoi(category); err != nil {
+			return entity.SearchRequest{}, err
+		}
+	}
+	if limit := r.Form.Get("limit"); limit != "" {
+		if req.Limit, err = strconv.Atoi(limit); err != nil || req.Limit <= 0 {
+			return entity.SearchRequest{}, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if req.Limit > entity.MaxPageLimit {
+			req.Limit = entity.MaxPageLimit
+		}
+	}
+	if offset := r.Form.Get("offset"); offset != "" {
+		if req.Offset, err = strconv.Atoi(offset); err != nil || req.Offset < 0 {
+			return entity.SearchRequest{}, fmt.Errorf("invalid offset: %s", offset)
+		}
+	}
+	return req, nil
+}
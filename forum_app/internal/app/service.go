@@ -2,32 +2,60 @@ package app
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	cr "forum_app/internal/comment/repository"
 	cUcse "forum_app/internal/comment/usecase"
+	"forum_app/internal/config"
+	mr "forum_app/internal/moderation/repository"
+	mUcse "forum_app/internal/moderation/usecase"
+	nr "forum_app/internal/notification/repository"
+	nUcse "forum_app/internal/notification/usecase"
 	pr "forum_app/internal/post/repository"
 	pUcse "forum_app/internal/post/usecase"
+	rr "forum_app/internal/report/repository"
+	rUcse "forum_app/internal/report/usecase"
+	sr "forum_app/internal/search/repository"
+	sUcse "forum_app/internal/search/usecase"
 	ur "forum_app/internal/user/repository"
 	uUcse "forum_app/internal/user/usecase"
+	"forum_app/pkg/logger"
+	"forum_app/pkg/metrics"
 	"forum_app/pkg/sqlite3"
 	"log"
+	"sync"
 	"time"
 )
 
-const duration = 5 * time.Second
+// duration bounds the handling of a request, Run sets it from the config
+var duration = 5 * time.Second
 
 type Handler struct {
-	errLog  *log.Logger
-	infoLog *log.Logger
-	ucase   UserUsecase
-	pcase   PostUsecase
-	ccase   CommentUsecase
+	log    *logger.Logger
+	errLog *log.Logger
+	ucase  UserUsecase
+	pcase  PostUsecase
+	kcase  CategoryUsecase
+	ccase  CommentUsecase
+	scase  SearchUsecase
+	mcase  ModerationUsecase
+	rcase  ReportUsecase
+	ncase  NotificationUsecase
+	db     *sql.DB
+	// tasks are the usecase calls still running, Close waits for them
+	tasks sync.WaitGroup
 }
 
-func NewHandler(errLog, infoLog *log.Logger) *Handler {
-	db, err := sqlite3.New()
+func NewHandler(cfg *config.Config, logs *logger.Logger, reg *metrics.Registry) *Handler {
+	errLog, infoLog := logs.Std(logger.LevelError), logs.Std(logger.LevelInfo)
+	queries := reg.NewHistogram("sqlite_query_duration_seconds", "Duration of the SQLite queries and execs.", metrics.DefaultBuckets, "op")
+	db, err := sqlite3.New(cfg.DB, func(op string, d time.Duration) {
+		queries.Observe(d.Seconds(), op)
+	})
 	if err != nil {
 		errLog.Fatalln(err)
 	}
+	reg.NewDBStats(db)
 	infoLog.Println("Connected to the database")
 	usersRepo := ur.NewUsersRepository(db, errLog)
 	postsRepo := pr.NewPostsRepository(db, errLog)
@@ -35,15 +63,55 @@ func NewHandler(errLog, infoLog *log.Logger) *Handler {
 	categoriesRepo := pr.NewCategoriesRepository(db, errLog)
 	commentsRepo := cr.NewCommentsRepository(db, errLog)
 	cReactionsRepo := cr.NewCommentReactionsRepository(db, errLog)
+	searchRepo := sr.NewSearchRepository(db, errLog)
+	moderationRepo := mr.NewModerationRepository(db, errLog)
+	reportsRepo := rr.NewReportsRepository(db, errLog)
+	notificationsRepo := nr.NewNotificationsRepository(db, errLog)
 	ucase := uUcse.NewUsersUsecase(usersRepo, postsRepo, pReactionsRepo, commentsRepo, cReactionsRepo, errLog)
-	pcase := pUcse.NewPostsUsecase(postsRepo, pReactionsRepo, commentsRepo, cReactionsRepo, categoriesRepo, usersRepo, errLog)
-	ccase := cUcse.NewCommentsUsecase(commentsRepo, cReactionsRepo, postsRepo, usersRepo, errLog)
-	return &Handler{errLog, infoLog, ucase, pcase, ccase}
+	pcase := pUcse.NewPostsUsecase(postsRepo, pReactionsRepo, commentsRepo, cReactionsRepo, categoriesRepo, usersRepo, notificationsRepo, errLog)
+	kcase := pUcse.NewCategoriesUsecase(categoriesRepo, usersRepo, errLog)
+	ccase := cUcse.NewCommentsUsecase(commentsRepo, cReactionsRepo, postsRepo, usersRepo, notificationsRepo, errLog)
+	scase := sUcse.NewSearchUsecase(searchRepo, errLog)
+	mcase := mUcse.NewModerationUsecase(moderationRepo, usersRepo, errLog)
+	rcase := rUcse.NewReportsUsecase(reportsRepo, postsRepo, commentsRepo, cfg.ReportHideAfter, errLog)
+	ncase := nUcse.NewNotificationsUsecase(notificationsRepo, errLog)
+	return &Handler{log: logs, errLog: errLog, ucase: ucase, pcase: pcase, kcase: kcase, ccase: ccase, scase: scase, mcase: mcase, rcase: rcase, ncase: ncase, db: db}
 }
 
+// background runs f in a goroutine that Close waits for
+func (h *Handler) background(f func()) {
+	h.tasks.Add(1)
+	go func() {
+		defer h.tasks.Done()
+		f()
+	}()
+}
+
+// Close waits for the running usecase calls, at most until ctx is done, and closes the database
+func (h *Handler) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		h.logError(ctx, "closing the database with usecase calls still running:", ctx.Err())
+	}
+	return h.db.Close()
+}
+
+// getTimeout detaches the work of a request from its connection, the request ID is kept for the logs
 func getTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := logger.WithRequestID(context.Background(), logger.RequestID(ctx))
 	if deadline, ok := ctx.Deadline(); ok {
-		return context.WithDeadline(context.Background(), deadline)
+		return context.WithDeadline(detached, deadline)
 	}
-	return context.WithTimeout(context.Background(), duration)
+	return context.WithTimeout(detached, duration)
+}
+
+// logError logs v like errLog.Println, along with the request ID of ctx
+func (h *Handler) logError(ctx context.Context, v ...interface{}) {
+	h.log.Output(ctx, 2, logger.LevelError, fmt.Sprintln(v...))
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"forum_app/internal/entity"
@@ -13,23 +14,23 @@ func (h *Handler) UsersAllHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	usersChan := make(chan entity.UsersResult)
+	usersChan := make(chan entity.UsersResult, 1)
 	var usersRes entity.UsersResult
 	var err error
-	go h.ucase.FetchAll(ctx, usersChan)
+	h.background(func() { h.ucase.FetchAll(ctx, usersChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case usersRes = <-usersChan:
 		if err = usersRes.Err; err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
@@ -42,37 +43,37 @@ func (h *Handler) UserByEmailHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 
 	email := r.Form.Get("email")
 	if email == "" {
-		h.errLog.Println("bad request: email is not provided")
+		h.logError(ctx, "bad request: email is not provided")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	userChan := make(chan entity.UserResult)
+	userChan := make(chan entity.UserResult, 1)
 	var userRes entity.UserResult
 	var err error
-	go h.ucase.FetchByEmail(ctx, email, userChan)
+	h.background(func() { h.ucase.FetchByEmail(ctx, email, userChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case userRes = <-userChan:
 		err = userRes.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if err == entity.ErrUserNotFound {
 				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
 				return
@@ -88,34 +89,34 @@ func (h *Handler) UserDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 	id, err := strconv.Atoi(r.Form.Get("id"))
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	userChan := make(chan entity.UserResult)
+	userChan := make(chan entity.UserResult, 1)
 	var userRes entity.UserResult
-	go h.ucase.FetchById(ctx, id, userChan)
+	h.background(func() { h.ucase.FetchById(ctx, id, userChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case userRes = <-userChan:
 		err = userRes.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if err == entity.ErrUserNotFound {
 				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
 				return
@@ -131,35 +132,35 @@ func (h *Handler) StoreUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
 	if err := r.ParseForm(); err != nil {
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
 	var user entity.User
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		h.errLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	resChan := make(chan entity.Result)
+	resChan := make(chan entity.Result, 1)
 	var res entity.Result
-	go h.ucase.Store(ctx, user, resChan)
+	h.background(func() { h.ucase.Store(ctx, user, resChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case res = <-resChan:
 		err = res.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			if err == entity.ErrUserExists {
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "User with a given email already exists"})
 				return
@@ -170,3 +171,86 @@ func (h *Handler) StoreUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	h.APIResponse(w, http.StatusCreated, entity.Response{Body: entity.User{Id: int(res.Id)}})
 }
+
+func (h *Handler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPut {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var update entity.RoleUpdate
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil || update.AdminId == 0 || update.UserId == 0 {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.ucase.UpdateRole(ctx, update, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.moderationErrorResponse(w, err)
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func (h *Handler) UpdatePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, h.ucase.UpdatePassword, func(user entity.User) bool {
+		return user.Password != ""
+	})
+}
+
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	h.updateUser(w, r, h.ucase.VerifyEmail, func(user entity.User) bool {
+		return user.Email != ""
+	})
+}
+
+// updateUser serves the PUT endpoints forum_auth uses to change the account of a user
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request, apply func(context.Context, entity.User, chan error), valid func(entity.User) bool) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPut {
+		h.logError(ctx, fmt.Sprintf("invalid method: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var user entity.User
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil || user.Id == 0 || !valid(user) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, user, errChan) })
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			if err == entity.ErrUserNotFound {
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+				return
+			}
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
@@ -25,7 +25,7 @@ func (cr *CommentsRepository) FetchById(ctx context.Context, id int) (entity.Com
 		return comment, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM comments WHERE id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, post_id, user_id, date, content, COALESCE(parent_id, 0), hidden FROM comments WHERE id = ?;")
 	if err != nil {
 		cr.errorLog.Println(err)
 		return comment, err
@@ -37,7 +37,7 @@ func (cr *CommentsRepository) FetchById(ctx context.Context, id int) (entity.Com
 		return comment, err
 	}
 	if rows.Next() {
-		rows.Scan(&comment.Id, &comment.Post.Id, &comment.User.Id, &comment.Date, &comment.Content)
+		rows.Scan(&comment.Id, &comment.Post.Id, &comment.User.Id, &comment.Date, &comment.Content, &comment.ParentId, &comment.Hidden)
 	}
 	if err = tx.Commit(); err != nil {
 		cr.errorLog.Println(err)
@@ -54,7 +54,7 @@ func (cr *CommentsRepository) FetchByPostId(ctx context.Context, id int) ([]enti
 		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT id, user_id, date, content FROM comments WHERE post_id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, user_id, date, content, COALESCE(parent_id, 0), hidden FROM comments WHERE post_id = ? ORDER BY id;")
 	if err != nil {
 		cr.errorLog.Println(err)
 		return nil, err
@@ -67,7 +67,7 @@ func (cr *CommentsRepository) FetchByPostId(ctx context.Context, id int) ([]enti
 	}
 	for rows.Next() {
 		comment := entity.Comment{}
-		rows.Scan(&comment.Id, &comment.User.Id, &comment.Date, &comment.Content)
+		rows.Scan(&comment.Id, &comment.User.Id, &comment.Date, &comment.Content, &comment.ParentId, &comment.Hidden)
 		comments = append(comments, comment)
 	}
 	if err = tx.Commit(); err != nil {
@@ -77,6 +77,35 @@ func (cr *CommentsRepository) FetchByPostId(ctx context.Context, id int) ([]enti
 	return comments, nil
 }
 
+func (cr *CommentsRepository) FetchLastByPostId(ctx context.Context, id int) (entity.Comment, error) {
+	comment := entity.Comment{}
+	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		cr.errorLog.Println(err)
+		return comment, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, post_id, user_id, date, content, COALESCE(parent_id, 0) FROM comments WHERE post_id = ? AND hidden = 0 ORDER BY id DESC LIMIT 1;")
+	if err != nil {
+		cr.errorLog.Println(err)
+		return comment, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, id)
+	if err != nil {
+		cr.errorLog.Println(err)
+		return comment, err
+	}
+	if rows.Next() {
+		rows.Scan(&comment.Id, &comment.Post.Id, &comment.User.Id, &comment.Date, &comment.Content, &comment.ParentId)
+	}
+	if err = tx.Commit(); err != nil {
+		cr.errorLog.Println(err)
+		return entity.Comment{}, err
+	}
+	return comment, nil
+}
+
 func (cr *CommentsRepository) FetchByUserId(ctx context.Context, id int) ([]entity.Comment, error) {
 	comments := []entity.Comment{}
 	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -85,7 +114,7 @@ func (cr *CommentsRepository) FetchByUserId(ctx context.Context, id int) ([]enti
 		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT c.id, c.post_id, p.title, c.date, c.content FROM comments AS c LEFT JOIN posts AS p ON c.post_id=p.id WHERE c.user_id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT c.id, c.post_id, p.title, c.date, c.content FROM comments AS c LEFT JOIN posts AS p ON c.post_id=p.id WHERE c.user_id = ? AND c.hidden = 0 AND p.hidden = 0;")
 	if err != nil {
 		cr.errorLog.Println(err)
 		return nil, err
@@ -115,17 +144,27 @@ func (cr *CommentsRepository) Store(ctx context.Context, comment entity.Comment)
 		return 0, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, `INSERT INTO comments(post_id, user_id, date, content) VALUES(?,?,?,?);`)
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO comments(post_id, user_id, date, content, parent_id) VALUES(?,?,?,?,?);`)
 	if err != nil {
 		cr.errorLog.Println(err)
 		return 0, err
 	}
 	defer stmt.Close()
-	res, err := stmt.ExecContext(ctx, comment.Post.Id, comment.User.Id, time.Now().Format("2006-01-02"), comment.Content)
+	var parentId interface{}
+	if comment.ParentId != 0 {
+		parentId = comment.ParentId
+	}
+	now := time.Now()
+	res, err := stmt.ExecContext(ctx, comment.Post.Id, comment.User.Id, now.Format("2006-01-02"), comment.Content, parentId)
 	if err != nil {
 		cr.errorLog.Println(err)
 		return 0, err
 	}
+	// the comment makes its post the most recently active one
+	if _, err = tx.ExecContext(ctx, `UPDATE posts SET last_activity = ? WHERE id = ?;`, now.Format(entity.ActivityLayout), comment.Post.Id); err != nil {
+		cr.errorLog.Println(err)
+		return 0, err
+	}
 	if err = tx.Commit(); err != nil {
 		cr.errorLog.Println(err)
 		return 0, err
@@ -11,15 +11,17 @@ type CommentsUsecase struct {
 	commentReactionsRepo CommentReactionsRepository
 	postsRepo            PostsRepository
 	usersRepo            UsersRepository
+	notificationsRepo    NotificationsRepository
 	errorLog             *log.Logger
 }
 
-func NewCommentsUsecase(commentsRepo CommentsRepository, commentReactionsRepo CommentReactionsRepository, postsRepo PostsRepository, usersRepo UsersRepository, errorLog *log.Logger) *CommentsUsecase {
+func NewCommentsUsecase(commentsRepo CommentsRepository, commentReactionsRepo CommentReactionsRepository, postsRepo PostsRepository, usersRepo UsersRepository, notificationsRepo NotificationsRepository, errorLog *log.Logger) *CommentsUsecase {
 	return &CommentsUsecase{
 		commentsRepo:         commentsRepo,
 		commentReactionsRepo: commentReactionsRepo,
 		postsRepo:            postsRepo,
 		usersRepo:            usersRepo,
+		notificationsRepo:    notificationsRepo,
 		errorLog:             errorLog,
 	}
 }
@@ -107,19 +109,141 @@ func (u *CommentsUsecase) FetchReactions(ctx context.Context, id int, reactionsC
 }
 
 func (cu *CommentsUsecase) Store(ctx context.Context, comment entity.Comment, res chan entity.Result) {
+	post, err := cu.postsRepo.FetchById(ctx, comment.Post.Id)
+	if err != nil {
+		res <- entity.Result{Err: err}
+		return
+	}
+	if post.Id == 0 || post.Hidden {
+		res <- entity.Result{Err: entity.ErrPostNotFound}
+		return
+	}
+	if post.Locked {
+		res <- entity.Result{Err: entity.ErrPostLocked}
+		return
+	}
+	repliedTo := comment.ParentId
+	if comment.ParentId != 0 {
+		parentId, err := cu.replyParent(ctx, comment)
+		if err != nil {
+			res <- entity.Result{Err: err}
+			return
+		}
+		comment.ParentId = parentId
+	}
 	id, err := cu.commentsRepo.Store(ctx, comment)
 	if err != nil {
 		res <- entity.Result{Err: err}
+		return
 	}
+	comment.Id = int(id)
+	cu.notifyComment(ctx, comment, post, repliedTo)
 	res <- entity.Result{Id: id}
 }
 
+// notifyComment tells the author of the replied comment, the author of the post and the mentioned
+// users about a new comment, everyone at most once and never the commenter, failures are only logged
+func (cu *CommentsUsecase) notifyComment(ctx context.Context, comment entity.Comment, post entity.Post, repliedTo int) {
+	base := entity.Notification{Actor: comment.User, Post: entity.Post{Id: post.Id}, CommentId: comment.Id}
+	notified := []int{comment.User.Id}
+	notifications := []entity.Notification{}
+	add := func(userId int, kind string) {
+		for _, id := range notified {
+			if id == userId {
+				return
+			}
+		}
+		notified = append(notified, userId)
+		n := base
+		n.User, n.Kind = entity.User{Id: userId}, kind
+		notifications = append(notifications, n)
+	}
+	if repliedTo != 0 {
+		parent, err := cu.commentsRepo.FetchById(ctx, repliedTo)
+		if err != nil {
+			cu.errorLog.Println(err)
+		} else if parent.Id != 0 {
+			add(parent.User.Id, entity.NotifyReply)
+		}
+	}
+	add(post.User.Id, entity.NotifyComment)
+	if len(notifications) != 0 {
+		if err := cu.notificationsRepo.Store(ctx, notifications); err != nil {
+			cu.errorLog.Println(err)
+		}
+	}
+	if err := cu.notificationsRepo.StoreMentions(ctx, base, entity.Mentions(comment.Content), notified); err != nil {
+		cu.errorLog.Println(err)
+	}
+}
+
+// replyParent checks that the parent belongs to the same post and moves replies
+// that would exceed MaxCommentDepth up to the deepest allowed level
+func (cu *CommentsUsecase) replyParent(ctx context.Context, comment entity.Comment) (int, error) {
+	parent, err := cu.commentsRepo.FetchById(ctx, comment.ParentId)
+	if err != nil {
+		return 0, err
+	}
+	if parent.Id == 0 || parent.Post.Id != comment.Post.Id {
+		return 0, entity.ErrInvalidParent
+	}
+	ancestors := []entity.Comment{parent}
+	for current := parent; current.ParentId != 0 && len(ancestors) < entity.MaxCommentDepth; {
+		current, err = cu.commentsRepo.FetchById(ctx, current.ParentId)
+		if err != nil {
+			return 0, err
+		}
+		ancestors = append(ancestors, current)
+	}
+	if len(ancestors) < entity.MaxCommentDepth {
+		return parent.Id, nil
+	}
+	return ancestors[len(ancestors)-entity.MaxCommentDepth+1].Id, nil
+}
+
+// StoreCommentReaction notifies the author of the comment about likes, dislikes go unannounced
 func (cu *CommentsUsecase) StoreCommentReaction(ctx context.Context, commentReaction entity.CommentReaction, err chan error) {
-	err <- cu.commentReactionsRepo.StoreReaction(ctx, commentReaction)
+	if e := cu.commentReactionsRepo.StoreReaction(ctx, commentReaction); e != nil {
+		err <- e
+		return
+	}
+	cu.notifyLike(ctx, commentReaction)
+	err <- nil
+}
+
+// UpdateCommentReaction notifies the author of the comment when a dislike turns into a like
+func (cu *CommentsUsecase) UpdateCommentReaction(ctx context.Context, commentReaction entity.CommentReaction, err chan error) {
+	if e := cu.commentReactionsRepo.UpdateReaction(ctx, commentReaction); e != nil {
+		err <- e
+		return
+	}
+	cu.notifyLike(ctx, commentReaction)
+	err <- nil
 }
 
-func (u *CommentsUsecase) UpdateCommentReaction(ctx context.Context, commentReaction entity.CommentReaction, err chan error) {
-	err <- u.commentReactionsRepo.UpdateReaction(ctx, commentReaction)
+// notifyLike tells the author of the comment about a like, a failure is only logged since the reaction is stored
+func (cu *CommentsUsecase) notifyLike(ctx context.Context, commentReaction entity.CommentReaction) {
+	if !commentReaction.Like {
+		return
+	}
+	comment, err := cu.commentsRepo.FetchById(ctx, commentReaction.Comment.Id)
+	if err != nil {
+		cu.errorLog.Println(err)
+		return
+	}
+	if comment.Id == 0 || comment.User.Id == commentReaction.Reaction.User.Id {
+		return
+	}
+	notification := entity.Notification{
+		User:      comment.User,
+		Actor:     commentReaction.Reaction.User,
+		Kind:      entity.NotifyCommentLike,
+		Post:      entity.Post{Id: comment.Post.Id},
+		CommentId: comment.Id,
+	}
+	if err = cu.notificationsRepo.Store(ctx, []entity.Notification{notification}); err != nil {
+		cu.errorLog.Println(err)
+	}
 }
 
 func (u *CommentsUsecase) DeleteCommentReaction(ctx context.Context, commentReaction entity.CommentReaction, err chan error) {
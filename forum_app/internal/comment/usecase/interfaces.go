@@ -24,3 +24,8 @@ type PostsRepository interface {
 type UsersRepository interface {
 	FetchById(context.Context, int) (entity.User, error)
 }
+
+type NotificationsRepository interface {
+	Store(context.Context, []entity.Notification) error
+	StoreMentions(context.Context, entity.Notification, []string, []int) error
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"forum_app/internal/entity"
+	"forum_app/pkg/logger"
+	"net"
+	"time"
+)
+
+type Config struct {
+	// Addr is where the API listens, forum_auth and forum_gateway are configured with it
+	Addr string `json:"addr"`
+	// DB is the path of the SQLite database
+	DB      string `json:"db"`
+	LogFile string `json:"log_file"`
+	// LogLevel is the least important level logged: debug, info, warn or error
+	LogLevel string `json:"log_level"`
+	// LogMaxSize is the size in megabytes the log file is rotated at, LogMaxBackups rotated files are kept
+	LogMaxSize    int `json:"log_max_size"`
+	LogMaxBackups int `json:"log_max_backups"`
+	// Timeout bounds the handling of a request
+	Timeout Duration `json:"timeout"`
+	// ReportHideAfter is the number of distinct reporters that hides a post or a comment, 0 turns it off
+	ReportHideAfter int `json:"report_hide_after"`
+	// PrintConfig asks to print the config instead of running
+	PrintConfig bool `json:"-"`
+}
+
+func Default() *Config {
+	return &Config{
+		Addr:            ":8080",
+		DB:              "./forum.db",
+		LogFile:         "forum_app.log",
+		LogLevel:        "info",
+		LogMaxSize:      10,
+		LogMaxBackups:   5,
+		Timeout:         Duration(5 * time.Second),
+		ReportHideAfter: entity.DefaultHideAfter,
+	}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", "HTTP_ADDR", "`address` to listen on", (*stringValue)(&c.Addr)},
+		{"db", "DB_PATH", "`path` of the SQLite database", (*stringValue)(&c.DB)},
+		{"log-file", "LOG_FILE", "`file` logs are appended to", (*stringValue)(&c.LogFile)},
+		{"log-level", "LOG_LEVEL", "least important `level` logged: debug, info, warn or error", (*stringValue)(&c.LogLevel)},
+		{"log-max-size", "LOG_MAX_SIZE", "`megabytes` the log file is rotated at", (*intValue)(&c.LogMaxSize)},
+		{"log-max-backups", "LOG_MAX_BACKUPS", "rotated log files kept", (*intValue)(&c.LogMaxBackups)},
+		{"timeout", "REQUEST_TIMEOUT", "time a request may take", &c.Timeout},
+		{"report-hide-after", "REPORT_HIDE_AFTER", "reporters that hide a post or comment, 0 for never", (*intValue)(&c.ReportHideAfter)},
+	}
+}
+
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	switch {
+	case c.DB == "":
+		return errors.New("db: missing path")
+	case c.LogFile == "":
+		return errors.New("log_file: missing file")
+	case c.LogMaxSize <= 0:
+		return errors.New("log_max_size: must be positive")
+	case c.LogMaxBackups < 0:
+		return errors.New("log_max_backups: must not be negative")
+	case c.Timeout <= 0:
+		return errors.New("timeout: must be positive")
+	case c.ReportHideAfter < 0:
+		return errors.New("report_hide_after: must not be negative")
+	}
+	return nil
+}
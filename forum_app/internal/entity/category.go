@@ -1,14 +1,49 @@
 package entity
 
 type Category struct {
-	Id         int    `json:"id,omitempty"`
-	Title      string `json:"title,omitempty"`
-	Posts      []Post `json:"posts,omitempty"`
-	TotalPosts int    `json:"total_posts,omitempty"`
+	Id            int        `json:"id,omitempty"`
+	Title         string     `json:"title,omitempty"`
+	ParentId      int        `json:"parent_id,omitempty"`
+	Position      int        `json:"position,omitempty"`
+	Archived      bool       `json:"archived,omitempty"`
+	Depth         int        `json:"depth,omitempty"`
+	Subcategories []Category `json:"subcategories,omitempty"`
+	Posts         []Post     `json:"posts,omitempty"`
+	TotalPosts    int        `json:"total_posts,omitempty"`
+	NextCursor    string     `json:"next_cursor,omitempty"`
+	Sort          string     `json:"sort,omitempty"`
+	Limit         int        `json:"limit,omitempty"`
 }
 
-func (c *Category) CountTotals() {
-	c.TotalPosts = len(c.Posts)
+// CategoryChange is an admin's request to create or modify categories,
+// Order lists the ids of the subcategories of ParentId in their new order
+type CategoryChange struct {
+	AdminId int `json:"admin_id,omitempty"`
+	Category
+	Order []int `json:"order,omitempty"`
+}
+
+// BuildCategoryTree nests categories ordered by position under their parents,
+// categories whose parent is missing from the list, e.g. archived, are left out
+func BuildCategoryTree(categories []Category) []Category {
+	children := map[int][]Category{}
+	for _, category := range categories {
+		children[category.ParentId] = append(children[category.ParentId], category)
+	}
+	var build func(parentId, depth int) []Category
+	build = func(parentId, depth int) []Category {
+		nodes := children[parentId]
+		for ix := range nodes {
+			nodes[ix].Depth = depth
+			nodes[ix].Subcategories = build(nodes[ix].Id, depth+1)
+		}
+		return nodes
+	}
+	tree := build(0, 0)
+	if tree == nil {
+		tree = []Category{}
+	}
+	return tree
 }
 
 type CatResult struct {
@@ -10,8 +10,15 @@ type Comment struct {
 	TotalLikes    int        `json:"total_likes,omitempty"`
 	Dislikes      []Reaction `json:"dislikes,omitempty"`
 	TotalDislikes int        `json:"total_dislikes,omitempty"`
+	ParentId      int        `json:"parent_id,omitempty"`
+	Depth         int        `json:"depth,omitempty"`
+	Replies       []Comment  `json:"replies,omitempty"`
+	Hidden        bool       `json:"hidden,omitempty"`
 }
 
+// MaxCommentDepth limits the nesting of replies, deeper replies are attached to the last allowed level
+const MaxCommentDepth = 5
+
 func (c *Comment) CountTotals() {
 	c.TotalLikes = len(c.Likes)
 	c.TotalDislikes = len(c.Dislikes)
@@ -21,3 +28,12 @@ type CommentResult struct {
 	Comment Comment
 	Err     error
 }
+
+// CountComments returns the number of comments in the thread including all replies
+func CountComments(comments []Comment) int {
+	total := len(comments)
+	for _, c := range comments {
+		total += CountComments(c.Replies)
+	}
+	return total
+}
@@ -0,0 +1,64 @@
+package entity
+
+import "strings"
+
+const (
+	DiffEqual  = " "
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff returns a line based diff (longest common subsequence) that turns oldText into newText
+func Diff(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return diff
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -3,8 +3,20 @@ package entity
 import "errors"
 
 var (
-	ErrUserNotFound     = errors.New("user doesn't exist")
-	ErrUserExists       = errors.New("user with a given email already exists")
-	ErrPostNotFound     = errors.New("post doesn't exist")
-	ErrCategoryNotFound = errors.New("category doesn't exist")
+	ErrUserNotFound         = errors.New("user doesn't exist")
+	ErrUserExists           = errors.New("user with a given email already exists")
+	ErrPostNotFound         = errors.New("post doesn't exist")
+	ErrCategoryNotFound     = errors.New("category doesn't exist")
+	ErrInvalidParent        = errors.New("parent comment doesn't belong to the post")
+	ErrInvalidCursor        = errors.New("invalid page cursor")
+	ErrForbidden            = errors.New("user is not allowed to modify the resource")
+	ErrCommentNotFound      = errors.New("comment doesn't exist")
+	ErrPostLocked           = errors.New("post is locked")
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrReportExists         = errors.New("user has already reported the target")
+	ErrCategoryExists       = errors.New("category with a given title already exists")
+	ErrCategoryParent       = errors.New("category can't be nested under itself or its subcategories")
+	ErrCategoryArchived     = errors.New("category is archived")
+	ErrCategoryOrder        = errors.New("order must list every subcategory of the parent once")
+	ErrNotificationNotFound = errors.New("notification doesn't exist")
 )
@@ -0,0 +1,62 @@
+package entity
+
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+
+	ActionHide   = "hide"
+	ActionUnhide = "unhide"
+	ActionLock   = "lock"
+	ActionUnlock = "unlock"
+	ActionDelete = "delete"
+	// ActionDismiss closes the open reports on a target without changing it
+	ActionDismiss = "dismiss"
+
+	ModerationQueueLimit = 50
+	summaryLength        = 100
+)
+
+// Summarize shortens a title or a content for the moderation log
+func Summarize(text string) string {
+	if runes := []rune(text); len(runes) > summaryLength {
+		return string(runes[:summaryLength]) + "…"
+	}
+	return text
+}
+
+// ValidModeration reports whether action applies to target, only posts can be locked
+func ValidModeration(target, action string) bool {
+	switch action {
+	case ActionHide, ActionUnhide, ActionDelete, ActionDismiss:
+		return target == TargetPost || target == TargetComment
+	case ActionLock, ActionUnlock:
+		return target == TargetPost
+	}
+	return false
+}
+
+// ModerationAction is a moderator's decision on a post or a comment, Summary keeps
+// the title or the content of the target so that the log stays readable after a deletion
+type ModerationAction struct {
+	Id        int    `json:"id,omitempty"`
+	Moderator User   `json:"moderator,omitempty"`
+	Target    string `json:"target,omitempty"`
+	TargetId  int    `json:"target_id,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Date      string `json:"date,omitempty"`
+}
+
+// ModerationQueue lists the open reports, the latest posts and comments, hidden ones first, and the latest actions
+type ModerationQueue struct {
+	Reports  []ReportSummary    `json:"reports,omitempty"`
+	Posts    []Post             `json:"posts,omitempty"`
+	Comments []Comment          `json:"comments,omitempty"`
+	Actions  []ModerationAction `json:"actions,omitempty"`
+}
+
+type ModerationQueueResult struct {
+	Queue ModerationQueue
+	Err   error
+}
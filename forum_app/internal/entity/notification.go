@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	NotifyComment     = "comment"
+	NotifyReply       = "reply"
+	NotifyMention     = "mention"
+	NotifyPostLike    = "post_like"
+	NotifyCommentLike = "comment_like"
+
+	// MaxMentions caps the users notified by a single comment
+	MaxMentions = 10
+)
+
+type Notification struct {
+	Id        int    `json:"id,omitempty"`
+	User      User   `json:"user,omitempty"`
+	Actor     User   `json:"actor,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Post      Post   `json:"post,omitempty"`
+	CommentId int    `json:"comment_id,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Read      bool   `json:"read,omitempty"`
+}
+
+type NotificationsRequest struct {
+	UserId int
+	Limit  int
+	Offset int
+}
+
+type Notifications struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int            `json:"unread"`
+	Limit         int            `json:"limit,omitempty"`
+	Offset        int            `json:"offset,omitempty"`
+	NextOffset    int            `json:"next_offset,omitempty"`
+}
+
+type NotificationsResult struct {
+	Notifications Notifications
+	Err           error
+}
+
+type UnreadResult struct {
+	Unread int
+	Err    error
+}
+
+// NotificationRead marks one notification of the user as read, or all of them when Id is 0
+type NotificationRead struct {
+	UserId int `json:"user_id,omitempty"`
+	Id     int `json:"id,omitempty"`
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_.-]+)`)
+
+// Mentions returns the distinct user names mentioned as @name in content, at most MaxMentions of them
+func Mentions(content string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == MaxMentions {
+			break
+		}
+	}
+	return names
+}
@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+const (
+	SortNewest   = "newest"
+	SortOldest   = "oldest"
+	SortLikes    = "likes"
+	SortComments = "comments"
+	SortActive   = "active"
+
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+func ValidSort(sort string) bool {
+	switch sort {
+	case SortNewest, SortOldest, SortLikes, SortComments, SortActive:
+		return true
+	}
+	return false
+}
+
+type PageRequest struct {
+	CategoryId int
+	Limit      int
+	Sort       string
+	Cursor     Cursor
+}
+
+// Cursor points at the last post of the previous page: its sort key and id
+type Cursor struct {
+	Key string
+	Id  int
+}
+
+func (c Cursor) Encode() string {
+	if c.Id == 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Key + "|" + strconv.Itoa(c.Id)))
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	sep := strings.LastIndex(string(raw), "|")
+	if sep < 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(raw[sep+1:]))
+	if err != nil || id <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Key: string(raw[:sep]), Id: id}, nil
+}
+
+type Page struct {
+	Posts      []Post `json:"posts"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+type PageResult struct {
+	Page Page
+	Err  error
+}
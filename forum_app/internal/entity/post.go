@@ -1,24 +1,49 @@
 package entity
 
+import "strconv"
+
+// ActivityLayout is how Post.Activity is stored, to the second so that the active sort follows the order of the posts and comments
+const ActivityLayout = "2006-01-02 15:04:05"
+
 type Post struct {
-	Id            int        `json:"id,omitempty"`
-	User          User       `json:"user,omitempty"`
-	Date          string     `json:"date,omitempty"`
-	Title         string     `json:"title,omitempty"`
-	Content       string     `json:"content,omitempty"`
-	Category      []Category `json:"categories,omitempty"`
-	Comments      []Comment  `json:"comments,omitempty"`
-	TotalComments int        `json:"total_comments,omitempty"`
-	Likes         []Reaction `json:"likes,omitempty"`
-	TotalLikes    int        `json:"total_likes,omitempty"`
-	Dislikes      []Reaction `json:"dislkes,omitempty"`
-	TotalDislikes int        `json:"total_dislikes,omitempty"`
+	Id             int        `json:"id,omitempty"`
+	User           User       `json:"user,omitempty"`
+	Date           string     `json:"date,omitempty"`
+	Title          string     `json:"title,omitempty"`
+	Content        string     `json:"content,omitempty"`
+	Category       []Category `json:"categories,omitempty"`
+	Comments       []Comment  `json:"comments,omitempty"`
+	TotalComments  int        `json:"total_comments,omitempty"`
+	Likes          []Reaction `json:"likes,omitempty"`
+	TotalLikes     int        `json:"total_likes,omitempty"`
+	Dislikes       []Reaction `json:"dislkes,omitempty"`
+	TotalDislikes  int        `json:"total_dislikes,omitempty"`
+	Revisions      []Revision `json:"revisions,omitempty"`
+	TotalRevisions int        `json:"total_revisions,omitempty"`
+	EditDate       string     `json:"edit_date,omitempty"`
+	Activity       string     `json:"activity,omitempty"`
+	Hidden         bool       `json:"hidden,omitempty"`
+	Locked         bool       `json:"locked,omitempty"`
 }
 
 func (p *Post) CountTotals() {
-	p.TotalComments = len(p.Comments)
+	p.TotalComments = CountComments(p.Comments)
 	p.TotalLikes = len(p.Likes)
 	p.TotalDislikes = len(p.Dislikes)
+	p.TotalRevisions = len(p.Revisions)
+}
+
+// CursorKey returns the value the post is ordered by in listings sorted by sort
+func (p *Post) CursorKey(sort string) string {
+	switch sort {
+	case SortLikes:
+		return strconv.Itoa(p.TotalLikes)
+	case SortComments:
+		return strconv.Itoa(p.TotalComments)
+	case SortActive:
+		return p.Activity
+	}
+	return ""
 }
 
 type PostResult struct {
@@ -26,7 +51,17 @@ type PostResult struct {
 	Err  error
 }
 
-type PostsResult struct {
-	Posts []Post
-	Err   error
+// Revision is a replaced version of a post, Changes holds the diff to the version that replaced it
+type Revision struct {
+	Id      int        `json:"id,omitempty"`
+	PostId  int        `json:"post_id,omitempty"`
+	Date    string     `json:"date,omitempty"`
+	Title   string     `json:"title,omitempty"`
+	Content string     `json:"content,omitempty"`
+	Changes []DiffLine `json:"changes,omitempty"`
+}
+
+type RevisionsResult struct {
+	Revisions []Revision
+	Err       error
 }
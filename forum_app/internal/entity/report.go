@@ -0,0 +1,58 @@
+package entity
+
+const (
+	ReasonSpam       = "spam"
+	ReasonAbuse      = "abuse"
+	ReasonOffTopic   = "off_topic"
+	ReasonIllegal    = "illegal"
+	ReasonOther      = "other"
+	DefaultHideAfter = 3
+)
+
+var reportReasons = map[string]bool{
+	ReasonSpam:     true,
+	ReasonAbuse:    true,
+	ReasonOffTopic: true,
+	ReasonIllegal:  true,
+	ReasonOther:    true,
+}
+
+func ValidReportReason(reason string) bool {
+	return reportReasons[reason]
+}
+
+type Report struct {
+	Id       int    `json:"id,omitempty"`
+	Reporter User   `json:"reporter,omitempty"`
+	Target   string `json:"target,omitempty"`
+	TargetId int    `json:"target_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Details  string `json:"details,omitempty"`
+	Date     string `json:"date,omitempty"`
+}
+
+// ReportSummary groups the open reports on a post or a comment for review,
+// Reasons counts reporters per reason category
+type ReportSummary struct {
+	Target    string         `json:"target,omitempty"`
+	TargetId  int            `json:"target_id,omitempty"`
+	PostId    int            `json:"post_id,omitempty"`
+	Summary   string         `json:"summary,omitempty"`
+	Author    User           `json:"author,omitempty"`
+	Hidden    bool           `json:"hidden,omitempty"`
+	Reporters int            `json:"reporters,omitempty"`
+	Reasons   map[string]int `json:"reasons,omitempty"`
+	Details   []string       `json:"details,omitempty"`
+	LastDate  string         `json:"last_date,omitempty"`
+}
+
+// ReportOutcome tells the reporter whether their report pushed the target over the threshold
+type ReportOutcome struct {
+	Id     int64 `json:"id,omitempty"`
+	Hidden bool  `json:"hidden,omitempty"`
+}
+
+type ReportResult struct {
+	Outcome ReportOutcome
+	Err     error
+}
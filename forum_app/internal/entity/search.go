@@ -0,0 +1,51 @@
+package entity
+
+import "strings"
+
+// Snippets mark matched terms with these control characters so that clients can highlight them after escaping
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+type SearchRequest struct {
+	Query      string
+	CategoryId int
+	Author     string
+	From       string
+	To         string
+	Limit      int
+	Offset     int
+}
+
+// MatchQuery turns free text into an FTS5 query where every word is a quoted term
+func (s SearchRequest) MatchQuery() string {
+	terms := []string{}
+	for _, word := range strings.Fields(s.Query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+type SearchHit struct {
+	Kind      string  `json:"kind"`
+	Post      Post    `json:"post"`
+	CommentId int     `json:"comment_id,omitempty"`
+	User      User    `json:"user"`
+	Date      string  `json:"date,omitempty"`
+	Snippet   string  `json:"snippet"`
+	Rank      float64 `json:"rank"`
+}
+
+type Search struct {
+	Hits       []SearchHit `json:"hits"`
+	Query      string      `json:"query"`
+	Limit      int         `json:"limit,omitempty"`
+	Offset     int         `json:"offset,omitempty"`
+	NextOffset int         `json:"next_offset,omitempty"`
+}
+
+type SearchResult struct {
+	Search Search
+	Err    error
+}
@@ -6,6 +6,8 @@ type User struct {
 	Email                string            `json:"email,omitempty"`
 	Password             string            `json:"password,omitempty"`
 	RegDate              string            `json:"registration_date,omitempty"`
+	Role                 string            `json:"role,omitempty"`
+	Verified             bool              `json:"email_verified,omitempty"`
 	Posts                []Post            `json:"posts,omitempty"`
 	TotalPosts           int               `json:"total_posts,omitempty"`
 	Comments             []Comment         `json:"comments,omitempty"`
@@ -29,6 +31,32 @@ func (u *User) CountTotals() {
 	u.TotalCommentDislikes = len(u.CommentDislikes)
 }
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders the roles, every role has the powers of the roles below it
+var roleRanks = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role has at least the powers of min
+func HasRole(role, min string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[min]
+}
+
+// RoleUpdate is an administrator's request to change the role of a user
+type RoleUpdate struct {
+	AdminId int    `json:"admin_id,omitempty"`
+	UserId  int    `json:"user_id,omitempty"`
+	Role    string `json:"role,omitempty"`
+}
+
 type Result struct {
 	Id  int64
 	Err error
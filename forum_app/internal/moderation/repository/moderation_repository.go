@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"forum_app/internal/entity"
+	"log"
+	"sort"
+	"time"
+)
+
+type ModerationRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewModerationRepository(db *sql.DB, errorLog *log.Logger) *ModerationRepository {
+	return &ModerationRepository{db, errorLog}
+}
+
+// moderationQueries maps a target and an action to the statement applying it, dismissing only resolves the reports
+var moderationQueries = map[string]map[string]string{
+	entity.TargetPost: {
+		entity.ActionHide:   "UPDATE posts SET hidden = 1 WHERE id = ?;",
+		entity.ActionUnhide: "UPDATE posts SET hidden = 0 WHERE id = ?;",
+		entity.ActionLock:   "UPDATE posts SET locked = 1 WHERE id = ?;",
+		entity.ActionUnlock: "UPDATE posts SET locked = 0 WHERE id = ?;",
+		entity.ActionDelete: "DELETE FROM posts WHERE id = ?;",
+	},
+	entity.TargetComment: {
+		entity.ActionHide:   "UPDATE comments SET hidden = 1 WHERE id = ?;",
+		entity.ActionUnhide: "UPDATE comments SET hidden = 0 WHERE id = ?;",
+		entity.ActionDelete: "DELETE FROM comments WHERE id = ?;",
+	},
+}
+
+// Apply carries out the action and records it in the moderation log in the same transaction
+func (mr *ModerationRepository) Apply(ctx context.Context, action entity.ModerationAction) error {
+	tx, err := mr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	var summary string
+	if action.Target == entity.TargetPost {
+		err = tx.QueryRowContext(ctx, "SELECT title FROM posts WHERE id = ?;", action.TargetId).Scan(&summary)
+		if err == sql.ErrNoRows {
+			return entity.ErrPostNotFound
+		}
+	} else {
+		err = tx.QueryRowContext(ctx, "SELECT content FROM comments WHERE id = ?;", action.TargetId).Scan(&summary)
+		if err == sql.ErrNoRows {
+			return entity.ErrCommentNotFound
+		}
+	}
+	if err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	if query, ok := moderationQueries[action.Target][action.Action]; ok {
+		if _, err = tx.ExecContext(ctx, query, action.TargetId); err != nil {
+			mr.errorLog.Println(err)
+			return err
+		}
+	}
+	// whatever the decision, the reports on the target have been reviewed
+	if _, err = tx.ExecContext(ctx, "UPDATE reports SET resolved = 1 WHERE target = ? AND target_id = ? AND resolved = 0;", action.Target, action.TargetId); err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO moderation_actions(moderator_id, target, target_id, action, reason, summary, date) VALUES(?,?,?,?,?,?,?);`)
+	if err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, action.Moderator.Id, action.Target, action.TargetId, action.Action, action.Reason, entity.Summarize(summary), time.Now().Format("2006-01-02 15:04"))
+	if err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (mr *ModerationRepository) FetchQueue(ctx context.Context, limit int) (entity.ModerationQueue, error) {
+	queue := entity.ModerationQueue{}
+	tx, err := mr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		mr.errorLog.Println(err)
+		return queue, err
+	}
+	defer tx.Rollback()
+	if queue.Reports, err = mr.fetchReports(ctx, tx, limit); err != nil {
+		mr.errorLog.Println(err)
+		return entity.ModerationQueue{}, err
+	}
+	if queue.Posts, err = mr.fetchQueuePosts(ctx, tx, limit); err != nil {
+		mr.errorLog.Println(err)
+		return entity.ModerationQueue{}, err
+	}
+	if queue.Comments, err = mr.fetchQueueComments(ctx, tx, limit); err != nil {
+		mr.errorLog.Println(err)
+		return entity.ModerationQueue{}, err
+	}
+	if queue.Actions, err = mr.fetchActions(ctx, tx, limit); err != nil {
+		mr.errorLog.Println(err)
+		return entity.ModerationQueue{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		mr.errorLog.Println(err)
+		return entity.ModerationQueue{}, err
+	}
+	return queue, nil
+}
+
+func (mr *ModerationRepository) fetchQueuePosts(ctx context.Context, tx *sql.Tx, limit int) ([]entity.Post, error) {
+	posts := []entity.Post{}
+	rows, err := tx.QueryContext(ctx, `SELECT p.id, p.user_id, u.name, p.date, p.title, p.content, p.hidden, p.locked
+	FROM posts AS p JOIN users AS u ON u.id = p.user_id
+	ORDER BY p.hidden DESC, p.id DESC LIMIT ?;`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		post := entity.Post{}
+		rows.Scan(&post.Id, &post.User.Id, &post.User.Name, &post.Date, &post.Title, &post.Content, &post.Hidden, &post.Locked)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+func (mr *ModerationRepository) fetchQueueComments(ctx context.Context, tx *sql.Tx, limit int) ([]entity.Comment, error) {
+	comments := []entity.Comment{}
+	rows, err := tx.QueryContext(ctx, `SELECT c.id, c.post_id, p.title, c.user_id, u.name, c.date, c.content, c.hidden
+	FROM comments AS c JOIN posts AS p ON p.id = c.post_id JOIN users AS u ON u.id = c.user_id
+	ORDER BY c.hidden DESC, c.id DESC LIMIT ?;`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		comment := entity.Comment{}
+		rows.Scan(&comment.Id, &comment.Post.Id, &comment.Post.Title, &comment.User.Id, &comment.User.Name, &comment.Date, &comment.Content, &comment.Hidden)
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (mr *ModerationRepository) fetchActions(ctx context.Context, tx *sql.Tx, limit int) ([]entity.ModerationAction, error) {
+	actions := []entity.ModerationAction{}
+	rows, err := tx.QueryContext(ctx, `SELECT a.id, COALESCE(a.moderator_id, 0), COALESCE(u.name, ''), a.target, a.target_id, a.action, a.reason, COALESCE(a.summary, ''), a.date
+	FROM moderation_actions AS a LEFT JOIN users AS u ON u.id = a.moderator_id
+	ORDER BY a.id DESC LIMIT ?;`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		action := entity.ModerationAction{}
+		rows.Scan(&action.Id, &action.Moderator.Id, &action.Moderator.Name, &action.Target, &action.TargetId, &action.Action, &action.Reason, &action.Summary, &action.Date)
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
+
+// fetchReports groups the open reports by target, the most reported first
+func (mr *ModerationRepository) fetchReports(ctx context.Context, tx *sql.Tx, limit int) ([]entity.ReportSummary, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT r.target, r.target_id, r.reason, COALESCE(r.details, ''), r.date,
+	COALESCE(p.id, c.post_id, 0), COALESCE(p.title, c.content, ''), COALESCE(pu.id, cu.id, 0), COALESCE(pu.name, cu.name, ''), COALESCE(p.hidden, c.hidden, 0)
+	FROM reports AS r
+	LEFT JOIN posts AS p ON r.target = 'post' AND p.id = r.target_id LEFT JOIN users AS pu ON pu.id = p.user_id
+	LEFT JOIN comments AS c ON r.target = 'comment' AND c.id = r.target_id LEFT JOIN users AS cu ON cu.id = c.user_id
+	WHERE r.resolved = 0 ORDER BY r.id;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reports := []entity.ReportSummary{}
+	index := map[string]int{}
+	for rows.Next() {
+		var (
+			report          entity.ReportSummary
+			reason, details string
+			date            string
+		)
+		rows.Scan(&report.Target, &report.TargetId, &reason, &details, &date, &report.PostId, &report.Summary, &report.Author.Id, &report.Author.Name, &report.Hidden)
+		key := fmt.Sprintf("%s:%d", report.Target, report.TargetId)
+		ix, ok := index[key]
+		if !ok {
+			report.Summary = entity.Summarize(report.Summary)
+			report.Reasons = map[string]int{}
+			reports = append(reports, report)
+			ix = len(reports) - 1
+			index[key] = ix
+		}
+		reports[ix].Reporters++
+		reports[ix].Reasons[reason]++
+		reports[ix].LastDate = date
+		if details != "" {
+			reports[ix].Details = append(reports[ix].Details, details)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Reporters != reports[j].Reporters {
+			return reports[i].Reporters > reports[j].Reporters
+		}
+		return reports[i].LastDate > reports[j].LastDate
+	})
+	if len(reports) > limit {
+		reports = reports[:limit]
+	}
+	return reports, nil
+}
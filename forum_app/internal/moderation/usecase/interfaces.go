@@ -0,0 +1,15 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+)
+
+type ModerationRepository interface {
+	Apply(context.Context, entity.ModerationAction) error
+	FetchQueue(context.Context, int) (entity.ModerationQueue, error)
+}
+
+type UsersRepository interface {
+	FetchById(context.Context, int) (entity.User, error)
+}
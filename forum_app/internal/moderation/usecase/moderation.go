@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type ModerationUsecase struct {
+	moderationRepo ModerationRepository
+	usersRepo      UsersRepository
+	errorLog       *log.Logger
+}
+
+func NewModerationUsecase(moderationRepo ModerationRepository, usersRepo UsersRepository, errorLog *log.Logger) *ModerationUsecase {
+	return &ModerationUsecase{
+		moderationRepo: moderationRepo,
+		usersRepo:      usersRepo,
+		errorLog:       errorLog,
+	}
+}
+
+func (mu *ModerationUsecase) Apply(ctx context.Context, action entity.ModerationAction, err chan error) {
+	if e := mu.checkModerator(ctx, action.Moderator.Id); e != nil {
+		err <- e
+		return
+	}
+	err <- mu.moderationRepo.Apply(ctx, action)
+}
+
+func (mu *ModerationUsecase) FetchQueue(ctx context.Context, moderatorId int, queueRes chan entity.ModerationQueueResult) {
+	if err := mu.checkModerator(ctx, moderatorId); err != nil {
+		queueRes <- entity.ModerationQueueResult{Err: err}
+		return
+	}
+	queue, err := mu.moderationRepo.FetchQueue(ctx, entity.ModerationQueueLimit)
+	queueRes <- entity.ModerationQueueResult{Queue: queue, Err: err}
+}
+
+// checkModerator looks the role up in the database rather than trusting the session,
+// so that a demoted moderator loses their powers at once
+func (mu *ModerationUsecase) checkModerator(ctx context.Context, id int) error {
+	user, err := mu.usersRepo.FetchById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Id == 0 || !entity.HasRole(user.Role, entity.RoleModerator) {
+		return entity.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum_app/internal/entity"
+	"log"
+	"strings"
+	"time"
+)
+
+type NotificationsRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewNotificationsRepository(db *sql.DB, errorLog *log.Logger) *NotificationsRepository {
+	return &NotificationsRepository{db, errorLog}
+}
+
+// Store skips the notifications the user already got, a like of the same actor is announced only once
+func (nr *NotificationsRepository) Store(ctx context.Context, notifications []entity.Notification) error {
+	tx, err := nr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO notifications(user_id, actor_id, kind, post_id, comment_id, date) VALUES(?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?) ON CONFLICT DO NOTHING;`)
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	date := time.Now().Format("2006-01-02 15:04")
+	for _, n := range notifications {
+		if _, err = stmt.ExecContext(ctx, n.User.Id, n.Actor.Id, n.Kind, n.Post.Id, n.CommentId, date); err != nil {
+			nr.errorLog.Println(err)
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// StoreMentions notifies the users with the given names, except the ones in skip
+// who already got a notification about the comment. Names aren't unique, a name held by several users
+// doesn't tell who was meant and notifies no one
+func (nr *NotificationsRepository) StoreMentions(ctx context.Context, mention entity.Notification, names []string, skip []int) error {
+	if len(names) == 0 {
+		return nil
+	}
+	args := []interface{}{mention.Actor.Id, entity.NotifyMention, mention.Post.Id, mention.CommentId, time.Now().Format("2006-01-02 15:04")}
+	for _, name := range names {
+		args = append(args, name)
+	}
+	for _, id := range skip {
+		args = append(args, id)
+	}
+	query := `INSERT INTO notifications(user_id, actor_id, kind, post_id, comment_id, date)
+	SELECT id, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ? FROM users WHERE name IN (
+		SELECT name FROM users WHERE name IN (` + placeholders(len(names)) + `) GROUP BY name HAVING COUNT(*) = 1
+	)`
+	if len(skip) != 0 {
+		query += ` AND id NOT IN (` + placeholders(len(skip)) + `)`
+	}
+	tx, err := nr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// FetchByUserId lists the notifications of the user, newest first, leaving out the ones about hidden posts
+func (nr *NotificationsRepository) FetchByUserId(ctx context.Context, req entity.NotificationsRequest) ([]entity.Notification, error) {
+	notifications := []entity.Notification{}
+	tx, err := nr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		nr.errorLog.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `SELECT n.id, COALESCE(n.actor_id, 0), COALESCE(u.name, ''), n.kind, COALESCE(n.post_id, 0), COALESCE(p.title, ''), COALESCE(n.comment_id, 0), n.date, n.read
+	FROM notifications AS n
+	LEFT JOIN users AS u ON u.id = n.actor_id
+	LEFT JOIN posts AS p ON p.id = n.post_id
+	WHERE n.user_id = ? AND COALESCE(p.hidden, 0) = 0
+	ORDER BY n.id DESC LIMIT ? OFFSET ?;`)
+	if err != nil {
+		nr.errorLog.Println(err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, req.UserId, req.Limit, req.Offset)
+	if err != nil {
+		nr.errorLog.Println(err)
+		return nil, err
+	}
+	for rows.Next() {
+		n := entity.Notification{User: entity.User{Id: req.UserId}}
+		rows.Scan(&n.Id, &n.Actor.Id, &n.Actor.Name, &n.Kind, &n.Post.Id, &n.Post.Title, &n.CommentId, &n.Date, &n.Read)
+		notifications = append(notifications, n)
+	}
+	if err = tx.Commit(); err != nil {
+		nr.errorLog.Println(err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (nr *NotificationsRepository) CountUnread(ctx context.Context, userId int) (int, error) {
+	var unread int
+	tx, err := nr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		nr.errorLog.Println(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications AS n LEFT JOIN posts AS p ON p.id = n.post_id
+	WHERE n.user_id = ? AND n.read = 0 AND COALESCE(p.hidden, 0) = 0;`, userId).Scan(&unread)
+	if err != nil {
+		nr.errorLog.Println(err)
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		nr.errorLog.Println(err)
+		return 0, err
+	}
+	return unread, nil
+}
+
+// MarkRead marks a notification of the user as read, or all of them when id is 0
+func (nr *NotificationsRepository) MarkRead(ctx context.Context, userId, id int) error {
+	tx, err := nr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "UPDATE notifications SET read = 1 WHERE user_id = ? AND (? = 0 OR id = ?);")
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, userId, id, id)
+	if err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		nr.errorLog.Println(err)
+		return err
+	} else if n == 0 && id != 0 {
+		return entity.ErrNotificationNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		nr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
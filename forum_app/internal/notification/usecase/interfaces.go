@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+)
+
+type NotificationsRepository interface {
+	FetchByUserId(context.Context, entity.NotificationsRequest) ([]entity.Notification, error)
+	CountUnread(context.Context, int) (int, error)
+	MarkRead(context.Context, int, int) error
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type NotificationsUsecase struct {
+	notificationsRepo NotificationsRepository
+	errorLog          *log.Logger
+}
+
+func NewNotificationsUsecase(notificationsRepo NotificationsRepository, errorLog *log.Logger) *NotificationsUsecase {
+	return &NotificationsUsecase{
+		notificationsRepo: notificationsRepo,
+		errorLog:          errorLog,
+	}
+}
+
+func (nu *NotificationsUsecase) Fetch(ctx context.Context, req entity.NotificationsRequest, notificationsRes chan entity.NotificationsResult) {
+	// one extra row tells whether there is a next page
+	limit := req.Limit
+	req.Limit++
+	notifications, err := nu.notificationsRepo.FetchByUserId(ctx, req)
+	if err != nil {
+		notificationsRes <- entity.NotificationsResult{Err: err}
+		return
+	}
+	result := entity.Notifications{Notifications: notifications, Limit: limit, Offset: req.Offset}
+	if len(notifications) > limit {
+		result.Notifications = notifications[:limit]
+		result.NextOffset = req.Offset + limit
+	}
+	result.Unread, err = nu.notificationsRepo.CountUnread(ctx, req.UserId)
+	notificationsRes <- entity.NotificationsResult{Notifications: result, Err: err}
+}
+
+func (nu *NotificationsUsecase) CountUnread(ctx context.Context, userId int, unreadRes chan entity.UnreadResult) {
+	unread, err := nu.notificationsRepo.CountUnread(ctx, userId)
+	unreadRes <- entity.UnreadResult{Unread: unread, Err: err}
+}
+
+func (nu *NotificationsUsecase) MarkRead(ctx context.Context, read entity.NotificationRead, err chan error) {
+	err <- nu.notificationsRepo.MarkRead(ctx, read.UserId, read.Id)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"forum_app/internal/entity"
 	"log"
+	"strings"
 )
 
 type CategoriesRepository struct {
@@ -24,7 +25,7 @@ func (cr *CategoriesRepository) FetchById(ctx context.Context, id int) (entity.C
 		return entity.Category{}, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM categories WHERE id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, title, COALESCE(parent_id, 0), position, archived FROM categories WHERE id = ?;")
 	if err != nil {
 		cr.errorLog.Println(err)
 		return entity.Category{}, err
@@ -36,7 +37,7 @@ func (cr *CategoriesRepository) FetchById(ctx context.Context, id int) (entity.C
 		return entity.Category{}, err
 	}
 	if rows.Next() {
-		rows.Scan(&category.Id, &category.Title)
+		rows.Scan(&category.Id, &category.Title, &category.ParentId, &category.Position, &category.Archived)
 	}
 	if err = tx.Commit(); err != nil {
 		cr.errorLog.Println(err)
@@ -45,7 +46,8 @@ func (cr *CategoriesRepository) FetchById(ctx context.Context, id int) (entity.C
 	return category, nil
 }
 
-func (cr *CategoriesRepository) FetchAllCategories(ctx context.Context) ([]entity.Category, error) {
+// FetchAllCategories returns the category tree with the number of visible posts in every category
+func (cr *CategoriesRepository) FetchAllCategories(ctx context.Context, archived bool) ([]entity.Category, error) {
 	categories := []entity.Category{}
 	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
@@ -53,27 +55,33 @@ func (cr *CategoriesRepository) FetchAllCategories(ctx context.Context) ([]entit
 		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM categories")
+	stmt, err := tx.PrepareContext(ctx, `SELECT c.id, c.title, COALESCE(c.parent_id, 0), c.position, c.archived, COUNT(p.id)
+	FROM categories AS c
+	LEFT JOIN post_categories AS pc ON pc.category_id = c.id
+	LEFT JOIN posts AS p ON p.id = pc.post_id AND p.hidden = 0
+	WHERE ? OR c.archived = 0
+	GROUP BY c.id
+	ORDER BY c.position, c.id;`)
 	if err != nil {
 		cr.errorLog.Println(err)
 		return nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, archived)
 	if err != nil {
 		cr.errorLog.Println(err)
 		return nil, err
 	}
 	for rows.Next() {
 		category := entity.Category{}
-		rows.Scan(&category.Id, &category.Title)
+		rows.Scan(&category.Id, &category.Title, &category.ParentId, &category.Position, &category.Archived, &category.TotalPosts)
 		categories = append(categories, category)
 	}
 	if err = tx.Commit(); err != nil {
 		cr.errorLog.Println(err)
 		return nil, err
 	}
-	return categories, nil
+	return entity.BuildCategoryTree(categories), nil
 }
 
 func (cr *CategoriesRepository) FetchByPostId(ctx context.Context, id int) ([]entity.Category, error) {
@@ -106,3 +114,183 @@ func (cr *CategoriesRepository) FetchByPostId(ctx context.Context, id int) ([]en
 	}
 	return categories, nil
 }
+
+// Store appends the category to the end of its parent's subcategories
+func (cr *CategoriesRepository) Store(ctx context.Context, category entity.Category) (int64, error) {
+	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		cr.errorLog.Println(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err = checkParent(ctx, tx, 0, category.ParentId); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO categories(title, parent_id, position)
+	VALUES(?, NULLIF(?, 0), (SELECT COALESCE(MAX(position), 0) + 1 FROM categories WHERE COALESCE(parent_id, 0) = ?));`)
+	if err != nil {
+		cr.errorLog.Println(err)
+		return 0, err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, category.Title, category.ParentId, category.ParentId)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: categories.title") {
+			return 0, entity.ErrCategoryExists
+		}
+		cr.errorLog.Println(err)
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		cr.errorLog.Println(err)
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Update renames the category and moves it under ParentId, a moved category goes to the end of its new siblings
+func (cr *CategoriesRepository) Update(ctx context.Context, category entity.Category) error {
+	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	var parentId int
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(parent_id, 0) FROM categories WHERE id = ?;", category.Id).Scan(&parentId)
+	if err == sql.ErrNoRows {
+		return entity.ErrCategoryNotFound
+	} else if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	if err = checkParent(ctx, tx, category.Id, category.ParentId); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `UPDATE categories SET title = ?, parent_id = NULLIF(?, 0),
+	position = CASE WHEN ? THEN position ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM categories WHERE COALESCE(parent_id, 0) = ?) END
+	WHERE id = ?;`)
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, category.Title, category.ParentId, parentId == category.ParentId, category.ParentId, category.Id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: categories.title") {
+			return entity.ErrCategoryExists
+		}
+		cr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Reorder sets the positions of the subcategories of parentId, order has to list all of them
+func (cr *CategoriesRepository) Reorder(ctx context.Context, parentId int, order []int) error {
+	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM categories WHERE COALESCE(parent_id, 0) = ?;", parentId)
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	siblings := map[int]bool{}
+	for rows.Next() {
+		var id int
+		rows.Scan(&id)
+		siblings[id] = true
+	}
+	rows.Close()
+	if len(order) != len(siblings) {
+		return entity.ErrCategoryOrder
+	}
+	stmt, err := tx.PrepareContext(ctx, "UPDATE categories SET position = ? WHERE id = ?;")
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	for position, id := range order {
+		if !siblings[id] {
+			return entity.ErrCategoryOrder
+		}
+		delete(siblings, id)
+		if _, err = stmt.ExecContext(ctx, position+1, id); err != nil {
+			cr.errorLog.Println(err)
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// SetArchived archives or restores a category, archived categories and their subcategories
+// are left out of the listing and don't take new posts, their posts stay visible
+func (cr *CategoriesRepository) SetArchived(ctx context.Context, id int, archived bool) error {
+	tx, err := cr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "UPDATE categories SET archived = ? WHERE id = ?;")
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, archived, id)
+	if err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		cr.errorLog.Println(err)
+		return err
+	} else if n == 0 {
+		return entity.ErrCategoryNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		cr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// checkParent makes sure the parent exists and, when id is set, isn't the category itself or one of its subcategories
+func checkParent(ctx context.Context, tx *sql.Tx, id, parentId int) error {
+	if parentId == 0 {
+		return nil
+	}
+	if id == parentId {
+		return entity.ErrCategoryParent
+	}
+	var found, cycle bool
+	err := tx.QueryRowContext(ctx, `WITH RECURSIVE ancestors(id) AS (
+		SELECT id FROM categories WHERE id = ?
+		UNION SELECT c.parent_id FROM categories AS c JOIN ancestors AS a ON c.id = a.id WHERE c.parent_id IS NOT NULL
+	)
+	SELECT COUNT(*) > 0, COALESCE(SUM(id = ?), 0) > 0 FROM ancestors;`, parentId, id).Scan(&found, &cycle)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return entity.ErrCategoryNotFound
+	}
+	if cycle {
+		return entity.ErrCategoryParent
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"forum_app/internal/entity"
 	"log"
+	"strconv"
 	"time"
 )
 
@@ -25,7 +27,7 @@ func (pr *PostsRepository) FetchById(ctx context.Context, id int) (entity.Post,
 		return post, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM posts WHERE id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, user_id, date, title, content, hidden, locked FROM posts WHERE id = ?;")
 	if err != nil {
 		pr.errorLog.Println(err)
 		return post, err
@@ -37,7 +39,7 @@ func (pr *PostsRepository) FetchById(ctx context.Context, id int) (entity.Post,
 		return post, err
 	}
 	if rows.Next() {
-		rows.Scan(&post.Id, &post.User.Id, &post.Date, &post.Title, &post.Content)
+		rows.Scan(&post.Id, &post.User.Id, &post.Date, &post.Title, &post.Content, &post.Hidden, &post.Locked)
 	}
 	if err = tx.Commit(); err != nil {
 		pr.errorLog.Println(err)
@@ -46,28 +48,87 @@ func (pr *PostsRepository) FetchById(ctx context.Context, id int) (entity.Post,
 	return post, nil
 }
 
-func (pr *PostsRepository) FetchAll(ctx context.Context) ([]entity.Post, error) {
+// pageOrders maps a listing sort to the column it is ordered by and the direction,
+// ties are broken by the post id in the same direction
+var pageOrders = map[string]struct {
+	key string
+	asc bool
+}{
+	entity.SortNewest:   {"", false},
+	entity.SortOldest:   {"", true},
+	entity.SortLikes:    {"t.likes", false},
+	entity.SortComments: {"t.comments", false},
+	entity.SortActive:   {"t.activity", false},
+}
+
+// FetchPage returns up to page.Limit+1 posts following page.Cursor, the extra post tells that a next page exists
+func (pr *PostsRepository) FetchPage(ctx context.Context, page entity.PageRequest) ([]entity.Post, error) {
 	posts := []entity.Post{}
+	order := pageOrders[page.Sort]
+	var (
+		args    []interface{}
+		filter  = " WHERE p.hidden = 0"
+		after   string
+		orderBy string
+		cmp     = "<"
+		dir     = "DESC"
+	)
+	if order.asc {
+		cmp, dir = ">", "ASC"
+	}
+	if page.CategoryId != 0 {
+		filter += " AND p.id IN (SELECT post_id FROM post_categories WHERE category_id = ?)"
+		args = append(args, page.CategoryId)
+	}
+	if order.key == "" {
+		orderBy = fmt.Sprintf("t.id %s", dir)
+		if page.Cursor.Id != 0 {
+			after = fmt.Sprintf(" WHERE t.id %s ?", cmp)
+			args = append(args, page.Cursor.Id)
+		}
+	} else {
+		orderBy = fmt.Sprintf("%s %s, t.id %s", order.key, dir, dir)
+		if page.Cursor.Id != 0 {
+			var key interface{} = page.Cursor.Key
+			if page.Sort != entity.SortActive {
+				n, err := strconv.Atoi(page.Cursor.Key)
+				if err != nil {
+					return nil, entity.ErrInvalidCursor
+				}
+				key = n
+			}
+			after = fmt.Sprintf(" WHERE (%[1]s %[2]s ? OR (%[1]s = ? AND t.id %[2]s ?))", order.key, cmp)
+			args = append(args, key, key, page.Cursor.Id)
+		}
+	}
+	args = append(args, page.Limit+1)
+	query := fmt.Sprintf(`SELECT t.id, t.user_id, t.date, t.title, t.content, t.locked, t.likes, t.dislikes, t.comments, t.activity FROM (
+		SELECT p.id, p.user_id, p.date, p.title, p.content, p.locked,
+			(SELECT COUNT(*) FROM post_reactions WHERE post_id = p.id AND like = 1) AS likes,
+			(SELECT COUNT(*) FROM post_reactions WHERE post_id = p.id AND like = 0) AS dislikes,
+			(SELECT COUNT(*) FROM comments WHERE post_id = p.id) AS comments,
+			p.last_activity AS activity
+		FROM posts AS p%s) AS t%s ORDER BY %s LIMIT ?;`, filter, after, orderBy)
 	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		pr.errorLog.Println(err)
 		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM posts;")
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		pr.errorLog.Println(err)
 		return nil, err
 	}
 	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx)
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		pr.errorLog.Println(err)
 		return nil, err
 	}
 	for rows.Next() {
 		post := entity.Post{}
-		rows.Scan(&post.Id, &post.User.Id, &post.Date, &post.Title, &post.Content)
+		rows.Scan(&post.Id, &post.User.Id, &post.Date, &post.Title, &post.Content, &post.Locked, &post.TotalLikes, &post.TotalDislikes, &post.TotalComments, &post.Activity)
 		posts = append(posts, post)
 	}
 	if err = tx.Commit(); err != nil {
@@ -85,7 +146,7 @@ func (pr *PostsRepository) FetchByUserId(ctx context.Context, id int) ([]entity.
 		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM posts WHERE user_id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, user_id, date, title, content FROM posts WHERE user_id = ? AND hidden = 0;")
 	if err != nil {
 		pr.errorLog.Println(err)
 		return nil, err
@@ -108,35 +169,24 @@ func (pr *PostsRepository) FetchByUserId(ctx context.Context, id int) ([]entity.
 	return posts, nil
 }
 
-func (pr *PostsRepository) FetchByCategoryId(ctx context.Context, id int) ([]entity.Post, error) {
-	posts := []entity.Post{}
+func (pr *PostsRepository) CountByCategoryId(ctx context.Context, id int) (int, error) {
+	var total int
 	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		pr.errorLog.Println(err)
-		return nil, err
+		return 0, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT post_id FROM post_categories WHERE category_id = ?")
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM post_categories AS pc JOIN posts AS p ON p.id = pc.post_id WHERE pc.category_id = ? AND p.hidden = 0;", id).Scan(&total)
 	if err != nil {
 		pr.errorLog.Println(err)
-		return nil, err
-	}
-	defer stmt.Close()
-	rows, err := stmt.QueryContext(ctx, id)
-	if err != nil {
-		pr.errorLog.Println(err)
-		return nil, err
-	}
-	for rows.Next() {
-		post := entity.Post{}
-		rows.Scan(&post.Id)
-		posts = append(posts, post)
+		return 0, err
 	}
 	if err = tx.Commit(); err != nil {
 		pr.errorLog.Println(err)
-		return nil, err
+		return 0, err
 	}
-	return posts, nil
+	return total, nil
 }
 
 func (pr *PostsRepository) Store(ctx context.Context, post entity.Post) (int64, error) {
@@ -148,14 +198,15 @@ func (pr *PostsRepository) Store(ctx context.Context, post entity.Post) (int64,
 	defer tx.Rollback()
 	stmt, err := tx.PrepareContext(
 		ctx,
-		`INSERT INTO posts(user_id, date, title, content) 
-		VALUES(?,?,?,?);`)
+		`INSERT INTO posts(user_id, date, title, content, last_activity) 
+		VALUES(?,?,?,?,?);`)
 	if err != nil {
 		pr.errorLog.Println(err)
 		return 0, err
 	}
 	defer stmt.Close()
-	res, err := stmt.ExecContext(ctx, post.User.Id, time.Now().Format("2006-01-02"), post.Title, post.Content)
+	now := time.Now()
+	res, err := stmt.ExecContext(ctx, post.User.Id, now.Format("2006-01-02"), post.Title, post.Content, now.Format(entity.ActivityLayout))
 	if err != nil {
 		pr.errorLog.Println(err)
 		return 0, err
@@ -184,3 +235,109 @@ func (pr *PostsRepository) Store(ctx context.Context, post entity.Post) (int64,
 	}
 	return post_id, nil
 }
+
+func (pr *PostsRepository) Update(ctx context.Context, post entity.Post) error {
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	current := entity.Post{}
+	err = tx.QueryRowContext(ctx, "SELECT title, content FROM posts WHERE id = ?;", post.Id).Scan(&current.Title, &current.Content)
+	if err == sql.ErrNoRows {
+		return entity.ErrPostNotFound
+	} else if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	stmt_rev, err := tx.PrepareContext(ctx, `INSERT INTO post_revisions(post_id, date, title, content) VALUES(?,?,?,?);`)
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	defer stmt_rev.Close()
+	if _, err = stmt_rev.ExecContext(ctx, post.Id, time.Now().Format("2006-01-02 15:04"), current.Title, current.Content); err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `UPDATE posts SET title = ?, content = ? WHERE id = ?;`)
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	if _, err = stmt.ExecContext(ctx, post.Title, post.Content, post.Id); err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (pr *PostsRepository) Delete(ctx context.Context, id int) error {
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM posts WHERE id = ?;`)
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	rAffected, err := res.RowsAffected()
+	if err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	if rAffected == 0 {
+		return entity.ErrPostNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		pr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (pr *PostsRepository) FetchRevisions(ctx context.Context, id int) ([]entity.Revision, error) {
+	revisions := []entity.Revision{}
+	tx, err := pr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		pr.errorLog.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, post_id, date, title, content FROM post_revisions WHERE post_id = ? ORDER BY id;")
+	if err != nil {
+		pr.errorLog.Println(err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, id)
+	if err != nil {
+		pr.errorLog.Println(err)
+		return nil, err
+	}
+	for rows.Next() {
+		revision := entity.Revision{}
+		rows.Scan(&revision.Id, &revision.PostId, &revision.Date, &revision.Title, &revision.Content)
+		revisions = append(revisions, revision)
+	}
+	if err = tx.Commit(); err != nil {
+		pr.errorLog.Println(err)
+		return nil, err
+	}
+	return revisions, nil
+}
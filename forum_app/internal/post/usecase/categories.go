@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type CategoriesUsecase struct {
+	categoriesRepo CategoriesRepository
+	usersRepo      UsersRepository
+	errorLog       *log.Logger
+}
+
+func NewCategoriesUsecase(categoriesRepo CategoriesRepository, usersRepo UsersRepository, errorLog *log.Logger) *CategoriesUsecase {
+	return &CategoriesUsecase{
+		categoriesRepo: categoriesRepo,
+		usersRepo:      usersRepo,
+		errorLog:       errorLog,
+	}
+}
+
+func (cu *CategoriesUsecase) Store(ctx context.Context, change entity.CategoryChange, res chan entity.Result) {
+	if err := cu.checkAdmin(ctx, change.AdminId); err != nil {
+		res <- entity.Result{Err: err}
+		return
+	}
+	id, err := cu.categoriesRepo.Store(ctx, change.Category)
+	res <- entity.Result{Id: id, Err: err}
+}
+
+func (cu *CategoriesUsecase) Update(ctx context.Context, change entity.CategoryChange, err chan error) {
+	if e := cu.checkAdmin(ctx, change.AdminId); e != nil {
+		err <- e
+		return
+	}
+	err <- cu.categoriesRepo.Update(ctx, change.Category)
+}
+
+func (cu *CategoriesUsecase) Reorder(ctx context.Context, change entity.CategoryChange, err chan error) {
+	if e := cu.checkAdmin(ctx, change.AdminId); e != nil {
+		err <- e
+		return
+	}
+	err <- cu.categoriesRepo.Reorder(ctx, change.ParentId, change.Order)
+}
+
+func (cu *CategoriesUsecase) Archive(ctx context.Context, change entity.CategoryChange, err chan error) {
+	if e := cu.checkAdmin(ctx, change.AdminId); e != nil {
+		err <- e
+		return
+	}
+	err <- cu.categoriesRepo.SetArchived(ctx, change.Id, change.Archived)
+}
+
+func (cu *CategoriesUsecase) checkAdmin(ctx context.Context, id int) error {
+	user, err := cu.usersRepo.FetchById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Id == 0 || !entity.HasRole(user.Role, entity.RoleAdmin) {
+		return entity.ErrForbidden
+	}
+	return nil
+}
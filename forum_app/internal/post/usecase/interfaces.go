@@ -7,9 +7,12 @@ import (
 
 type PostsRepository interface {
 	FetchById(context.Context, int) (entity.Post, error)
-	FetchByCategoryId(context.Context, int) ([]entity.Post, error)
-	FetchAll(context.Context) ([]entity.Post, error)
+	FetchPage(context.Context, entity.PageRequest) ([]entity.Post, error)
+	CountByCategoryId(context.Context, int) (int, error)
 	Store(context.Context, entity.Post) (int64, error)
+	Update(context.Context, entity.Post) error
+	Delete(context.Context, int) error
+	FetchRevisions(context.Context, int) ([]entity.Revision, error)
 }
 
 type PostReactionsRepository interface {
@@ -25,6 +28,7 @@ type UsersRepository interface {
 
 type CommentsRepository interface {
 	FetchByPostId(context.Context, int) ([]entity.Comment, error)
+	FetchLastByPostId(context.Context, int) (entity.Comment, error)
 }
 
 type CommentReactionsRepository interface {
@@ -34,5 +38,13 @@ type CommentReactionsRepository interface {
 type CategoriesRepository interface {
 	FetchById(context.Context, int) (entity.Category, error)
 	FetchByPostId(context.Context, int) ([]entity.Category, error)
-	FetchAllCategories(context.Context) ([]entity.Category, error)
+	FetchAllCategories(context.Context, bool) ([]entity.Category, error)
+	Store(context.Context, entity.Category) (int64, error)
+	Update(context.Context, entity.Category) error
+	Reorder(context.Context, int, []int) error
+	SetArchived(context.Context, int, bool) error
+}
+
+type NotificationsRepository interface {
+	Store(context.Context, []entity.Notification) error
 }
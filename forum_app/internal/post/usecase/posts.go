@@ -13,6 +13,7 @@ type PostsUsecase struct {
 	commentReactionsRepo CommentReactionsRepository
 	categoriesRepo       CategoriesRepository
 	usersRepo            UsersRepository
+	notificationsRepo    NotificationsRepository
 	errorLog             *log.Logger
 }
 
@@ -21,7 +22,8 @@ func NewPostsUsecase(postsRepo PostsRepository,
 	commentsRepo CommentsRepository,
 	commentReactionRepo CommentReactionsRepository,
 	categoriesRepo CategoriesRepository,
-	usersRepo UsersRepository, errorLog *log.Logger) *PostsUsecase {
+	usersRepo UsersRepository,
+	notificationsRepo NotificationsRepository, errorLog *log.Logger) *PostsUsecase {
 	return &PostsUsecase{
 		postsRepo:            postsRepo,
 		postReactionsRepo:    postReactionsRepo,
@@ -29,6 +31,7 @@ func NewPostsUsecase(postsRepo PostsRepository,
 		commentReactionsRepo: commentReactionRepo,
 		categoriesRepo:       categoriesRepo,
 		usersRepo:            usersRepo,
+		notificationsRepo:    notificationsRepo,
 		errorLog:             errorLog,
 	}
 }
@@ -40,15 +43,42 @@ func (u *PostsUsecase) FetchById(ctx context.Context, id int, postRes chan entit
 		postRes <- entity.PostResult{Err: err}
 		return
 	}
-	if post.Id == 0 {
+	if post.Id == 0 || post.Hidden {
 		u.errorLog.Println(entity.ErrPostNotFound)
 		postRes <- entity.PostResult{Err: entity.ErrPostNotFound}
 		return
 	}
 	u.fetchPostDetails(ctx, &post)
+	post.Revisions, err = u.fetchRevisions(ctx, post)
+	if err != nil {
+		u.errorLog.Println(err)
+	}
+	post.CountTotals()
+	if post.TotalRevisions > 0 {
+		post.EditDate = post.Revisions[0].Date
+	}
 	postRes <- entity.PostResult{Post: post}
 }
 
+// fetchRevisions returns the revisions of the post, newest first, each with the diff to its successor
+func (u *PostsUsecase) fetchRevisions(ctx context.Context, post entity.Post) ([]entity.Revision, error) {
+	revisions, err := u.postsRepo.FetchRevisions(ctx, post.Id)
+	if err != nil {
+		return nil, err
+	}
+	for ix := range revisions {
+		next := post.Content
+		if ix+1 < len(revisions) {
+			next = revisions[ix+1].Content
+		}
+		revisions[ix].Changes = entity.Diff(revisions[ix].Content, next)
+	}
+	for i, j := 0, len(revisions)-1; i < j; i, j = i+1, j-1 {
+		revisions[i], revisions[j] = revisions[j], revisions[i]
+	}
+	return revisions, nil
+}
+
 func (u *PostsUsecase) fetchPostDetails(ctx context.Context, post *entity.Post) {
 	var (
 		err           error
@@ -95,21 +125,57 @@ func (u *PostsUsecase) fetchPostDetails(ctx context.Context, post *entity.Post)
 	post.CountTotals()
 }
 
-func (u *PostsUsecase) FetchAll(ctx context.Context, postsRes chan entity.PostsResult) {
-	posts, err := u.postsRepo.FetchAll(ctx)
+func (u *PostsUsecase) FetchPage(ctx context.Context, req entity.PageRequest, pageRes chan entity.PageResult) {
+	page, err := u.fetchPage(ctx, req)
 	if err != nil {
-		postsRes <- entity.PostsResult{Err: err}
+		pageRes <- entity.PageResult{Err: err}
+		return
+	}
+	pageRes <- entity.PageResult{Page: page}
+}
+
+func (u *PostsUsecase) fetchPage(ctx context.Context, req entity.PageRequest) (entity.Page, error) {
+	posts, err := u.postsRepo.FetchPage(ctx, req)
+	if err != nil {
+		return entity.Page{}, err
+	}
+	page := entity.Page{Sort: req.Sort, Limit: req.Limit}
+	if len(posts) > req.Limit {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		page.NextCursor = entity.Cursor{Key: last.CursorKey(req.Sort), Id: last.Id}.Encode()
 	}
 	for ix := range posts {
-		u.fetchPostDetails(ctx, &posts[ix])
-		if len(posts[ix].Comments) > 0 {
-			posts[ix].Comments = posts[ix].Comments[len(posts[ix].Comments)-1:]
-		}
-		posts[ix].Likes, posts[ix].Dislikes = nil, nil
+		u.fetchPostSummary(ctx, &posts[ix])
 	}
-	postsRes <- entity.PostsResult{Posts: posts}
+	page.Posts = posts
+	return page, nil
 }
 
+// fetchPostSummary loads what a listing shows next to the totals already counted by FetchPage:
+// the author, the categories and the latest comment
+func (u *PostsUsecase) fetchPostSummary(ctx context.Context, post *entity.Post) {
+	var err error
+	post.User, err = u.usersRepo.FetchById(ctx, post.User.Id)
+	if err != nil {
+		u.errorLog.Println(err)
+	}
+	post.Category, err = u.categoriesRepo.FetchByPostId(ctx, post.Id)
+	if err != nil {
+		u.errorLog.Println(err)
+	}
+	latest, err := u.commentsRepo.FetchLastByPostId(ctx, post.Id)
+	if err != nil {
+		u.errorLog.Println(err)
+	}
+	if latest.Id != 0 {
+		latest.User, err = u.usersRepo.FetchById(ctx, latest.User.Id)
+		if err != nil {
+			u.errorLog.Println(err)
+		}
+		post.Comments = []entity.Comment{latest}
+	}
+}
 func (u *PostsUsecase) fetchUser(ctx context.Context, id int, user chan entity.User, errUser chan error) {
 	tempUser, err := u.usersRepo.FetchById(ctx, id)
 	user <- tempUser
@@ -125,6 +191,10 @@ func (u *PostsUsecase) fetchComments(ctx context.Context, id int, comments chan
 			u.errorLog.Println(e)
 		}
 		tempComments[i].Post.Id = id
+		if tempComments[i].Hidden {
+			// hidden comments keep their place in the thread so that replies stay attached
+			tempComments[i].Content = ""
+		}
 		tempComments[i].Likes, e = u.fetchCommentReactions(ctx, tempComments[i].Id, true)
 		if e != nil {
 			u.errorLog.Println(e)
@@ -135,10 +205,30 @@ func (u *PostsUsecase) fetchComments(ctx context.Context, id int, comments chan
 		}
 		tempComments[i].CountTotals()
 	}
-	comments <- tempComments
+	comments <- buildCommentTree(tempComments)
 	errComments <- err
 }
 
+// buildCommentTree nests replies under their parents, keeping every level in chronological order
+func buildCommentTree(flat []entity.Comment) []entity.Comment {
+	children := map[int][]entity.Comment{}
+	for _, c := range flat {
+		children[c.ParentId] = append(children[c.ParentId], c)
+	}
+	var attach func(parentId, depth int) []entity.Comment
+	attach = func(parentId, depth int) []entity.Comment {
+		level := children[parentId]
+		for ix := range level {
+			level[ix].Depth = depth
+			if depth+1 < entity.MaxCommentDepth {
+				level[ix].Replies = attach(level[ix].Id, depth+1)
+			}
+		}
+		return level
+	}
+	return attach(0, 0)
+}
+
 func (u *PostsUsecase) fetchCommentReactions(ctx context.Context, id int, like bool) ([]entity.Reaction, error) {
 	return u.commentReactionsRepo.FetchByCommentId(ctx, id, like)
 }
@@ -175,56 +265,149 @@ func (u *PostsUsecase) FetchReactions(ctx context.Context, id int, reactionsChan
 	reactionsChan <- entity.ReactionsResult{Reactions: append(likes, dislikes...)}
 }
 
-func (u *PostsUsecase) FetchCategoryPosts(ctx context.Context, id int, catRes chan entity.CatResult) {
-	var err error
-	category, err := u.categoriesRepo.FetchById(ctx, id)
+func (u *PostsUsecase) FetchCategoryPosts(ctx context.Context, req entity.PageRequest, catRes chan entity.CatResult) {
+	category, err := u.categoriesRepo.FetchById(ctx, req.CategoryId)
 	if err != nil {
 		catRes <- entity.CatResult{Err: err}
 		return
 	}
-	category.Posts, err = u.postsRepo.FetchByCategoryId(ctx, category.Id)
+	if category.Id == 0 {
+		catRes <- entity.CatResult{Err: entity.ErrCategoryNotFound}
+		return
+	}
+	page, err := u.fetchPage(ctx, req)
 	if err != nil {
 		catRes <- entity.CatResult{Err: err}
 		return
 	}
-	if category.Id == 0 {
-		catRes <- entity.CatResult{Err: entity.ErrCategoryNotFound}
+	category.Posts, category.NextCursor, category.Sort, category.Limit = page.Posts, page.NextCursor, page.Sort, page.Limit
+	category.TotalPosts, err = u.postsRepo.CountByCategoryId(ctx, category.Id)
+	if err != nil {
+		catRes <- entity.CatResult{Err: err}
 		return
 	}
-	for ix := range category.Posts {
-		category.Posts[ix], err = u.postsRepo.FetchById(ctx, category.Posts[ix].Id)
-		u.fetchPostDetails(ctx, &category.Posts[ix])
-		if len(category.Posts[ix].Comments) > 0 {
-			category.Posts[ix].Comments = category.Posts[ix].Comments[len(category.Posts[ix].Comments)-1:]
-		}
-		category.Posts[ix].Likes = nil
-		category.Posts[ix].Dislikes = nil
-	}
-	category.CountTotals()
 	catRes <- entity.CatResult{Cat: category}
 }
 
 func (u *PostsUsecase) Store(ctx context.Context, post entity.Post, res chan entity.Result) {
+	for _, category := range post.Category {
+		if err := u.checkOpen(ctx, category.Id); err != nil {
+			res <- entity.Result{Err: err}
+			return
+		}
+	}
 	id, err := u.postsRepo.Store(ctx, post)
 	if err != nil {
 		res <- entity.Result{Err: err}
+		return
 	}
 	res <- entity.Result{Id: id}
 }
 
+// checkOpen tells whether the category takes new posts, it doesn't when it or one of its parents is archived
+func (u *PostsUsecase) checkOpen(ctx context.Context, id int) error {
+	seen := map[int]bool{}
+	for {
+		if seen[id] { // a loop in the parents, the category can't be placed
+			return entity.ErrCategoryNotFound
+		}
+		seen[id] = true
+		category, err := u.categoriesRepo.FetchById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if category.Id == 0 {
+			return entity.ErrCategoryNotFound
+		}
+		if category.Archived {
+			return entity.ErrCategoryArchived
+		}
+		if category.ParentId == 0 {
+			return nil
+		}
+		id = category.ParentId
+	}
+}
+
+func (u *PostsUsecase) Update(ctx context.Context, post entity.Post, err chan error) {
+	if e := u.checkOwner(ctx, post); e != nil {
+		err <- e
+		return
+	}
+	err <- u.postsRepo.Update(ctx, post)
+}
+
+func (u *PostsUsecase) Delete(ctx context.Context, post entity.Post, err chan error) {
+	if e := u.checkOwner(ctx, post); e != nil {
+		err <- e
+		return
+	}
+	err <- u.postsRepo.Delete(ctx, post.Id)
+}
+
+func (u *PostsUsecase) checkOwner(ctx context.Context, post entity.Post) error {
+	stored, err := u.postsRepo.FetchById(ctx, post.Id)
+	if err != nil {
+		return err
+	}
+	if stored.Id == 0 || stored.Hidden {
+		return entity.ErrPostNotFound
+	}
+	if stored.User.Id != post.User.Id {
+		return entity.ErrForbidden
+	}
+	return nil
+}
+
+// StorePostReaction notifies the author of the post about likes, dislikes go unannounced
 func (u *PostsUsecase) StorePostReaction(ctx context.Context, postReaction entity.PostReaction, err chan error) {
-	err <- u.postReactionsRepo.StoreReaction(ctx, postReaction)
+	if e := u.postReactionsRepo.StoreReaction(ctx, postReaction); e != nil {
+		err <- e
+		return
+	}
+	u.notifyLike(ctx, postReaction)
+	err <- nil
 }
 
+// UpdatePostReaction notifies the author of the post when a dislike turns into a like
 func (u *PostsUsecase) UpdatePostReaction(ctx context.Context, postReaction entity.PostReaction, err chan error) {
-	err <- u.postReactionsRepo.UpdateReaction(ctx, postReaction)
+	if e := u.postReactionsRepo.UpdateReaction(ctx, postReaction); e != nil {
+		err <- e
+		return
+	}
+	u.notifyLike(ctx, postReaction)
+	err <- nil
+}
+
+// notifyLike tells the author of the post about a like, a failure is only logged since the reaction is stored
+func (u *PostsUsecase) notifyLike(ctx context.Context, postReaction entity.PostReaction) {
+	if !postReaction.Like {
+		return
+	}
+	post, err := u.postsRepo.FetchById(ctx, postReaction.Post.Id)
+	if err != nil {
+		u.errorLog.Println(err)
+		return
+	}
+	if post.Id == 0 || post.User.Id == postReaction.Reaction.User.Id {
+		return
+	}
+	notification := entity.Notification{
+		User:  post.User,
+		Actor: postReaction.Reaction.User,
+		Kind:  entity.NotifyPostLike,
+		Post:  entity.Post{Id: post.Id},
+	}
+	if err = u.notificationsRepo.Store(ctx, []entity.Notification{notification}); err != nil {
+		u.errorLog.Println(err)
+	}
 }
 
 func (u *PostsUsecase) DeletePostReaction(ctx context.Context, postReaction entity.PostReaction, err chan error) {
 	err <- u.postReactionsRepo.DeleteReaction(ctx, postReaction)
 }
 
-func (u *PostsUsecase) FetchCategories(ctx context.Context, catsChan chan entity.CategoriesResult) {
-	cats, err := u.categoriesRepo.FetchAllCategories(ctx)
+func (u *PostsUsecase) FetchCategories(ctx context.Context, archived bool, catsChan chan entity.CategoriesResult) {
+	cats, err := u.categoriesRepo.FetchAllCategories(ctx, archived)
 	catsChan <- entity.CategoriesResult{Categories: cats, Error: err}
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"forum_app/internal/entity"
+	"log"
+	"strings"
+	"time"
+)
+
+type ReportsRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewReportsRepository(db *sql.DB, errorLog *log.Logger) *ReportsRepository {
+	return &ReportsRepository{db, errorLog}
+}
+
+var targetTables = map[string]string{
+	entity.TargetPost:    "posts",
+	entity.TargetComment: "comments",
+}
+
+// Store records the report and hides the target once hideAfter distinct users have open reports on it,
+// the automatic hiding is logged as a moderation action without a moderator
+func (rr *ReportsRepository) Store(ctx context.Context, report entity.Report, hideAfter int) (entity.ReportOutcome, error) {
+	outcome := entity.ReportOutcome{}
+	tx, err := rr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		rr.errorLog.Println(err)
+		return outcome, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO reports(reporter_id, target, target_id, reason, details, date) VALUES(?,?,?,?,?,?);")
+	if err != nil {
+		rr.errorLog.Println(err)
+		return outcome, err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, report.Reporter.Id, report.Target, report.TargetId, report.Reason, report.Details, time.Now().Format("2006-01-02 15:04"))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return outcome, entity.ErrReportExists
+		}
+		rr.errorLog.Println(err)
+		return outcome, err
+	}
+	if outcome.Id, err = res.LastInsertId(); err != nil {
+		rr.errorLog.Println(err)
+		return outcome, err
+	}
+	var reporters int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(DISTINCT reporter_id) FROM reports WHERE target = ? AND target_id = ? AND resolved = 0;", report.Target, report.TargetId).Scan(&reporters)
+	if err != nil {
+		rr.errorLog.Println(err)
+		return outcome, err
+	}
+	if hideAfter > 0 && reporters >= hideAfter {
+		if outcome.Hidden, err = rr.hide(ctx, tx, report, reporters); err != nil {
+			rr.errorLog.Println(err)
+			return outcome, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		rr.errorLog.Println(err)
+		return entity.ReportOutcome{}, err
+	}
+	return outcome, nil
+}
+
+func (rr *ReportsRepository) hide(ctx context.Context, tx *sql.Tx, report entity.Report, reporters int) (bool, error) {
+	table := targetTables[report.Target]
+	res, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET hidden = 1 WHERE id = ? AND hidden = 0;", table), report.TargetId)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	column := "content"
+	if report.Target == entity.TargetPost {
+		column = "title"
+	}
+	var summary string
+	if err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?;", column, table), report.TargetId).Scan(&summary); err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO moderation_actions(moderator_id, target, target_id, action, reason, summary, date) VALUES(NULL,?,?,?,?,?,?);`,
+		report.Target, report.TargetId, entity.ActionHide, fmt.Sprintf("hidden automatically after %d reports", reporters), entity.Summarize(summary), time.Now().Format("2006-01-02 15:04"))
+	return err == nil, err
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+)
+
+type ReportsRepository interface {
+	Store(context.Context, entity.Report, int) (entity.ReportOutcome, error)
+}
+
+type PostsRepository interface {
+	FetchById(context.Context, int) (entity.Post, error)
+}
+
+type CommentsRepository interface {
+	FetchById(context.Context, int) (entity.Comment, error)
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type ReportsUsecase struct {
+	reportsRepo  ReportsRepository
+	postsRepo    PostsRepository
+	commentsRepo CommentsRepository
+	hideAfter    int
+	errorLog     *log.Logger
+}
+
+func NewReportsUsecase(reportsRepo ReportsRepository, postsRepo PostsRepository, commentsRepo CommentsRepository, hideAfter int, errorLog *log.Logger) *ReportsUsecase {
+	return &ReportsUsecase{
+		reportsRepo:  reportsRepo,
+		postsRepo:    postsRepo,
+		commentsRepo: commentsRepo,
+		hideAfter:    hideAfter,
+		errorLog:     errorLog,
+	}
+}
+
+func (ru *ReportsUsecase) Store(ctx context.Context, report entity.Report, reportRes chan entity.ReportResult) {
+	if err := ru.checkTarget(ctx, report); err != nil {
+		reportRes <- entity.ReportResult{Err: err}
+		return
+	}
+	outcome, err := ru.reportsRepo.Store(ctx, report, ru.hideAfter)
+	reportRes <- entity.ReportResult{Outcome: outcome, Err: err}
+}
+
+// checkTarget makes sure the target is visible and doesn't belong to the reporter
+func (ru *ReportsUsecase) checkTarget(ctx context.Context, report entity.Report) error {
+	postId, authorId := report.TargetId, 0
+	if report.Target == entity.TargetComment {
+		comment, err := ru.commentsRepo.FetchById(ctx, report.TargetId)
+		if err != nil {
+			return err
+		}
+		if comment.Id == 0 || comment.Hidden {
+			return entity.ErrCommentNotFound
+		}
+		postId, authorId = comment.Post.Id, comment.User.Id
+	}
+	post, err := ru.postsRepo.FetchById(ctx, postId)
+	if err != nil {
+		return err
+	}
+	if post.Id == 0 || post.Hidden {
+		if report.Target == entity.TargetComment {
+			return entity.ErrCommentNotFound
+		}
+		return entity.ErrPostNotFound
+	}
+	if report.Target == entity.TargetPost {
+		authorId = post.User.Id
+	}
+	if authorId == report.Reporter.Id {
+		return entity.ErrForbidden
+	}
+	return nil
+}
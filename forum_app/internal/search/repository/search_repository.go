@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type SearchRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewSearchRepository(db *sql.DB, errorLog *log.Logger) *SearchRepository {
+	return &SearchRepository{db, errorLog}
+}
+
+// Search ranks posts and comments with bm25, a match in the title weighs ten times a match in the content
+func (sr *SearchRepository) Search(ctx context.Context, req entity.SearchRequest) ([]entity.SearchHit, error) {
+	hits := []entity.SearchHit{}
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		sr.errorLog.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `
+	SELECT s.kind, s.post_id, COALESCE(s.comment_id, 0), p.title, u.id, u.name, COALESCE(c.date, p.date),
+		snippet(search_index, -1, ?, ?, '…', 16), bm25(search_index, 10.0, 1.0) AS rank
+	FROM search_index AS s
+	JOIN posts AS p ON p.id = s.post_id
+	LEFT JOIN comments AS c ON c.id = s.comment_id
+	JOIN users AS u ON u.id = COALESCE(c.user_id, p.user_id)
+	WHERE search_index MATCH ? AND p.hidden = 0 AND COALESCE(c.hidden, 0) = 0
+		AND (? = 0 OR s.post_id IN (SELECT post_id FROM post_categories WHERE category_id = ?))
+		AND (? = '' OR u.name = ? COLLATE NOCASE)
+		AND (? = '' OR COALESCE(c.date, p.date) >= ?)
+		AND (? = '' OR COALESCE(c.date, p.date) <= ?)
+	ORDER BY rank LIMIT ? OFFSET ?;`)
+	if err != nil {
+		sr.errorLog.Println(err)
+		return nil, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx, entity.SnippetStart, entity.SnippetEnd, req.MatchQuery(),
+		req.CategoryId, req.CategoryId, req.Author, req.Author, req.From, req.From, req.To, req.To, req.Limit+1, req.Offset)
+	if err != nil {
+		sr.errorLog.Println(err)
+		return nil, err
+	}
+	for rows.Next() {
+		hit := entity.SearchHit{}
+		rows.Scan(&hit.Kind, &hit.Post.Id, &hit.CommentId, &hit.Post.Title, &hit.User.Id, &hit.User.Name, &hit.Date, &hit.Snippet, &hit.Rank)
+		hits = append(hits, hit)
+	}
+	if err = tx.Commit(); err != nil {
+		sr.errorLog.Println(err)
+		return nil, err
+	}
+	return hits, nil
+}
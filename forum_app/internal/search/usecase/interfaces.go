@@ -0,0 +1,10 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+)
+
+type SearchRepository interface {
+	Search(context.Context, entity.SearchRequest) ([]entity.SearchHit, error)
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"context"
+	"forum_app/internal/entity"
+	"log"
+)
+
+type SearchUsecase struct {
+	searchRepo SearchRepository
+	errorLog   *log.Logger
+}
+
+func NewSearchUsecase(searchRepo SearchRepository, errorLog *log.Logger) *SearchUsecase {
+	return &SearchUsecase{
+		searchRepo: searchRepo,
+		errorLog:   errorLog,
+	}
+}
+
+func (su *SearchUsecase) Search(ctx context.Context, req entity.SearchRequest, searchRes chan entity.SearchResult) {
+	hits, err := su.searchRepo.Search(ctx, req)
+	if err != nil {
+		searchRes <- entity.SearchResult{Err: err}
+		return
+	}
+	search := entity.Search{Query: req.Query, Limit: req.Limit, Offset: req.Offset}
+	if len(hits) > req.Limit {
+		hits = hits[:req.Limit]
+		search.NextOffset = req.Offset + req.Limit
+	}
+	search.Hits = hits
+	searchRes <- entity.SearchResult{Search: search}
+}
@@ -26,7 +26,7 @@ func (ur *UsersRepository) FetchById(ctx context.Context, id int) (entity.User,
 		return user, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT id, name, email, registration_date FROM users WHERE id = ?;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, name, email, registration_date, role, email_verified FROM users WHERE id = ?;")
 	if err != nil {
 		ur.errorLog.Println(err)
 		return user, err
@@ -38,7 +38,7 @@ func (ur *UsersRepository) FetchById(ctx context.Context, id int) (entity.User,
 		return user, err
 	}
 	if rows.Next() {
-		rows.Scan(&user.Id, &user.Name, &user.Email, &user.RegDate)
+		rows.Scan(&user.Id, &user.Name, &user.Email, &user.RegDate, &user.Role, &user.Verified)
 	}
 	stmt1, err := tx.PrepareContext(ctx, "SELECT count(id) FROM posts WHERE user_id = ?;")
 	if err != nil {
@@ -76,6 +76,7 @@ func (ur *UsersRepository) FetchById(ctx context.Context, id int) (entity.User,
 	return user, nil
 }
 
+// FetchAll lists the users without their emails, the list is public
 func (ur *UsersRepository) FetchAll(ctx context.Context) ([]entity.User, error) {
 	users := []entity.User{}
 	tx, err := ur.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -84,7 +85,7 @@ func (ur *UsersRepository) FetchAll(ctx context.Context) ([]entity.User, error)
 		return users, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT id, name, email, registration_date FROM users;")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, name, registration_date, role FROM users;")
 	if err != nil {
 		ur.errorLog.Println(err)
 		return users, err
@@ -97,7 +98,7 @@ func (ur *UsersRepository) FetchAll(ctx context.Context) ([]entity.User, error)
 	}
 	for rows.Next() {
 		tempUser := entity.User{}
-		rows.Scan(&tempUser.Id, &tempUser.Name, &tempUser.Email, &tempUser.RegDate)
+		rows.Scan(&tempUser.Id, &tempUser.Name, &tempUser.RegDate, &tempUser.Role)
 		users = append(users, tempUser)
 	}
 	if err = tx.Commit(); err != nil {
@@ -114,7 +115,7 @@ func (ur *UsersRepository) FetchByEmail(ctx context.Context, email string) (enti
 		return user, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM users WHERE email = ?")
+	stmt, err := tx.PrepareContext(ctx, "SELECT id, name, email, password, registration_date, role, email_verified FROM users WHERE email = ?")
 	if err != nil {
 		ur.errorLog.Println(err)
 		return user, err
@@ -126,7 +127,7 @@ func (ur *UsersRepository) FetchByEmail(ctx context.Context, email string) (enti
 		return user, err
 	}
 	if rows.Next() {
-		rows.Scan(&user.Id, &user.Name, &user.Email, &user.Password, &user.RegDate)
+		rows.Scan(&user.Id, &user.Name, &user.Email, &user.Password, &user.RegDate, &user.Role, &user.Verified)
 	}
 	if err = tx.Commit(); err != nil {
 		ur.errorLog.Println(err)
@@ -142,14 +143,14 @@ func (ur *UsersRepository) Store(ctx context.Context, user entity.User) (int64,
 		return 0, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO users(name, email, password, registration_date) VALUES (?, ?, ?, ?);")
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO users(name, email, password, registration_date, email_verified) VALUES (?, ?, ?, ?, ?);")
 	if err != nil {
 		ur.errorLog.Println(err)
 		return 0, err
 	}
 	defer stmt.Close()
 	user.RegDate = time.Now().Format("2006-01-02")
-	res, err := stmt.ExecContext(ctx, user.Name, user.Email, user.Password, user.RegDate)
+	res, err := stmt.ExecContext(ctx, user.Name, user.Email, user.Password, user.RegDate, user.Verified)
 	if err != nil {
 		ur.errorLog.Println(err)
 		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
@@ -164,3 +165,78 @@ func (ur *UsersRepository) Store(ctx context.Context, user entity.User) (int64,
 	}
 	return res.LastInsertId()
 }
+
+func (ur *UsersRepository) UpdateRole(ctx context.Context, id int, role string) error {
+	tx, err := ur.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "UPDATE users SET role = ? WHERE id = ?;")
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, role, id)
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	rAffected, err := res.RowsAffected()
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	if rAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (ur *UsersRepository) UpdatePassword(ctx context.Context, id int, password string) error {
+	return ur.updateUser(ctx, "UPDATE users SET password = ? WHERE id = ?;", password, id)
+}
+
+// SetVerified confirms the email of the user, unless it has changed since the confirmation was requested
+func (ur *UsersRepository) SetVerified(ctx context.Context, id int, email string) error {
+	return ur.updateUser(ctx, "UPDATE users SET email_verified = 1 WHERE id = ? AND email = ?;", id, email)
+}
+
+func (ur *UsersRepository) updateUser(ctx context.Context, query string, args ...interface{}) error {
+	tx, err := ur.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	rAffected, err := res.RowsAffected()
+	if err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	if rAffected == 0 {
+		return entity.ErrUserNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		ur.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
@@ -10,6 +10,9 @@ type UsersRepository interface {
 	FetchAll(context.Context) ([]entity.User, error)
 	FetchByEmail(context.Context, string) (entity.User, error)
 	Store(context.Context, entity.User) (int64, error)
+	UpdateRole(context.Context, int, string) error
+	UpdatePassword(context.Context, int, string) error
+	SetVerified(context.Context, int, string) error
 }
 
 type PostsRepository interface {
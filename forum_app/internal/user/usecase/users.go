@@ -151,7 +151,34 @@ func (u *UsersUsecase) fetchCommentReactions(ctx context.Context, id int, like b
 	errCommentReactions <- err
 }
 
+// UpdateRole changes the role of a user on behalf of an administrator, who can't change their own role
+func (u *UsersUsecase) UpdateRole(ctx context.Context, update entity.RoleUpdate, err chan error) {
+	if !entity.ValidRole(update.Role) {
+		err <- entity.ErrInvalidRole
+		return
+	}
+	admin, e := u.userRepo.FetchById(ctx, update.AdminId)
+	if e != nil {
+		err <- e
+		return
+	}
+	if !entity.HasRole(admin.Role, entity.RoleAdmin) || admin.Id == update.UserId {
+		err <- entity.ErrForbidden
+		return
+	}
+	err <- u.userRepo.UpdateRole(ctx, update.UserId, update.Role)
+}
+
 func (u *UsersUsecase) Store(ctx context.Context, user entity.User, result chan entity.Result) {
 	id, err := u.userRepo.Store(ctx, user)
 	result <- entity.Result{Id: id, Err: err}
 }
+
+// UpdatePassword stores a password already hashed by forum_auth
+func (u *UsersUsecase) UpdatePassword(ctx context.Context, user entity.User, err chan error) {
+	err <- u.userRepo.UpdatePassword(ctx, user.Id, user.Password)
+}
+
+func (u *UsersUsecase) VerifyEmail(ctx context.Context, user entity.User, err chan error) {
+	err <- u.userRepo.SetVerified(ctx, user.Id, user.Email)
+}
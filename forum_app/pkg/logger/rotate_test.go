@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forum_app.log")
+	rf, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err = rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for p, want := range map[string]string{path: "third\n", path + ".1": "second\n", path + ".2": "first\n"} {
+		if got := readFile(t, p); got != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(p), got, want)
+		}
+	}
+}
+
+// A rotation that can't rename the file keeps writing to it and tries again maxSize bytes later
+func TestRotateFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forum_app.log")
+	// a directory in the way of the first backup can be neither removed nor replaced
+	if err := os.MkdirAll(filepath.Join(path+".1", "taken"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := OpenRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() { os.Stderr = stderr }()
+	for _, line := range []string{"first\n", "second\n", "3\n"} {
+		if n, err := rf.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write(%q) = %d, %v", line, n, err)
+		}
+	}
+	if got := readFile(t, path); got != "first\nsecond\n3\n" {
+		t.Errorf("log = %q", got)
+	}
+	if rf.limit != int64(len("first\n"))+10 {
+		t.Errorf("next rotation at %d bytes", rf.limit)
+	}
+
+	if err = os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rf.Write([]byte("fourth\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "fourth\n" {
+		t.Errorf("log after the rotation = %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "first\nsecond\n3\n" {
+		t.Errorf("backup = %q", got)
+	}
+}
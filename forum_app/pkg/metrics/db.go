@@ -0,0 +1,22 @@
+package metrics
+
+import "database/sql"
+
+// NewDBStats exposes the connection pool of db, read at every scrape
+func (r *Registry) NewDBStats(db *sql.DB) {
+	r.NewGaugeFunc("db_open_connections", "Connections to the database, in use or idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	r.NewGaugeFunc("db_in_use_connections", "Connections to the database in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	r.NewGaugeFunc("db_idle_connections", "Idle connections to the database.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	r.NewCounterFunc("db_wait_count_total", "Times a query waited for a free connection.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	r.NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a free connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+}
@@ -0,0 +1,218 @@
+package sqlite3
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "forum.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testMigrations build authors, then books that reference them, then a column of books
+var testMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "authors",
+		Up:      execSQL(`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT);`),
+		Down:    execSQL(`DROP TABLE authors;`),
+	},
+	{
+		Version: 2,
+		Name:    "books",
+		Up:      execSQL(`CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES authors(id) ON DELETE CASCADE);`),
+		Down:    execSQL(`DROP TABLE books;`),
+	},
+	{
+		Version: 3,
+		Name:    "book titles",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "books", "title", "TEXT NOT NULL DEFAULT ''")
+		},
+		Down: execSQL(`ALTER TABLE books DROP COLUMN title;`),
+	},
+}
+
+func tables(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations' ORDER BY name;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func version(t *testing.T, m *Migrator) int {
+	t.Helper()
+	v, err := m.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	db := newTestDB(t)
+	m := NewMigrator(db, testMigrations)
+	tests := []struct {
+		name    string
+		run     func() error
+		version int
+		tables  string
+	}{
+		{"up to 1", func() error { return m.Up(1) }, 1, "authors"},
+		{"up to latest", func() error { return m.Up(m.Latest()) }, 3, "authors,books"},
+		{"up again", func() error { return m.Up(m.Latest()) }, 3, "authors,books"},
+		{"down to 2", func() error { return m.Down(2) }, 2, "authors,books"},
+		{"down to 0", func() error { return m.Down(0) }, 0, ""},
+		{"down again", func() error { return m.Down(0) }, 0, ""},
+		{"up after down", func() error { return m.Up(m.Latest()) }, 3, "authors,books"},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if v := version(t, m); v != tt.version {
+			t.Errorf("%s: version %d, want %d", tt.name, v, tt.version)
+		}
+		if got := tables(t, db); got != tt.tables {
+			t.Errorf("%s: tables %q, want %q", tt.name, got, tt.tables)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO books(title) VALUES ('title');`); err != nil {
+		t.Errorf("the column of migration 3 is missing: %v", err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.AppliedAt == "" {
+			t.Errorf("migration %d not applied: %+v", s.Version, s)
+		}
+	}
+}
+
+// A failing step is rolled back whole and stops the migrations after it
+func TestMigratorFailedStep(t *testing.T) {
+	db := newTestDB(t)
+	failing := append([]Migration{}, testMigrations...)
+	failing[1].Up = func(tx *sql.Tx) error {
+		if err := testMigrations[1].Up(tx); err != nil {
+			return err
+		}
+		return errors.New("broken")
+	}
+	m := NewMigrator(db, failing)
+	err := m.Up(m.Latest())
+	if err == nil || !strings.Contains(err.Error(), "migration 2 books: broken") {
+		t.Fatalf("Up = %v", err)
+	}
+	if v := version(t, m); v != 1 {
+		t.Errorf("version %d, want 1", v)
+	}
+	if got := tables(t, db); got != "authors" {
+		t.Errorf("tables %q, the failed step wasn't rolled back", got)
+	}
+}
+
+// Foreign keys are off during a step but checked before it commits
+func TestMigratorForeignKeyCheck(t *testing.T) {
+	db := newTestDB(t)
+	m := NewMigrator(db, append(append([]Migration{}, testMigrations...), Migration{
+		Version: 4,
+		Name:    "orphan",
+		Up:      execSQL(`INSERT INTO books(author_id) VALUES (42);`),
+		Down:    execSQL(`DELETE FROM books;`),
+	}))
+	err := m.Up(m.Latest())
+	if err == nil || !strings.Contains(err.Error(), "foreign key constraint failed") {
+		t.Fatalf("Up = %v", err)
+	}
+	if v := version(t, m); v != 3 {
+		t.Errorf("version %d, want 3", v)
+	}
+	var books int
+	if err = db.QueryRow(`SELECT COUNT(*) FROM books;`).Scan(&books); err != nil || books != 0 {
+		t.Errorf("books = %d, %v", books, err)
+	}
+}
+
+// A database migrated by a newer build is refused, up or down
+func TestMigratorCheck(t *testing.T) {
+	db := newTestDB(t)
+	if err := NewMigrator(db, testMigrations).Up(3); err != nil {
+		t.Fatal(err)
+	}
+	older := NewMigrator(db, testMigrations[:2])
+	for name, run := range map[string]func() error{
+		"Check": older.Check,
+		"Up":    func() error { return older.Up(older.Latest()) },
+		"Down":  func() error { return older.Down(0) },
+	} {
+		if err := run(); !errors.Is(err, ErrNewerSchema) {
+			t.Errorf("%s = %v, want %v", name, err, ErrNewerSchema)
+		}
+	}
+	if v := version(t, older); v != 3 {
+		t.Errorf("version %d, want 3", v)
+	}
+	if err := NewMigrator(db, testMigrations).Check(); err != nil {
+		t.Errorf("Check of the build that migrated = %v", err)
+	}
+}
+
+// addColumn leaves a column that a database from before the migrations already has
+func TestAddColumnExisting(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT, email TEXT);`); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	for _, column := range []string{"email", "EMAIL", "phone"} {
+		if err = addColumn(tx, "authors", column, "TEXT"); err != nil {
+			t.Errorf("addColumn(%s) = %v", column, err)
+		}
+	}
+	if ok, err := hasColumn(tx, "authors", "phone"); err != nil || !ok {
+		t.Errorf("phone wasn't added: %v", err)
+	}
+}
+
+// Every migration of the forum reverts cleanly and applies again
+func TestForumMigrations(t *testing.T) {
+	db := newTestDB(t)
+	m := NewForumMigrator(db)
+	for _, step := range []func() error{
+		func() error { return m.Up(m.Latest()) },
+		func() error { return m.Down(0) },
+		func() error { return m.Up(m.Latest()) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := version(t, m); v != m.Latest() {
+		t.Errorf("version %d, want %d", v, m.Latest())
+	}
+}
@@ -0,0 +1,352 @@
+package sqlite3
+
+import "database/sql"
+
+// migrations are applied in order and never edited once released, schema changes go into a new one
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			email TEXT NOT NULL UNIQUE,
+			password TEXT NOT NULL,
+			registration_date TEXT
+		);`, `
+		CREATE TABLE IF NOT EXISTS posts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			date TEXT,
+			title TEXT,
+			content TEXT
+		);`, `
+		CREATE TABLE IF NOT EXISTS post_reactions (
+			post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			date TEXT,
+			like INTEGER,
+			UNIQUE(post_id, user_id)
+		);`, `
+		CREATE TABLE IF NOT EXISTS categories (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT UNIQUE
+		);`, `
+		INSERT OR IGNORE INTO categories(title) VALUES('golang'), ('python'), ('rust'), ('c'), ('php'), ('java'), ('js');`, `
+		CREATE TABLE IF NOT EXISTS post_categories (
+			post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+			category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+			UNIQUE(post_id, category_id)
+		);`, `
+		CREATE TABLE IF NOT EXISTS comments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			date STRING,
+			content STRING
+		);`, `
+		CREATE TABLE IF NOT EXISTS comment_reactions (
+			comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			date TEXT,
+			like INTEGER,
+			UNIQUE(comment_id, user_id)
+		);`),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS comment_reactions;`,
+			`DROP TABLE IF EXISTS comments;`,
+			`DROP TABLE IF EXISTS post_categories;`,
+			`DROP TABLE IF EXISTS categories;`,
+			`DROP TABLE IF EXISTS post_reactions;`,
+			`DROP TABLE IF EXISTS posts;`,
+			`DROP TABLE IF EXISTS users;`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "post revisions",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS post_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+			date TEXT,
+			title TEXT,
+			content TEXT
+		);`),
+		Down: execSQL(`DROP TABLE IF EXISTS post_revisions;`),
+	},
+	{
+		Version: 3,
+		Name:    "comment replies",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "comments", "parent_id", "INTEGER REFERENCES comments(id) ON DELETE CASCADE")
+		},
+		Down: func(tx *sql.Tx) error {
+			return rebuildTable(tx, "comments", `
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			date STRING,
+			content STRING`, "id", "post_id", "user_id", "date", "content")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "comments by post index",
+		Up:      execSQL(`CREATE INDEX IF NOT EXISTS comments_post_id ON comments(post_id);`),
+		Down:    execSQL(`DROP INDEX IF EXISTS comments_post_id;`),
+	},
+	{
+		Version: 5,
+		Name:    "full-text search index",
+		// posts are indexed with rowid id*2 and comments with id*2+1
+		Up: execSQL(
+			`DROP TABLE IF EXISTS search_index;`, `
+			CREATE VIRTUAL TABLE search_index USING fts5(
+				title,
+				content,
+				kind UNINDEXED,
+				post_id UNINDEXED,
+				comment_id UNINDEXED,
+				tokenize = 'unicode61 remove_diacritics 2'
+			);`, `
+			CREATE TRIGGER IF NOT EXISTS posts_search_insert AFTER INSERT ON posts BEGIN
+				INSERT INTO search_index(rowid, title, content, kind, post_id, comment_id) VALUES(new.id*2, new.title, new.content, 'post', new.id, NULL);
+			END;`, `
+			CREATE TRIGGER IF NOT EXISTS posts_search_update AFTER UPDATE OF title, content ON posts BEGIN
+				UPDATE search_index SET title = new.title, content = new.content WHERE rowid = new.id*2;
+			END;`, `
+			CREATE TRIGGER IF NOT EXISTS posts_search_delete AFTER DELETE ON posts BEGIN
+				DELETE FROM search_index WHERE rowid = old.id*2;
+			END;`, `
+			CREATE TRIGGER IF NOT EXISTS comments_search_insert AFTER INSERT ON comments BEGIN
+				INSERT INTO search_index(rowid, title, content, kind, post_id, comment_id) VALUES(new.id*2+1, '', new.content, 'comment', new.post_id, new.id);
+			END;`, `
+			CREATE TRIGGER IF NOT EXISTS comments_search_delete AFTER DELETE ON comments BEGIN
+				DELETE FROM search_index WHERE rowid = old.id*2+1;
+			END;`, `
+			INSERT INTO search_index(rowid, title, content, kind, post_id, comment_id)
+				SELECT id*2, title, content, 'post', id, NULL FROM posts;`, `
+			INSERT INTO search_index(rowid, title, content, kind, post_id, comment_id)
+				SELECT id*2+1, '', content, 'comment', post_id, id FROM comments;`,
+		),
+		Down: execSQL(
+			`DROP TRIGGER IF EXISTS posts_search_insert;`,
+			`DROP TRIGGER IF EXISTS posts_search_update;`,
+			`DROP TRIGGER IF EXISTS posts_search_delete;`,
+			`DROP TRIGGER IF EXISTS comments_search_insert;`,
+			`DROP TRIGGER IF EXISTS comments_search_delete;`,
+			`DROP TABLE IF EXISTS search_index;`,
+		),
+	},
+	{
+		Version: 6,
+		Name:    "roles and moderation",
+		Up: func(tx *sql.Tx) error {
+			columns := []struct{ table, column, definition string }{
+				{"users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+				{"posts", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+				{"posts", "locked", "INTEGER NOT NULL DEFAULT 0"},
+				{"comments", "hidden", "INTEGER NOT NULL DEFAULT 0"},
+			}
+			for _, c := range columns {
+				if err := addColumn(tx, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return execSQL(`
+			CREATE TABLE IF NOT EXISTS moderation_actions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				target TEXT NOT NULL,
+				target_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				reason TEXT NOT NULL,
+				summary TEXT,
+				date TEXT
+			);`)(tx)
+		},
+		Down: execSQL(
+			`DROP TABLE IF EXISTS moderation_actions;`,
+			`ALTER TABLE comments DROP COLUMN hidden;`,
+			`ALTER TABLE posts DROP COLUMN locked;`,
+			`ALTER TABLE posts DROP COLUMN hidden;`,
+			`ALTER TABLE users DROP COLUMN role;`,
+		),
+	},
+	{
+		Version: 7,
+		Name:    "reports",
+		// reports point at either a post or a comment, so they are cleaned up by triggers rather than foreign keys
+		Up: execSQL(`
+			CREATE TABLE IF NOT EXISTS reports (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				reporter_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+				target TEXT NOT NULL,
+				target_id INTEGER NOT NULL,
+				reason TEXT NOT NULL,
+				details TEXT,
+				date TEXT,
+				resolved INTEGER NOT NULL DEFAULT 0,
+				UNIQUE(reporter_id, target, target_id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS reports_target ON reports(target, target_id);`,
+			postsReportsTrigger,
+			commentsReportsTrigger,
+		),
+		Down: execSQL(
+			`DROP TRIGGER IF EXISTS comments_reports_delete;`,
+			`DROP TRIGGER IF EXISTS posts_reports_delete;`,
+			`DROP TABLE IF EXISTS reports;`,
+		),
+	},
+	{
+		Version: 8,
+		Name:    "category tree",
+		Up: func(tx *sql.Tx) error {
+			columns := []struct{ column, definition string }{
+				{"parent_id", "INTEGER REFERENCES categories(id) ON DELETE CASCADE"},
+				{"position", "INTEGER NOT NULL DEFAULT 0"},
+				{"archived", "INTEGER NOT NULL DEFAULT 0"},
+			}
+			for _, c := range columns {
+				if err := addColumn(tx, "categories", c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return execSQL(
+				`UPDATE categories SET position = id;`,
+				`CREATE INDEX IF NOT EXISTS post_categories_category_id ON post_categories(category_id);`,
+			)(tx)
+		},
+		// going down flattens the tree, subcategories become top-level categories
+		Down: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`DROP INDEX IF EXISTS post_categories_category_id;`); err != nil {
+				return err
+			}
+			return rebuildTable(tx, "categories", `
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT UNIQUE`, "id", "title")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "notifications",
+		Up: execSQL(`
+			CREATE TABLE IF NOT EXISTS notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				actor_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+				kind TEXT NOT NULL,
+				post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+				comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+				date TEXT,
+				read INTEGER NOT NULL DEFAULT 0
+			);`,
+			`CREATE INDEX IF NOT EXISTS notifications_user_id ON notifications(user_id, read);`,
+		),
+		Down: execSQL(`DROP TABLE IF EXISTS notifications;`),
+	},
+	{
+		Version: 10,
+		Name:    "email verification",
+		Up: func(tx *sql.Tx) error {
+			return addColumn(tx, "users", "email_verified", "INTEGER NOT NULL DEFAULT 0")
+		},
+		Down: execSQL(`ALTER TABLE users DROP COLUMN email_verified;`),
+	},
+	{
+		Version: 11,
+		Name:    "unique like notifications",
+		Up: execSQL(`
+			DELETE FROM notifications WHERE kind IN ('post_like', 'comment_like') AND id NOT IN (
+				SELECT MIN(id) FROM notifications WHERE kind IN ('post_like', 'comment_like')
+				GROUP BY user_id, actor_id, kind, post_id, COALESCE(comment_id, 0)
+			);`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS notifications_like ON notifications(user_id, actor_id, kind, post_id, COALESCE(comment_id, 0))
+			WHERE kind IN ('post_like', 'comment_like');`,
+		),
+		Down: execSQL(`DROP INDEX IF EXISTS notifications_like;`),
+	},
+	{
+		Version: 12,
+		Name:    "post activity",
+		// existing posts and comments only have their day, it is the best their activity can be known to
+		Up: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "posts", "last_activity", "TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+			return execSQL(
+				`UPDATE posts SET last_activity = MAX(date, COALESCE((SELECT MAX(date) FROM comments WHERE post_id = posts.id), ''));`,
+				`CREATE INDEX IF NOT EXISTS posts_last_activity ON posts(last_activity, id);`,
+			)(tx)
+		},
+		Down: execSQL(
+			`DROP INDEX IF EXISTS posts_last_activity;`,
+			`ALTER TABLE posts DROP COLUMN last_activity;`,
+		),
+	},
+	{
+		Version: 13,
+		Name:    "unique open reports",
+		// a user reports a target once while the report is open, resolved reports don't stop a new one
+		Up: func(tx *sql.Tx) error {
+			if err := rebuildReports(tx, reportsTable); err != nil {
+				return err
+			}
+			return execSQL(`CREATE UNIQUE INDEX IF NOT EXISTS reports_open ON reports(reporter_id, target, target_id) WHERE resolved = 0;`)(tx)
+		},
+		// going down keeps the latest report of every user on a target
+		Down: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`DELETE FROM reports WHERE id NOT IN (SELECT MAX(id) FROM reports GROUP BY reporter_id, target, target_id);`); err != nil {
+				return err
+			}
+			return rebuildReports(tx, reportsTable+`,
+			UNIQUE(reporter_id, target, target_id)`)
+		},
+	},
+}
+
+const (
+	postsReportsTrigger = `
+			CREATE TRIGGER IF NOT EXISTS posts_reports_delete AFTER DELETE ON posts BEGIN
+				DELETE FROM reports WHERE target = 'post' AND target_id = old.id;
+			END;`
+	commentsReportsTrigger = `
+			CREATE TRIGGER IF NOT EXISTS comments_reports_delete AFTER DELETE ON comments BEGIN
+				DELETE FROM reports WHERE target = 'comment' AND target_id = old.id;
+			END;`
+)
+
+// reportsTable is the definition of reports without the constraint migration 13 changes
+const reportsTable = `
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reporter_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			target TEXT NOT NULL,
+			target_id INTEGER NOT NULL,
+			reason TEXT NOT NULL,
+			details TEXT,
+			date TEXT,
+			resolved INTEGER NOT NULL DEFAULT 0`
+
+// rebuildReports recreates reports with definition. The triggers that delete reports are dropped meanwhile,
+// SQLite refuses to rename a table into place while a trigger names a table that is missing
+func rebuildReports(tx *sql.Tx, definition string) error {
+	err := execSQL(
+		`DROP TRIGGER IF EXISTS comments_reports_delete;`,
+		`DROP TRIGGER IF EXISTS posts_reports_delete;`,
+	)(tx)
+	if err != nil {
+		return err
+	}
+	err = rebuildTable(tx, "reports", definition, "id", "reporter_id", "target", "target_id", "reason", "details", "date", "resolved")
+	if err != nil {
+		return err
+	}
+	return execSQL(
+		`CREATE INDEX IF NOT EXISTS reports_target ON reports(target, target_id);`,
+		postsReportsTrigger,
+		commentsReportsTrigger,
+	)(tx)
+}
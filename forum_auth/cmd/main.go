@@ -1,12 +1,33 @@
 package main
 
-import "forum_auth/internal/app"
+import (
+	"flag"
+	"forum_auth/internal/app"
+	"forum_auth/internal/config"
+	"log"
+	"os"
+)
 
 // TODO:
 // - info logging
 // - error logging in correct place
-// - config file
 // - limit active sessions
 func main() {
-	app.Run()
+	cfg, args, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+	if cfg.PrintConfig {
+		if err = cfg.Print(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		app.Migrate(cfg, args[1:])
+		return
+	}
+	app.Run(cfg)
 }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials entity.Credentials
+	h.accountRequest(w, r, &credentials, func() bool {
+		return credentials.Email != ""
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.ForgotPassword(ctx, credentials, errChan)
+	})
+}
+
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var reset entity.PasswordReset
+	h.accountRequest(w, r, &reset, func() bool {
+		return reset.Token != "" && reset.Password != ""
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.ResetPassword(ctx, reset, errChan)
+	})
+}
+
+func (h *Handler) SendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	var req entity.VerificationRequest
+	h.accountRequest(w, r, &req, func() bool {
+		return req.UserId != 0
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.SendVerification(ctx, req, errChan)
+	})
+}
+
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req entity.VerificationRequest
+	h.accountRequest(w, r, &req, func() bool {
+		return req.Token != ""
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.VerifyEmail(ctx, req, errChan)
+	})
+}
+
+// accountRequest decodes the POST body into req and runs apply with the timeout of the request
+func (h *Handler) accountRequest(w http.ResponseWriter, r *http.Request, req interface{}, valid func() bool, apply func(context.Context, chan error)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || !valid() {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, errChan) })
+	select {
+	case err := <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			switch err {
+			case entity.ErrInvalidToken:
+				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Invalid or expired token"})
+			case entity.ErrNotFound:
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+			case entity.ErrRequestTimeout:
+				h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+			default:
+				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			}
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
@@ -1,20 +1,27 @@
 package app
 
 import (
+	"context"
+	"forum_auth/internal/config"
+	"forum_auth/pkg/logger"
+	"forum_auth/pkg/metrics"
 	"io"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Run() {
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Llongfile)
-	f, _ := os.OpenFile("log.txt", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	defer f.Close()
-	wrt := io.MultiWriter(os.Stderr, f)
-	errorLog.SetOutput(wrt)
-	h := NewHandler(errorLog)
+func Run(cfg *config.Config) {
+	logs, file := newLogger(cfg)
+	if file != nil {
+		defer file.Close()
+	}
+	infoLog, errorLog := logs.Std(logger.LevelInfo), logs.Std(logger.LevelError)
+	duration = time.Duration(cfg.Timeout)
+	reg := metrics.NewRegistry()
+	h := NewHandler(cfg, logs, reg)
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/sign_in", h.SignInHandler)
@@ -22,12 +29,64 @@ func Run() {
 	mux.HandleFunc("/authenticate", h.Authenticate)
 	mux.HandleFunc("/sign_up", h.SignUpHandler)
 	mux.HandleFunc("/oauth_signin", h.OauthSignInHandler)
+	mux.HandleFunc("/password/forgot", h.ForgotPasswordHandler)
+	mux.HandleFunc("/password/reset", h.ResetPasswordHandler)
+	mux.HandleFunc("/email/send_verification", h.SendVerificationHandler)
+	mux.HandleFunc("/email/verify", h.VerifyEmailHandler)
+	mux.HandleFunc("/sessions", h.SessionsHandler)
+	mux.HandleFunc("/sessions/revoke", h.RevokeSessionHandler)
+	mux.HandleFunc("/sessions/revoke_others", h.RevokeOtherSessionsHandler)
+	mux.HandleFunc("/admin/sessions/sweep", h.SweepSessionsHandler)
+	mux.HandleFunc("/admin/lockouts", h.LockoutsHandler)
+	mux.HandleFunc("/identities", h.IdentitiesHandler)
+	mux.HandleFunc("/identities/link", h.LinkIdentityHandler)
+	mux.HandleFunc("/identities/unlink", h.UnlinkIdentityHandler)
+	mux.HandleFunc("/sign_in/two_factor", h.TwoFactorSignInHandler)
+	mux.HandleFunc("/two_factor", h.TwoFactorHandler)
+	mux.HandleFunc("/two_factor/setup", h.SetupTwoFactorHandler)
+	mux.HandleFunc("/two_factor/enable", h.EnableTwoFactorHandler)
+	mux.HandleFunc("/two_factor/disable", h.DisableTwoFactorHandler)
+	mux.HandleFunc("/admin/two_factor/reset", h.ResetTwoFactorHandler)
+	mux.HandleFunc("/healthz", h.HealthzHandler)
+	mux.HandleFunc("/readyz", h.ReadyzHandler)
+	mux.Handle("/metrics", reg.Handler())
 	srv := &http.Server{
-		Addr:     ":8081",
+		Addr:     cfg.Addr,
 		ErrorLog: errorLog,
-		Handler:  mux,
+		Handler:  logs.Middleware(reg.InstrumentMux(mux)),
+	}
+	h.reaper.Start()
+	go func() {
+		infoLog.Println("Listening on " + cfg.Addr)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			errorLog.Fatal(err)
+		}
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	infoLog.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		errorLog.Println(err)
+	}
+	h.reaper.Stop()
+	ctx, cancel = context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	if err := h.Close(ctx); err != nil {
+		errorLog.Println(err)
+	}
+}
+
+// newLogger logs to stderr and to the log file, which is rotated, or only to stderr when the file can't be opened
+func newLogger(cfg *config.Config) (*logger.Logger, io.Closer) {
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	file, err := logger.OpenRotatingFile(cfg.LogFile, int64(cfg.LogMaxSize)<<20, cfg.LogMaxBackups)
+	if err != nil {
+		logs := logger.New(os.Stderr, level).With("service", "forum_auth")
+		logs.Error(context.Background(), "Log file doesn't open", "error", err)
+		return logs, nil
 	}
-	infoLog.Println("Listening on localhost:8081")
-	err := srv.ListenAndServe()
-	errorLog.Fatal(err)
+	return logger.New(io.MultiWriter(os.Stderr, file), level).With("service", "forum_auth"), file
 }
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"forum_auth/internal/entity"
+	"net/http"
+)
+
+// HealthzHandler answers as long as the process serves requests
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: "ok"})
+}
+
+// ReadyzHandler answers once the database can be reached
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if err := h.db.PingContext(ctx); err != nil {
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusServiceUnavailable, entity.Response{ErrorMessage: "Service Unavailable"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: "ok"})
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) IdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var session entity.Session
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil || session.Token == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	identitiesChan := make(chan entity.IdentitiesResult, 1)
+	var identitiesRes entity.IdentitiesResult
+	h.background(func() { h.aucase.Identities(ctx, session, identitiesChan) })
+	select {
+	case identitiesRes = <-identitiesChan:
+		if err = identitiesRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.sessionErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: identitiesRes.Identities})
+}
+
+// LinkIdentityHandler connects the OAuth account of the credentials to the owner of the token
+func (h *Handler) LinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	h.identityRequest(w, r, http.MethodPost, func(req entity.IdentityRequest) bool {
+		return req.Credentials.Provider != "" && req.Credentials.Subject != ""
+	}, h.aucase.LinkIdentity)
+}
+
+func (h *Handler) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	h.identityRequest(w, r, http.MethodDelete, func(req entity.IdentityRequest) bool {
+		return req.Provider != ""
+	}, h.aucase.UnlinkIdentity)
+}
+
+func (h *Handler) identityRequest(w http.ResponseWriter, r *http.Request, method string, valid func(entity.IdentityRequest) bool, apply func(context.Context, entity.IdentityRequest, chan error)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != method {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var req entity.IdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || !valid(req) {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, req, errChan) })
+	select {
+	case err := <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.identityErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+// identityErrorResponse tells the conflicts of linking apart by their message, other errors are those of sessions
+func (h *Handler) identityErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrIdentityConflict, entity.ErrIdentityLinked, entity.ErrLastIdentity:
+		h.APIResponse(w, http.StatusConflict, entity.Response{ErrorMessage: err.Error()})
+	case entity.ErrRequestTimeout:
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+	default:
+		h.sessionErrorResponse(w, err)
+	}
+}
@@ -11,4 +11,33 @@ type AuthUsecase interface {
 	Authenticate(ctx context.Context, session entity.Session, authStatus chan entity.AuthStatusResult)
 	SignOut(ctx context.Context, session entity.Session, err chan error)
 	OauthSignIn(ctx context.Context, credentials entity.Credentials, sessionRes chan entity.SessionResult)
+	ForgotPassword(ctx context.Context, credentials entity.Credentials, err chan error)
+	ResetPassword(ctx context.Context, reset entity.PasswordReset, err chan error)
+	SendVerification(ctx context.Context, req entity.VerificationRequest, err chan error)
+	VerifyEmail(ctx context.Context, req entity.VerificationRequest, err chan error)
+	Sessions(ctx context.Context, session entity.Session, sessionsRes chan entity.SessionsResult)
+	RevokeSession(ctx context.Context, revoke entity.SessionRevoke, err chan error)
+	RevokeOtherSessions(ctx context.Context, session entity.Session, err chan error)
+	RequireRole(ctx context.Context, session entity.Session, role string, err chan error)
+	Identities(ctx context.Context, session entity.Session, identitiesRes chan entity.IdentitiesResult)
+	LinkIdentity(ctx context.Context, req entity.IdentityRequest, err chan error)
+	UnlinkIdentity(ctx context.Context, req entity.IdentityRequest, err chan error)
+	FinishSignIn(ctx context.Context, req entity.TwoFactorRequest, sessionRes chan entity.SessionResult)
+	TwoFactor(ctx context.Context, session entity.Session, tfRes chan entity.TwoFactorResult)
+	SetupTwoFactor(ctx context.Context, session entity.Session, tfRes chan entity.TwoFactorResult)
+	EnableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, tfRes chan entity.TwoFactorResult)
+	DisableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, err chan error)
+	ResetTwoFactor(ctx context.Context, req entity.TwoFactorRequest, err chan error)
+}
+
+type SessionReaper interface {
+	Start()
+	Stop()
+	Sweep(ctx context.Context, reaperRes chan entity.ReaperResult)
+	Stats(ctx context.Context, reaperRes chan entity.ReaperResult)
+	Active(ctx context.Context) (int64, error)
+}
+
+type LoginGuard interface {
+	Lockouts(ctx context.Context, lockoutsRes chan entity.LockoutsResult)
 }
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"forum_auth/internal/config"
+	"forum_auth/internal/entity"
+	"forum_auth/internal/repository"
+	"forum_auth/pkg/logger"
+	"forum_auth/pkg/metrics"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *metrics.Registry) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DB = filepath.Join(t.TempDir(), "session.db")
+	reg := metrics.NewRegistry()
+	h := NewHandler(cfg, logger.New(io.Discard, logger.LevelError), reg)
+	t.Cleanup(func() { h.Close(context.Background()) })
+	return h, reg
+}
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+// The gauge counts the sessions in the database at every scrape, expired ones are left out
+func TestActiveSessionsGauge(t *testing.T) {
+	h, reg := newTestHandler(t)
+	if body := scrape(t, reg); !strings.Contains(body, "auth_active_sessions 0\n") {
+		t.Fatalf("no sessions yet:\n%s", body)
+	}
+	repo := repository.NewSessionsRepository(h.db, log.New(io.Discard, "", 0))
+	now := time.Now()
+	sessions := []entity.Session{
+		{UserId: 1, Token: "active", ExpiryTime: now.Add(time.Hour), AbsoluteExpiry: now.Add(24 * time.Hour)},
+		{UserId: 1, Token: "idle", ExpiryTime: now.Add(-time.Minute), AbsoluteExpiry: now.Add(24 * time.Hour)},
+		{UserId: 2, Token: "ended", ExpiryTime: now.Add(time.Hour), AbsoluteExpiry: now.Add(-time.Minute)},
+	}
+	for _, session := range sessions {
+		if _, err := repo.Store(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	body := scrape(t, reg)
+	for _, line := range []string{
+		"auth_active_sessions 1\n",
+		`sqlite_query_duration_seconds_count{op="exec"}`,
+		`sqlite_query_duration_seconds_count{op="query"}`,
+		"db_open_connections ",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+	"forum_auth/internal/config"
+	"forum_auth/pkg/sqlite3"
+	"log"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: main migrate [command]
+
+commands:
+  up [version]    apply pending migrations, up to version if given
+  down [version]  revert the last migration, or every migration newer than version
+  status          list migrations and whether they are applied`
+
+// Migrate runs the migrate subcommand: main migrate up|down|status
+func Migrate(cfg *config.Config, args []string) {
+	errLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime)
+	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
+	db, err := sqlite3.Open(cfg.DB)
+	if err != nil {
+		errLog.Fatalln(err)
+	}
+	defer db.Close()
+	migrator := sqlite3.NewAuthMigrator(db)
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		target := migrator.Latest()
+		if len(args) > 1 {
+			if target, err = strconv.Atoi(args[1]); err != nil {
+				errLog.Fatalln(migrateUsage)
+			}
+		}
+		err = migrator.Up(target)
+	case "down":
+		var target int
+		if target, err = migrator.Version(); err != nil {
+			errLog.Fatalln(err)
+		}
+		target--
+		if len(args) > 1 {
+			if target, err = strconv.Atoi(args[1]); err != nil {
+				errLog.Fatalln(migrateUsage)
+			}
+		}
+		err = migrator.Down(target)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			errLog.Fatalln(err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = "applied " + status.AppliedAt
+			}
+			fmt.Printf("%4d  %-28s %s\n", status.Version, status.Name, applied)
+		}
+		return
+	default:
+		errLog.Fatalln(migrateUsage)
+	}
+	if err != nil {
+		errLog.Fatalln(err)
+	}
+	version, err := migrator.Version()
+	if err != nil {
+		errLog.Fatalln(err)
+	}
+	infoLog.Printf("Database schema is at version %d\n", version)
+}
@@ -2,44 +2,110 @@ package app
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"forum_auth/internal/client"
+	"forum_auth/internal/config"
 	"forum_auth/internal/entity"
+	"forum_auth/internal/mail"
 	"forum_auth/internal/repository"
 	"forum_auth/internal/usecase"
+	"forum_auth/pkg/logger"
+	"forum_auth/pkg/metrics"
 	"forum_auth/pkg/sqlite3"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Handler struct {
+	log      *logger.Logger
 	errorLog *log.Logger
 	aucase   AuthUsecase
+	reaper   SessionReaper
+	guard    LoginGuard
+	db       *sql.DB
+	// tasks are the usecase calls still running, Close waits for them
+	tasks sync.WaitGroup
 }
 
-const duration = 10 * time.Second
+// duration bounds the handling of a request, Run sets it from the config
+var duration = 10 * time.Second
 
-func NewHandler(errorLog *log.Logger) *Handler {
-	db, err := sqlite3.New()
+func NewHandler(cfg *config.Config, logs *logger.Logger, reg *metrics.Registry) *Handler {
+	infoLog, errorLog := logs.Std(logger.LevelInfo), logs.Std(logger.LevelError)
+	queries := reg.NewHistogram("sqlite_query_duration_seconds", "Duration of the SQLite queries and execs.", metrics.DefaultBuckets, "op")
+	db, err := sqlite3.New(cfg.DB, func(op string, d time.Duration) {
+		queries.Observe(d.Seconds(), op)
+	})
+	if err != nil {
+		errorLog.Fatalln(err)
+	}
+	reg.NewDBStats(db)
+	mailer, err := mail.NewFileSender(cfg.MailOutbox)
 	if err != nil {
 		errorLog.Fatalln(err)
 	}
 	authRepo := repository.NewSessionsRepository(db, errorLog)
-	aucase := usecase.NewAuthUsecase(authRepo, errorLog)
-	return &Handler{errorLog: errorLog, aucase: aucase}
+	tokensRepo := repository.NewTokensRepository(db, errorLog)
+	identitiesRepo := repository.NewIdentitiesRepository(db, errorLog)
+	twoFactorRepo := repository.NewTwoFactorRepository(db, errorLog)
+	guard := usecase.NewLoginGuard(repository.NewLoginFailuresRepository(db, errorLog), cfg.Login.Policy(), infoLog, errorLog)
+	appOptions := cfg.Client.Options(cfg.AppURL)
+	appOptions.Latency = reg.NewHistogram("upstream_request_duration_seconds", "Duration of the calls to forum_app, by service, method, path and status.",
+		metrics.DefaultBuckets, "service", "method", "path", "status")
+	aucase := usecase.NewAuthUsecase(authRepo, tokensRepo, identitiesRepo, twoFactorRepo, guard, mailer, client.NewApp(appOptions), cfg.GatewayURL, cfg.Session.Lifetime(), errorLog)
+	reaper := usecase.NewSessionReaper(authRepo, time.Duration(cfg.Session.ReaperInterval), infoLog, errorLog)
+	reg.NewGaugeFunc("auth_active_sessions", "Sessions that are not expired.", func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), duration)
+		defer cancel()
+		active, err := reaper.Active(ctx)
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(active)
+	})
+	return &Handler{log: logs, errorLog: errorLog, aucase: aucase, reaper: reaper, guard: guard, db: db}
+}
+
+// background runs f in a goroutine that Close waits for
+func (h *Handler) background(f func()) {
+	h.tasks.Add(1)
+	go func() {
+		defer h.tasks.Done()
+		f()
+	}()
+}
+
+// Close waits for the running usecase calls, at most until ctx is done, and closes the database
+func (h *Handler) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		h.logError(ctx, "closing the database with usecase calls still running:", ctx.Err())
+	}
+	return h.db.Close()
 }
 
 func (h *Handler) SignInHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errorLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	sessionChan := make(chan entity.SessionResult)
+	sessionChan := make(chan entity.SessionResult, 1)
 	var (
 		sessionRes  entity.SessionResult
 		err         error
@@ -47,41 +113,55 @@ func (h *Handler) SignInHandler(w http.ResponseWriter, r *http.Request) {
 	)
 	err = json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil || !validateCredentials(credentials) {
-		h.errorLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	go h.aucase.SignIn(ctx, credentials, sessionChan)
+	h.background(func() { h.aucase.SignIn(ctx, credentials, sessionChan) })
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errorLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	case sessionRes = <-sessionChan:
 		err = sessionRes.Err
 		if err != nil {
-			h.errorLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) {
 				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
 				return
 			}
 			switch err {
-			case entity.ErrNotFound:
-				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "User with a given email doesn't exist"})
+			case entity.ErrInvalidCredentials:
+				h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: err.Error()})
+			case entity.ErrLockedOut:
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter(sessionRes.LockedUntil)))
+				h.APIResponse(w, http.StatusTooManyRequests, entity.Response{ErrorMessage: err.Error()})
 			case entity.ErrRequestTimeout:
 				h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
-			case entity.ErrInvalidPassword:
-				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Invalid Password"})
 			default:
 				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			}
 			return
 		}
 	}
+	if sessionRes.Pending.Token != "" {
+		h.APIResponse(w, http.StatusAccepted, entity.Response{Body: sessionRes.Pending})
+		return
+	}
 	h.APIResponse(w, http.StatusCreated, entity.Response{Body: sessionRes.Session})
 }
 
+// retryAfter is the Retry-After of a lockout in whole seconds, rounded up
+func retryAfter(lockedUntil time.Time) int {
+	seconds := int(time.Until(lockedUntil).Seconds()) + 1
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
 func validateCredentials(credentials entity.Credentials) bool {
 	return credentials.Email != "" && credentials.Password != ""
 }
@@ -94,11 +174,11 @@ func (h *Handler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errorLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	credentialsChan := make(chan entity.CredentialsResult)
+	credentialsChan := make(chan entity.CredentialsResult, 1)
 	var (
 		credentialsRes entity.CredentialsResult
 		err            error
@@ -107,16 +187,16 @@ func (h *Handler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil {
-		h.errorLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	go h.aucase.SignUp(ctx, credentials, credentialsChan)
+	h.background(func() { h.aucase.SignUp(ctx, credentials, credentialsChan) })
 	select {
 	case credentialsRes = <-credentialsChan:
 		err := credentialsRes.Err
 		if err != nil {
-			h.errorLog.Println(err)
+			h.logError(ctx, err)
 			switch err {
 			case entity.ErrEmailExists:
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "User with a given email already exists"})
@@ -129,7 +209,7 @@ func (h *Handler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errorLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	}
@@ -140,26 +220,26 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodGet {
-		h.errorLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	authStatusChan := make(chan entity.AuthStatusResult)
+	authStatusChan := make(chan entity.AuthStatusResult, 1)
 	var (
 		authStatusRes entity.AuthStatusResult
 		session       entity.Session
 	)
 	err := json.NewDecoder(r.Body).Decode(&session)
 	if err != nil {
-		h.errorLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	go h.aucase.Authenticate(ctx, session, authStatusChan)
+	h.background(func() { h.aucase.Authenticate(ctx, session, authStatusChan) })
 	select {
 	case authStatusRes = <-authStatusChan:
 		if authStatusRes.Err != nil {
-			h.errorLog.Println(authStatusRes.Err)
+			h.logError(ctx, authStatusRes.Err)
 			w.Header().Set("Content-Type", "application/json")
 			authStatusRes.Err = nil
 			h.APIResponse(w, http.StatusOK, entity.Response{Body: authStatusRes})
@@ -167,7 +247,7 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errorLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	}
@@ -178,29 +258,29 @@ func (h *Handler) SignOutHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodDelete {
-		h.errorLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	var session entity.Session
 	err := json.NewDecoder(r.Body).Decode(&session)
 	if err != nil {
-		h.errorLog.Println("bad request")
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	go h.aucase.SignOut(ctx, session, errChan)
+	h.background(func() { h.aucase.SignOut(ctx, session, errChan) })
 	select {
 	case err = <-errChan:
 		if err != nil {
-			h.errorLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
 			return
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errorLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	}
@@ -211,41 +291,45 @@ func (h *Handler) OauthSignInHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	if r.Method != http.MethodPost {
-		h.errorLog.Println(fmt.Sprintf("method not allowed: %s", r.Method))
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
 		return
 	}
-	sessionChan := make(chan entity.SessionResult)
+	sessionChan := make(chan entity.SessionResult, 1)
 	var (
 		sessionRes  entity.SessionResult
 		err         error
 		credentials entity.Credentials
 	)
 	err = json.NewDecoder(r.Body).Decode(&credentials)
-	if err != nil {
-		h.errorLog.Println("bad request")
+	if err != nil || credentials.Provider == "" || credentials.Subject == "" {
+		h.logError(ctx, "bad request")
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
 		return
 	}
-	go h.aucase.OauthSignIn(ctx, credentials, sessionChan)
+	h.background(func() { h.aucase.OauthSignIn(ctx, credentials, sessionChan) })
 	select {
 	case sessionRes = <-sessionChan:
 		err = sessionRes.Err
 		if err != nil {
-			h.errorLog.Println(err)
+			h.logError(ctx, err)
 			if isConstraintError(err) {
 				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
 				return
 			}
-			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			h.identityErrorResponse(w, err)
 			return
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errorLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
 		return
 	}
+	if sessionRes.Pending.Token != "" {
+		h.APIResponse(w, http.StatusAccepted, entity.Response{Body: sessionRes.Pending})
+		return
+	}
 	h.APIResponse(w, http.StatusCreated, entity.Response{Body: sessionRes.Session})
 }
 
@@ -266,9 +350,17 @@ func (h *Handler) APIResponse(w http.ResponseWriter, code int, response entity.R
 	w.Write(jsonResponse)
 }
 
+// getTimeout detaches the work of a request from its connection, the request ID is kept for the logs and
+// the calls to forum_app
 func getTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := logger.WithRequestID(context.Background(), logger.RequestID(ctx))
 	if deadline, ok := ctx.Deadline(); ok {
-		return context.WithDeadline(context.Background(), deadline)
+		return context.WithDeadline(detached, deadline)
 	}
-	return context.WithTimeout(context.Background(), duration)
+	return context.WithTimeout(detached, duration)
+}
+
+// logError logs v like errorLog.Println, along with the request ID of ctx
+func (h *Handler) logError(ctx context.Context, v ...interface{}) {
+	h.log.Output(ctx, 2, logger.LevelError, fmt.Sprintln(v...))
 }
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) SessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var session entity.Session
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil || session.Token == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	sessionsChan := make(chan entity.SessionsResult, 1)
+	var sessionsRes entity.SessionsResult
+	h.background(func() { h.aucase.Sessions(ctx, session, sessionsChan) })
+	select {
+	case sessionsRes = <-sessionsChan:
+		if err = sessionsRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.sessionErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: sessionsRes.Sessions})
+}
+
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var revoke entity.SessionRevoke
+	h.revokeSessions(w, r, &revoke, func() bool {
+		return revoke.Token != "" && revoke.Id != 0
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.RevokeSession(ctx, revoke, errChan)
+	})
+}
+
+func (h *Handler) RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	var session entity.Session
+	h.revokeSessions(w, r, &session, func() bool {
+		return session.Token != ""
+	}, func(ctx context.Context, errChan chan error) {
+		h.aucase.RevokeOtherSessions(ctx, session, errChan)
+	})
+}
+
+// revokeSessions decodes the DELETE body into req and runs apply with the timeout of the request
+func (h *Handler) revokeSessions(w http.ResponseWriter, r *http.Request, req interface{}, valid func() bool, apply func(context.Context, chan error)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodDelete {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || !valid() {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, errChan) })
+	select {
+	case err := <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.sessionErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+func (h *Handler) sessionErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrInvalidSession:
+		h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: "Unauthorized"})
+	case entity.ErrForbidden:
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"})
+	case entity.ErrNotFound:
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"})
+	default:
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+	}
+}
+
+// SweepSessionsHandler reports what the session reaper has purged, POST forces a sweep first; admins only
+func (h *Handler) SweepSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var session entity.Session
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil || session.Token == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.aucase.RequireRole(ctx, session, entity.RoleAdmin, errChan) })
+	select {
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.sessionErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	reaperChan := make(chan entity.ReaperResult, 1)
+	var reaperRes entity.ReaperResult
+	if r.Method == http.MethodPost {
+		h.background(func() { h.reaper.Sweep(ctx, reaperChan) })
+	} else {
+		h.background(func() { h.reaper.Stats(ctx, reaperChan) })
+	}
+	select {
+	case reaperRes = <-reaperChan:
+		if err = reaperRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: reaperRes.Stats})
+}
+
+// LockoutsHandler returns the audit log of sign in lockouts; admins only
+func (h *Handler) LockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodGet {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var session entity.Session
+	err := json.NewDecoder(r.Body).Decode(&session)
+	if err != nil || session.Token == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { h.aucase.RequireRole(ctx, session, entity.RoleAdmin, errChan) })
+	select {
+	case err = <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.sessionErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	lockoutsChan := make(chan entity.LockoutsResult, 1)
+	var lockoutsRes entity.LockoutsResult
+	h.background(func() { h.guard.Lockouts(ctx, lockoutsChan) })
+	select {
+	case lockoutsRes = <-lockoutsChan:
+		if err = lockoutsRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"})
+			return
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: lockoutsRes.Lockouts})
+}
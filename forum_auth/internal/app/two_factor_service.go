@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/http"
+	"strconv"
+)
+
+// TwoFactorSignInHandler finishes a sign in that answered 202 with the code of the user's app or a recovery code
+func (h *Handler) TwoFactorSignInHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	if r.Method != http.MethodPost {
+		h.logError(ctx, fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return
+	}
+	var req entity.TwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || req.Code == "" {
+		h.logError(ctx, "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return
+	}
+	sessionChan := make(chan entity.SessionResult, 1)
+	var sessionRes entity.SessionResult
+	h.background(func() { h.aucase.FinishSignIn(ctx, req, sessionChan) })
+	select {
+	case sessionRes = <-sessionChan:
+		if err := sessionRes.Err; err != nil {
+			h.logError(ctx, err)
+			if err == entity.ErrLockedOut {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter(sessionRes.LockedUntil)))
+				h.APIResponse(w, http.StatusTooManyRequests, entity.Response{ErrorMessage: err.Error()})
+				return
+			}
+			h.twoFactorErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusCreated, entity.Response{Body: sessionRes.Session})
+}
+
+func (h *Handler) TwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	h.twoFactorQuery(w, r, http.MethodGet, nil, func(ctx context.Context, req entity.TwoFactorRequest, tfRes chan entity.TwoFactorResult) {
+		h.aucase.TwoFactor(ctx, entity.Session{Token: req.Token}, tfRes)
+	}, func(res entity.TwoFactorResult) interface{} {
+		return res.TwoFactor
+	})
+}
+
+// SetupTwoFactorHandler returns the secret and the otpauth URI to add to an authenticator app
+func (h *Handler) SetupTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	h.twoFactorQuery(w, r, http.MethodPost, nil, func(ctx context.Context, req entity.TwoFactorRequest, tfRes chan entity.TwoFactorResult) {
+		h.aucase.SetupTwoFactor(ctx, entity.Session{Token: req.Token}, tfRes)
+	}, func(res entity.TwoFactorResult) interface{} {
+		return res.Setup
+	})
+}
+
+// EnableTwoFactorHandler checks the first code of the app and returns the recovery codes
+func (h *Handler) EnableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	h.twoFactorQuery(w, r, http.MethodPost, func(req entity.TwoFactorRequest) bool {
+		return req.Code != ""
+	}, h.aucase.EnableTwoFactor, func(res entity.TwoFactorResult) interface{} {
+		return res.Setup
+	})
+}
+
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	h.twoFactorCommand(w, r, func(req entity.TwoFactorRequest) bool {
+		return req.Code != ""
+	}, h.aucase.DisableTwoFactor)
+}
+
+// ResetTwoFactorHandler turns off the two-factor authentication of the user; admins only
+func (h *Handler) ResetTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	h.twoFactorCommand(w, r, func(req entity.TwoFactorRequest) bool {
+		return req.UserId != 0
+	}, h.aucase.ResetTwoFactor)
+}
+
+func (h *Handler) twoFactorQuery(w http.ResponseWriter, r *http.Request, method string, valid func(entity.TwoFactorRequest) bool, apply func(context.Context, entity.TwoFactorRequest, chan entity.TwoFactorResult), body func(entity.TwoFactorResult) interface{}) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	req, ok := h.twoFactorRequest(w, r, method, valid)
+	if !ok {
+		return
+	}
+	tfChan := make(chan entity.TwoFactorResult, 1)
+	var tfRes entity.TwoFactorResult
+	h.background(func() { apply(ctx, req, tfChan) })
+	select {
+	case tfRes = <-tfChan:
+		if err := tfRes.Err; err != nil {
+			h.logError(ctx, err)
+			h.twoFactorErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusOK, entity.Response{Body: body(tfRes)})
+}
+
+func (h *Handler) twoFactorCommand(w http.ResponseWriter, r *http.Request, valid func(entity.TwoFactorRequest) bool, apply func(context.Context, entity.TwoFactorRequest, chan error)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	req, ok := h.twoFactorRequest(w, r, http.MethodDelete, valid)
+	if !ok {
+		return
+	}
+	errChan := make(chan error, 1)
+	h.background(func() { apply(ctx, req, errChan) })
+	select {
+	case err := <-errChan:
+		if err != nil {
+			h.logError(ctx, err)
+			h.twoFactorErrorResponse(w, err)
+			return
+		}
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+		return
+	}
+	h.APIResponse(w, http.StatusNoContent, entity.Response{})
+}
+
+// twoFactorRequest checks the method and decodes a request with a token, valid checks the rest when not nil
+func (h *Handler) twoFactorRequest(w http.ResponseWriter, r *http.Request, method string, valid func(entity.TwoFactorRequest) bool) (entity.TwoFactorRequest, bool) {
+	if r.Method != method {
+		h.logError(r.Context(), fmt.Sprintf("method not allowed: %s", r.Method))
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{})
+		return entity.TwoFactorRequest{}, false
+	}
+	var req entity.TwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || (valid != nil && !valid(req)) {
+		h.logError(r.Context(), "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"})
+		return entity.TwoFactorRequest{}, false
+	}
+	return req, true
+}
+
+func (h *Handler) twoFactorErrorResponse(w http.ResponseWriter, err error) {
+	switch err {
+	case entity.ErrInvalidCode:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Invalid code"})
+	case entity.ErrInvalidToken:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Invalid or expired token"})
+	case entity.ErrInvalidCredentials:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: err.Error()})
+	case entity.ErrLockedOut:
+		h.APIResponse(w, http.StatusTooManyRequests, entity.Response{ErrorMessage: err.Error()})
+	case entity.ErrTwoFactorEnabled:
+		h.APIResponse(w, http.StatusConflict, entity.Response{ErrorMessage: err.Error()})
+	case entity.ErrRequestTimeout:
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"})
+	default:
+		h.sessionErrorResponse(w, err)
+	}
+}
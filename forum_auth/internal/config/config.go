@@ -0,0 +1,214 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"forum_auth/internal/client"
+	"forum_auth/internal/entity"
+	"forum_auth/pkg/logger"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Addr is where the API listens, forum_gateway is configured with it
+	Addr string `json:"addr"`
+	// DB is the path of the SQLite database of sessions, tokens and identities
+	DB      string `json:"db"`
+	LogFile string `json:"log_file"`
+	// LogLevel is the least important level logged: debug, info, warn or error
+	LogLevel string `json:"log_level"`
+	// LogMaxSize is the size in megabytes the log file is rotated at, LogMaxBackups rotated files are kept
+	LogMaxSize    int `json:"log_max_size"`
+	LogMaxBackups int `json:"log_max_backups"`
+	// Timeout bounds the handling of a request
+	Timeout Duration `json:"timeout"`
+	// AppURL is the base URL of forum_app, where users are stored
+	AppURL string `json:"app_url"`
+	// Client is how the other services are called
+	Client Client `json:"client"`
+	// GatewayURL is where the links in the mails point to
+	GatewayURL string `json:"gateway_url"`
+	// MailOutbox is the file the development mail sender appends to, mails go to stdout when it is empty
+	MailOutbox string  `json:"mail_outbox"`
+	Session    Session `json:"session"`
+	Login      Login   `json:"login"`
+	// PrintConfig asks to print the config instead of running
+	PrintConfig bool `json:"-"`
+}
+
+// Session is how long sessions stay valid without activity, at most, and with remember-me, and how often
+// the expired ones are purged
+type Session struct {
+	Idle           Duration `json:"idle_timeout"`
+	Absolute       Duration `json:"absolute_timeout"`
+	Remember       Duration `json:"remember_timeout"`
+	ReaperInterval Duration `json:"reaper_interval"`
+}
+
+// Login is when failed sign ins lock accounts and IPs out, thresholds of 0 turn the lockouts off
+type Login struct {
+	Threshold   int      `json:"lockout_threshold"`
+	IPThreshold int      `json:"ip_lockout_threshold"`
+	Window      Duration `json:"failure_window"`
+	Lockout     Duration `json:"lockout"`
+	MaxLockout  Duration `json:"max_lockout"`
+}
+
+// Client is how long an attempt of a call to another service may take, how often idempotent calls are retried
+// and how long the first retry waits
+type Client struct {
+	Timeout Duration `json:"timeout"`
+	Retries int      `json:"retries"`
+	Backoff Duration `json:"backoff"`
+}
+
+func Default() *Config {
+	return &Config{
+		Addr:          ":8081",
+		DB:            "./session.db",
+		LogFile:       "forum_auth.log",
+		LogLevel:      "info",
+		LogMaxSize:    10,
+		LogMaxBackups: 5,
+		Timeout:       Duration(10 * time.Second),
+		AppURL:        "http://localhost:8080",
+		Client: Client{
+			Timeout: Duration(5 * time.Second),
+			Retries: 2,
+			Backoff: Duration(100 * time.Millisecond),
+		},
+		GatewayURL: "https://localhost:8082",
+		Session: Session{
+			Idle:           Duration(10 * time.Minute),
+			Absolute:       Duration(24 * time.Hour),
+			Remember:       Duration(30 * 24 * time.Hour),
+			ReaperInterval: Duration(time.Hour),
+		},
+		Login: Login{
+			Threshold:   5,
+			IPThreshold: 20,
+			Window:      Duration(time.Hour),
+			Lockout:     Duration(time.Minute),
+			MaxLockout:  Duration(time.Hour),
+		},
+	}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", "HTTP_ADDR", "`address` to listen on", (*stringValue)(&c.Addr)},
+		{"db", "DB_PATH", "`path` of the SQLite database", (*stringValue)(&c.DB)},
+		{"log-file", "LOG_FILE", "`file` logs are appended to", (*stringValue)(&c.LogFile)},
+		{"log-level", "LOG_LEVEL", "least important `level` logged: debug, info, warn or error", (*stringValue)(&c.LogLevel)},
+		{"log-max-size", "LOG_MAX_SIZE", "`megabytes` the log file is rotated at", (*intValue)(&c.LogMaxSize)},
+		{"log-max-backups", "LOG_MAX_BACKUPS", "rotated log files kept", (*intValue)(&c.LogMaxBackups)},
+		{"timeout", "REQUEST_TIMEOUT", "time a request may take", &c.Timeout},
+		{"app-url", "APP_URL", "base `URL` of forum_app", (*stringValue)(&c.AppURL)},
+		{"client-timeout", "CLIENT_TIMEOUT", "time an attempt of a call to another service may take", &c.Client.Timeout},
+		{"client-retries", "CLIENT_RETRIES", "retries of idempotent calls to another service", (*intValue)(&c.Client.Retries)},
+		{"client-backoff", "CLIENT_BACKOFF", "wait before the first retry, doubled with every further one", &c.Client.Backoff},
+		{"gateway-url", "GATEWAY_URL", "public `URL` of the gateway, for the links in mails", (*stringValue)(&c.GatewayURL)},
+		{"mail-outbox", "MAIL_OUTBOX", "`file` mails are appended to, stdout when empty", (*stringValue)(&c.MailOutbox)},
+		{"session-idle-timeout", "SESSION_IDLE_TIMEOUT", "time a session lasts without activity", &c.Session.Idle},
+		{"session-absolute-timeout", "SESSION_ABSOLUTE_TIMEOUT", "time a session lasts at most", &c.Session.Absolute},
+		{"session-remember-timeout", "SESSION_REMEMBER_TIMEOUT", "time a remember-me session lasts", &c.Session.Remember},
+		{"session-reaper-interval", "SESSION_REAPER_INTERVAL", "time between purges of expired sessions", &c.Session.ReaperInterval},
+		{"login-lockout-threshold", "LOGIN_LOCKOUT_THRESHOLD", "failed sign ins that lock an account out, 0 for never", (*intValue)(&c.Login.Threshold)},
+		{"login-ip-lockout-threshold", "LOGIN_IP_LOCKOUT_THRESHOLD", "failed sign ins that lock an IP out, 0 for never", (*intValue)(&c.Login.IPThreshold)},
+		{"login-failure-window", "LOGIN_FAILURE_WINDOW", "time failed sign ins are counted for", &c.Login.Window},
+		{"login-lockout", "LOGIN_LOCKOUT", "first lockout, doubled with every further failure", &c.Login.Lockout},
+		{"login-max-lockout", "LOGIN_MAX_LOCKOUT", "longest lockout", &c.Login.MaxLockout},
+	}
+}
+
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	urls := []struct {
+		name  string
+		value *string
+	}{
+		{"app_url", &c.AppURL},
+		{"gateway_url", &c.GatewayURL},
+	}
+	for _, u := range urls {
+		*u.value = strings.TrimRight(*u.value, "/")
+		if err := validateURL(*u.value); err != nil {
+			return fmt.Errorf("%s: %w", u.name, err)
+		}
+	}
+	durations := []struct {
+		name  string
+		value Duration
+	}{
+		{"timeout", c.Timeout},
+		{"client.timeout", c.Client.Timeout},
+		{"client.backoff", c.Client.Backoff},
+		{"session.idle_timeout", c.Session.Idle},
+		{"session.absolute_timeout", c.Session.Absolute},
+		{"session.remember_timeout", c.Session.Remember},
+		{"session.reaper_interval", c.Session.ReaperInterval},
+		{"login.failure_window", c.Login.Window},
+		{"login.lockout", c.Login.Lockout},
+		{"login.max_lockout", c.Login.MaxLockout},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("%s: must be positive", d.name)
+		}
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	switch {
+	case c.DB == "":
+		return errors.New("db: missing path")
+	case c.LogFile == "":
+		return errors.New("log_file: missing file")
+	case c.LogMaxSize <= 0:
+		return errors.New("log_max_size: must be positive")
+	case c.LogMaxBackups < 0:
+		return errors.New("log_max_backups: must not be negative")
+	case c.Client.Retries < 0:
+		return errors.New("client.retries: must not be negative")
+	case c.Login.Threshold < 0 || c.Login.IPThreshold < 0:
+		return errors.New("login: thresholds must not be negative")
+	case c.Login.MaxLockout < c.Login.Lockout:
+		return errors.New("login.max_lockout: shorter than login.lockout")
+	}
+	return nil
+}
+
+// validateURL accepts absolute http and https URLs
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
+	return nil
+}
+
+func (s Session) Lifetime() entity.SessionLifetime {
+	return entity.SessionLifetime{Idle: time.Duration(s.Idle), Absolute: time.Duration(s.Absolute), Remember: time.Duration(s.Remember)}
+}
+
+func (l Login) Policy() entity.LoginPolicy {
+	return entity.LoginPolicy{
+		Threshold:   l.Threshold,
+		IPThreshold: l.IPThreshold,
+		Window:      time.Duration(l.Window),
+		BaseLockout: time.Duration(l.Lockout),
+		MaxLockout:  time.Duration(l.MaxLockout),
+	}
+}
+
+func (c Client) Options(baseURL string) client.Options {
+	return client.Options{BaseURL: baseURL, Timeout: time.Duration(c.Timeout), Retries: c.Retries, Backoff: time.Duration(c.Backoff)}
+}
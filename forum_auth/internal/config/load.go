@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// setting binds a field of the config to its environment variable and its flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+// Load builds the config from the defaults, the JSON file named by -config or CONFIG_FILE, the environment
+// and the flags, each overriding the ones before. It returns the arguments after the flags, like a subcommand
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	fs := flag.NewFlagSet("forum_auth", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config `file` (env CONFIG_FILE)")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the resulting config and exit")
+	// flags are parsed into a config of their own, so that they can be applied last
+	flags := Default()
+	for _, s := range flags.settings() {
+		fs.Var(s.value, s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if *file != "" {
+		if err := readFile(*file, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	settings := map[string]setting{}
+	for _, s := range cfg.settings() {
+		settings[s.flag] = s
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.value.Set(value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := settings[f.Name]; ok && err == nil {
+			err = s.value.Set(f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func readFile(path string, cfg *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Print writes the config as JSON, in the format of the config file
+func (c *Config) Print(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// Duration is a time.Duration written like "90m" in files, variables and flags
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+type stringValue string
+
+func (s *stringValue) String() string {
+	return string(*s)
+}
+
+func (s *stringValue) Set(value string) error {
+	*s = stringValue(value)
+	return nil
+}
+
+type intValue int
+
+func (i *intValue) String() string {
+	return strconv.Itoa(int(*i))
+}
+
+func (i *intValue) Set(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*i = intValue(n)
+	return nil
+}
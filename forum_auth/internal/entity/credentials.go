@@ -5,6 +5,16 @@ type Credentials struct {
 	Name     string `json:"name,omitempty"`
 	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Verified bool   `json:"email_verified,omitempty"`
+	// the device signing in, recorded in the session
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	// RememberMe asks for a long-lived persistent session
+	RememberMe bool `json:"remember_me,omitempty"`
+	// Provider and Subject identify the OAuth account signing in
+	Provider string `json:"provider,omitempty"`
+	Subject  string `json:"subject,omitempty"`
 }
 
 type CredentialsResult struct {
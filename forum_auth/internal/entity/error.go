@@ -8,4 +8,17 @@ var (
 	ErrInternalServer  = errors.New("Internal Server Error")
 	ErrInvalidPassword = errors.New("Invalid password")
 	ErrEmailExists     = errors.New("Email already exists")
+	ErrInvalidToken    = errors.New("Invalid or expired token")
+	ErrInvalidSession  = errors.New("Invalid session")
+	ErrForbidden       = errors.New("Forbidden")
+	// identity errors
+	ErrIdentityConflict = errors.New("Email belongs to another account")
+	ErrIdentityLinked   = errors.New("Identity is linked to another account")
+	ErrLastIdentity     = errors.New("Last way to sign in")
+	// sign in errors, unknown emails and wrong passwords are not told apart
+	ErrInvalidCredentials = errors.New("Invalid email or password")
+	ErrLockedOut          = errors.New("Too many failed sign in attempts")
+	// two-factor errors
+	ErrInvalidCode      = errors.New("Invalid code")
+	ErrTwoFactorEnabled = errors.New("Two-factor authentication is already enabled")
 )
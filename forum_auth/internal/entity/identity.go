@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Identity is an account of an OAuth provider linked to a forum user, Subject is the id of the account at Provider
+type Identity struct {
+	Id        int64     `json:"id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+	UserId    int64     `json:"user_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// IdentityRequest links the account described by Credentials to the owner of Token, or unlinks the one of Provider
+type IdentityRequest struct {
+	Token       string      `json:"token,omitempty"`
+	Provider    string      `json:"provider,omitempty"`
+	Credentials Credentials `json:"credentials,omitempty"`
+}
+
+type IdentitiesResult struct {
+	Identities []Identity
+	Err        error
+}
@@ -0,0 +1,64 @@
+package entity
+
+import "time"
+
+// failed sign ins are counted per account and per IP
+const (
+	LoginKindAccount = "account"
+	LoginKindIP      = "ip"
+)
+
+// LoginPolicy is when failed sign ins lock out: from the Threshold-th failure within Window on, each failure
+// locks the account for BaseLockout, doubled for every failure past the threshold up to MaxLockout. IPs get
+// their own, higher, threshold since many users can share one
+type LoginPolicy struct {
+	Threshold   int
+	IPThreshold int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Lockout returns how long failures failed sign ins of the kind lock out for, 0 below the threshold
+func (p LoginPolicy) Lockout(kind string, failures int) time.Duration {
+	threshold := p.Threshold
+	if kind == LoginKindIP {
+		threshold = p.IPThreshold
+	}
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+	lockout := p.BaseLockout
+	for i := threshold; i < failures && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+// LoginFailure counts the failed sign ins of an account, by its email, or of an IP
+type LoginFailure struct {
+	Kind        string
+	Value       string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Lockout is an entry of the audit log of lockouts
+type Lockout struct {
+	Id          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	Failures    int       `json:"failures"`
+	IP          string    `json:"ip,omitempty"`
+	LockedUntil time.Time `json:"locked_until"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LockoutsResult struct {
+	Lockouts []Lockout
+	Err      error
+}
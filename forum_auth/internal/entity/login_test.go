@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockout(t *testing.T) {
+	p := LoginPolicy{Threshold: 5, IPThreshold: 20, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	tests := []struct {
+		kind     string
+		failures int
+		want     time.Duration
+	}{
+		{LoginKindAccount, 1, 0},
+		{LoginKindAccount, 4, 0},
+		{LoginKindAccount, 5, time.Minute},
+		{LoginKindAccount, 6, 2 * time.Minute},
+		{LoginKindAccount, 7, 4 * time.Minute},
+		{LoginKindAccount, 10, 32 * time.Minute},
+		{LoginKindAccount, 11, time.Hour},
+		{LoginKindAccount, 1000, time.Hour},
+		{LoginKindIP, 5, 0},
+		{LoginKindIP, 19, 0},
+		{LoginKindIP, 20, time.Minute},
+		{LoginKindIP, 21, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.Lockout(tt.kind, tt.failures); got != tt.want {
+			t.Errorf("Lockout(%s, %d) = %v, want %v", tt.kind, tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutLimits(t *testing.T) {
+	if got := (LoginPolicy{BaseLockout: time.Minute, MaxLockout: time.Hour}).Lockout(LoginKindAccount, 100); got != 0 {
+		t.Errorf("a threshold of 0 locked out for %v", got)
+	}
+	// a maximum below the base lockout caps it from the first lockout on
+	p := LoginPolicy{Threshold: 1, BaseLockout: time.Hour, MaxLockout: time.Minute}
+	for _, failures := range []int{1, 2, 50} {
+		if got := p.Lockout(LoginKindAccount, failures); got != time.Minute {
+			t.Errorf("Lockout(%d) = %v, want the maximum", failures, got)
+		}
+	}
+}
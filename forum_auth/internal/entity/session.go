@@ -3,14 +3,90 @@ package entity
 import "time"
 
 type Session struct {
+	Id         int64     `json:"id,omitempty"`
 	Token      string    `json:"token,omitempty"`
 	UserId     int64     `json:"user_id,omitempty"`
+	Role       string    `json:"role,omitempty"`
 	ExpiryTime time.Time `json:"expiry_time,omitempty"`
+	// AbsoluteExpiry is the moment the session ends however active it is
+	AbsoluteExpiry time.Time `json:"absolute_expiry,omitempty"`
+	// Persistent sessions were opened with remember-me
+	Persistent bool      `json:"persistent,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	LastSeen   time.Time `json:"last_seen,omitempty"`
+	// Current marks the session the list was asked for
+	Current bool `json:"current,omitempty"`
 }
 
+// Expired reports whether the session has been idle for too long or has outlived its absolute expiry
+func (s Session) Expired(now time.Time) bool {
+	return now.After(s.EndsAt())
+}
+
+// EndsAt is when the session expires unless it is used before, the earlier of its idle and absolute expiry
+func (s Session) EndsAt() time.Time {
+	if !s.AbsoluteExpiry.IsZero() && s.AbsoluteExpiry.Before(s.ExpiryTime) {
+		return s.AbsoluteExpiry
+	}
+	return s.ExpiryTime
+}
+
+// SessionLifetime is how long sessions live, remember-me sessions use Remember for both timeouts
+type SessionLifetime struct {
+	Idle     time.Duration
+	Absolute time.Duration
+	Remember time.Duration
+}
+
+// Timeouts returns the idle and absolute timeouts of a new session
+func (l SessionLifetime) Timeouts(persistent bool) (time.Duration, time.Duration) {
+	if persistent {
+		return l.Remember, l.Remember
+	}
+	return l.Idle, l.Absolute
+}
+
+// SessionRevoke signs out the session with Id, the request is made on behalf of the owner of Token
+type SessionRevoke struct {
+	Token string `json:"token,omitempty"`
+	Id    int64  `json:"id,omitempty"`
+}
+
+type SessionsResult struct {
+	Sessions []Session
+	Err      error
+}
+
+// ReaperStats counts what the session reaper has purged since forum_auth started
+type ReaperStats struct {
+	Sweeps     int64     `json:"sweeps"`
+	Purged     int64     `json:"purged"`
+	LastPurged int64     `json:"last_purged"`
+	LastSweep  time.Time `json:"last_sweep,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+type ReaperResult struct {
+	Stats ReaperStats
+	Err   error
+}
+
+// roles are assigned in forum_app, the session carries the role the user had when signing in
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// SessionResult has a Pending sign in instead of a Session when the user still has to enter a code,
+// LockedUntil says when to retry after ErrLockedOut
 type SessionResult struct {
-	Session Session
-	Err     error
+	Session     Session
+	Pending     PendingSignIn
+	LockedUntil time.Time
+	Err         error
 }
 
 type AuthStatus int
@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// purposes of one-time tokens, a token is only accepted for the purpose it was issued for
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+var tokenLifetimes = map[string]time.Duration{
+	PurposePasswordReset:     time.Hour,
+	PurposeEmailVerification: 24 * time.Hour,
+}
+
+func TokenLifetime(purpose string) time.Duration {
+	return tokenLifetimes[purpose]
+}
+
+// OneTimeToken is sent to the user by email, only its hash is stored
+type OneTimeToken struct {
+	Token      string
+	UserId     int64
+	Email      string
+	Purpose    string
+	ExpiryTime time.Time
+}
+
+type PasswordReset struct {
+	Token    string `json:"token,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// VerificationRequest asks to mail a confirmation link to the user, or confirms the email with the token
+type VerificationRequest struct {
+	UserId int64  `json:"user_id,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
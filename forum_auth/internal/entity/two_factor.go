@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+const (
+	// PendingSignInLifetime is how long a user has to enter the code after the password
+	PendingSignInLifetime = 5 * time.Minute
+	// MaxTwoFactorAttempts wrong codes end the pending sign in
+	MaxTwoFactorAttempts = 5
+	RecoveryCodeCount    = 10
+)
+
+// TwoFactor is the TOTP enrollment of a user, it only protects sign in once Enabled
+type TwoFactor struct {
+	UserId  int64  `json:"-"`
+	Secret  string `json:"-"`
+	Enabled bool   `json:"enabled"`
+	// LastStep is the time step of the last accepted code, a code is never accepted twice
+	LastStep      int64 `json:"-"`
+	RecoveryCodes int   `json:"recovery_codes"`
+}
+
+// PendingSignIn is a sign in waiting for the second factor, only the hash of Token is stored
+type PendingSignIn struct {
+	Token      string    `json:"two_factor_token,omitempty"`
+	UserId     int64     `json:"-"`
+	Persistent bool      `json:"-"`
+	Attempts   int       `json:"-"`
+	ExpiryTime time.Time `json:"expiry_time,omitempty"`
+}
+
+// TwoFactorSetup is shown once: the secret while enrolling and the recovery codes when it is enabled
+type TwoFactorSetup struct {
+	Secret        string   `json:"secret,omitempty"`
+	URI           string   `json:"uri,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorRequest carries a code on behalf of the owner of Token, the token of a session or of a pending
+// sign in; admins reset the two-factor authentication of UserId
+type TwoFactorRequest struct {
+	Token     string `json:"token,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Password  string `json:"password,omitempty"`
+	UserId    int64  `json:"user_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+type TwoFactorResult struct {
+	TwoFactor TwoFactor
+	Setup     TwoFactorSetup
+	Err       error
+}
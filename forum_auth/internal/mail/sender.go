@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"forum_auth/internal/entity"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterSender writes the mails out instead of delivering them, it is meant for local development
+type WriterSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSender(w io.Writer) *WriterSender {
+	return &WriterSender{w: w}
+}
+
+// NewFileSender appends the mails to the file at path, or prints them to stdout when path is empty
+func NewFileSender(path string) (*WriterSender, error) {
+	if path == "" {
+		return NewWriterSender(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSender(f), nil
+}
+
+func (s *WriterSender) Send(ctx context.Context, mail entity.Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.w, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), mail.To, mail.Subject, mail.Body)
+	return err
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum_auth/internal/entity"
+	"log"
+	"strings"
+	"time"
+)
+
+type IdentitiesRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewIdentitiesRepository(db *sql.DB, errorLog *log.Logger) *IdentitiesRepository {
+	return &IdentitiesRepository{db, errorLog}
+}
+
+// Fetch finds the identity of the account at the provider, ErrNotFound means it isn't linked to anyone
+func (ir *IdentitiesRepository) Fetch(ctx context.Context, provider, subject string) (entity.Identity, error) {
+	identities, err := ir.fetch(ctx, "SELECT "+identityColumns+" FROM identities WHERE provider=? AND subject=?", provider, subject)
+	if err != nil {
+		return entity.Identity{}, err
+	}
+	if len(identities) == 0 {
+		return entity.Identity{}, entity.ErrNotFound
+	}
+	return identities[0], nil
+}
+
+// FetchByUserId lists the identities linked to the user, the oldest first
+func (ir *IdentitiesRepository) FetchByUserId(ctx context.Context, userId int64) ([]entity.Identity, error) {
+	return ir.fetch(ctx, "SELECT "+identityColumns+" FROM identities WHERE user_id=? ORDER BY id", userId)
+}
+
+const identityColumns = "id, provider, subject, user_id, email, created_at"
+
+func (ir *IdentitiesRepository) fetch(ctx context.Context, query string, args ...interface{}) ([]entity.Identity, error) {
+	identities := []entity.Identity{}
+	tx, err := ir.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		ir.errorLog.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		ir.errorLog.Println(err)
+		return nil, err
+	}
+	for rows.Next() {
+		identity := entity.Identity{}
+		var created string
+		if err = rows.Scan(&identity.Id, &identity.Provider, &identity.Subject, &identity.UserId, &identity.Email, &created); err != nil {
+			rows.Close()
+			ir.errorLog.Println(err)
+			return nil, err
+		}
+		identity.CreatedAt, _ = time.Parse(time.Layout, created)
+		identities = append(identities, identity)
+	}
+	rows.Close()
+	if err = tx.Commit(); err != nil {
+		ir.errorLog.Println(err)
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Store links the identity to its user, ErrIdentityLinked means the account or the provider is already linked
+func (ir *IdentitiesRepository) Store(ctx context.Context, identity entity.Identity) (entity.Identity, error) {
+	tx, err := ir.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		ir.errorLog.Println(err)
+		return entity.Identity{}, err
+	}
+	defer tx.Rollback()
+	now := time.Now().Format(time.Layout)
+	res, err := tx.ExecContext(ctx, "INSERT INTO identities(provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?);",
+		identity.Provider, identity.Subject, identity.UserId, identity.Email, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return entity.Identity{}, entity.ErrIdentityLinked
+		}
+		ir.errorLog.Println(err)
+		return entity.Identity{}, err
+	}
+	if identity.Id, err = res.LastInsertId(); err != nil {
+		ir.errorLog.Println(err)
+		return entity.Identity{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		ir.errorLog.Println(err)
+		return entity.Identity{}, err
+	}
+	identity.CreatedAt, _ = time.Parse(time.Layout, now)
+	return identity, nil
+}
+
+// Delete unlinks the identity of the provider from the user, ErrNotFound means there is none
+func (ir *IdentitiesRepository) Delete(ctx context.Context, userId int64, provider string) error {
+	tx, err := ir.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		ir.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, "DELETE FROM identities WHERE user_id=? AND provider=?;", userId, provider)
+	if err != nil {
+		ir.errorLog.Println(err)
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		ir.errorLog.Println(err)
+		return err
+	}
+	if n == 0 {
+		return entity.ErrNotFound
+	}
+	if err = tx.Commit(); err != nil {
+		ir.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum_auth/internal/entity"
+	"log"
+	"time"
+)
+
+type LoginFailuresRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewLoginFailuresRepository(db *sql.DB, errorLog *log.Logger) *LoginFailuresRepository {
+	return &LoginFailuresRepository{db, errorLog}
+}
+
+// Fetch returns the counter of the kind and value, a zero one when nothing failed
+func (lr *LoginFailuresRepository) Fetch(ctx context.Context, kind, value string) (entity.LoginFailure, error) {
+	failure := entity.LoginFailure{Kind: kind, Value: value}
+	var last, locked int64
+	err := lr.db.QueryRowContext(ctx, "SELECT failures, last_failure, locked_until FROM login_failures WHERE kind = ? AND value = ?;", kind, value).
+		Scan(&failure.Failures, &last, &locked)
+	if err == sql.ErrNoRows {
+		return failure, nil
+	} else if err != nil {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	failure.LastFailure, failure.LockedUntil = time.Unix(last, 0), time.Unix(locked, 0)
+	return failure, nil
+}
+
+// Fail counts a failure at now, the count starts over when the last failure is older than window. Counters
+// that are neither recent nor locked are purged on the way
+func (lr *LoginFailuresRepository) Fail(ctx context.Context, kind, value string, now time.Time, window time.Duration) (entity.LoginFailure, error) {
+	tx, err := lr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	defer tx.Rollback()
+	stale := now.Add(-window).Unix()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM login_failures WHERE last_failure < ? AND locked_until < ?;", stale, now.Unix()); err != nil {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	failure := entity.LoginFailure{Kind: kind, Value: value, LastFailure: now}
+	var locked int64
+	err = tx.QueryRowContext(ctx, "SELECT failures, locked_until FROM login_failures WHERE kind = ? AND value = ? AND last_failure >= ?;", kind, value, stale).
+		Scan(&failure.Failures, &locked)
+	if err != nil && err != sql.ErrNoRows {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	failure.Failures++
+	failure.LockedUntil = time.Unix(locked, 0)
+	_, err = tx.ExecContext(ctx, `INSERT INTO login_failures(kind, value, failures, last_failure, locked_until) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(kind, value) DO UPDATE SET failures = excluded.failures, last_failure = excluded.last_failure, locked_until = excluded.locked_until;`,
+		kind, value, failure.Failures, now.Unix(), locked)
+	if err != nil {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		lr.errorLog.Println(err)
+		return entity.LoginFailure{}, err
+	}
+	return failure, nil
+}
+
+// Lock locks the counter of the lockout until its LockedUntil and adds it to the audit log
+func (lr *LoginFailuresRepository) Lock(ctx context.Context, lockout entity.Lockout) error {
+	tx, err := lr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		lr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "UPDATE login_failures SET locked_until = ? WHERE kind = ? AND value = ?;", lockout.LockedUntil.Unix(), lockout.Kind, lockout.Value); err != nil {
+		lr.errorLog.Println(err)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO lockouts(kind, value, failures, ip, locked_until, created_at) VALUES (?, ?, ?, ?, ?, ?);",
+		lockout.Kind, lockout.Value, lockout.Failures, lockout.IP, lockout.LockedUntil.Unix(), lockout.CreatedAt.Unix())
+	if err != nil {
+		lr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		lr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Reset forgets the failures of the kind and value
+func (lr *LoginFailuresRepository) Reset(ctx context.Context, kind, value string) error {
+	if _, err := lr.db.ExecContext(ctx, "DELETE FROM login_failures WHERE kind = ? AND value = ?;", kind, value); err != nil {
+		lr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// FetchLockouts returns the audit log of lockouts, latest first
+func (lr *LoginFailuresRepository) FetchLockouts(ctx context.Context, limit int) ([]entity.Lockout, error) {
+	rows, err := lr.db.QueryContext(ctx, "SELECT id, kind, value, failures, ip, locked_until, created_at FROM lockouts ORDER BY id DESC LIMIT ?;", limit)
+	if err != nil {
+		lr.errorLog.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+	lockouts := []entity.Lockout{}
+	for rows.Next() {
+		var lockout entity.Lockout
+		var locked, created int64
+		if err = rows.Scan(&lockout.Id, &lockout.Kind, &lockout.Value, &lockout.Failures, &lockout.IP, &locked, &created); err != nil {
+			lr.errorLog.Println(err)
+			return nil, err
+		}
+		lockout.LockedUntil, lockout.CreatedAt = time.Unix(locked, 0), time.Unix(created, 0)
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts, rows.Err()
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"forum_auth/internal/entity"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// The count starts over once the last failure is older than the window, unless the counter is locked
+func TestFailWindow(t *testing.T) {
+	ctx := context.Background()
+	lr := NewLoginFailuresRepository(newTestDB(t), log.New(io.Discard, "", 0))
+	start, window := time.Unix(1_000_000, 0), time.Hour
+	tests := []struct {
+		value string
+		at    time.Duration
+		want  int
+	}{
+		{"a@example.com", 0, 1},
+		{"a@example.com", 30 * time.Minute, 2},
+		{"a@example.com", 89 * time.Minute, 3},
+		{"a@example.com", 150 * time.Minute, 1},
+		{"b@example.com", 150 * time.Minute, 1},
+	}
+	for _, tt := range tests {
+		failure, err := lr.Fail(ctx, entity.LoginKindAccount, tt.value, start.Add(tt.at), window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failure.Failures != tt.want {
+			t.Errorf("%s at %v: %d failures, want %d", tt.value, tt.at, failure.Failures, tt.want)
+		}
+	}
+	until := start.Add(10 * time.Hour)
+	if err := lr.Lock(ctx, entity.Lockout{Kind: entity.LoginKindAccount, Value: "b@example.com", LockedUntil: until, CreatedAt: start}); err != nil {
+		t.Fatal(err)
+	}
+	// a stale counter is purged by the failures of others, a locked one is kept
+	if _, err := lr.Fail(ctx, entity.LoginKindIP, "192.0.2.1", start.Add(5*time.Hour), window); err != nil {
+		t.Fatal(err)
+	}
+	if failure, err := lr.Fetch(ctx, entity.LoginKindAccount, "a@example.com"); err != nil || failure.Failures != 0 {
+		t.Errorf("the stale counter = %+v, %v", failure, err)
+	}
+	if failure, err := lr.Fetch(ctx, entity.LoginKindAccount, "b@example.com"); err != nil || failure.Failures != 1 || !failure.LockedUntil.Equal(until) {
+		t.Errorf("the locked counter = %+v, %v", failure, err)
+	}
+}
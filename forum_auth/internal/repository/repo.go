@@ -5,11 +5,10 @@ import (
 	"database/sql"
 	"forum_auth/internal/entity"
 	"log"
+	"sort"
 	"time"
 )
 
-const sessionExpiry = 10 * time.Minute
-
 type SessionsRepository struct {
 	db       *sql.DB
 	errorLog *log.Logger
@@ -27,7 +26,7 @@ func (sr *SessionsRepository) Fetch(ctx context.Context, token string) (entity.S
 		return entity.Session{}, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM sessions WHERE token=?")
+	stmt, err := tx.PrepareContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE token=?")
 	if err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
@@ -39,16 +38,10 @@ func (sr *SessionsRepository) Fetch(ctx context.Context, token string) (entity.S
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
 	}
-	temp := ""
 	if rows.Next() {
-		rows.Scan(&session.UserId, &session.Token, &temp)
-	}
-	if session.UserId != 0 {
-		session.ExpiryTime, err = time.Parse(time.Layout, temp)
-		if err != nil {
-			sr.errorLog.Println(err)
-		}
+		session = sr.scanSession(rows)
 	}
+	rows.Close()
 	if err = tx.Commit(); err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
@@ -56,39 +49,56 @@ func (sr *SessionsRepository) Fetch(ctx context.Context, token string) (entity.S
 	return session, nil
 }
 
-func (sr *SessionsRepository) FetchByUserId(ctx context.Context, id int64) (entity.Session, error) {
-	session := entity.Session{}
+// FetchByUserId lists the sessions of the user, the most recently used first
+func (sr *SessionsRepository) FetchByUserId(ctx context.Context, id int64) ([]entity.Session, error) {
+	sessions := []entity.Session{}
 	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		sr.errorLog.Println(err)
-		return entity.Session{}, err
+		return nil, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM sessions WHERE user_id=?")
+	stmt, err := tx.PrepareContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE user_id=?")
 	if err != nil {
 		sr.errorLog.Println(err)
-		return entity.Session{}, err
+		return nil, err
 	}
 	defer stmt.Close()
 
 	rows, err := stmt.QueryContext(ctx, id)
 	if err != nil {
 		sr.errorLog.Println(err)
-		return entity.Session{}, err
+		return nil, err
 	}
-	temp := ""
-	if rows.Next() {
-		rows.Scan(&session.UserId, &session.Token, &session.ExpiryTime)
+	for rows.Next() {
+		sessions = append(sessions, sr.scanSession(rows))
 	}
-	session.ExpiryTime, err = time.Parse(time.Layout, temp)
-	if err != nil {
+	rows.Close()
+	if err = tx.Commit(); err != nil {
 		sr.errorLog.Println(err)
+		return nil, err
 	}
-	if err = tx.Commit(); err != nil {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+const sessionColumns = "id, user_id, token, expiry_date, absolute_expiry, persistent, role, user_agent, ip, created_at, last_seen"
+
+// scanSession reads a row of sessionColumns, times stored before they were recorded stay zero
+func (sr *SessionsRepository) scanSession(rows *sql.Rows) entity.Session {
+	session := entity.Session{}
+	var expiry, absolute, created, lastSeen string
+	rows.Scan(&session.Id, &session.UserId, &session.Token, &expiry, &absolute, &session.Persistent, &session.Role, &session.UserAgent, &session.IP, &created, &lastSeen)
+	var err error
+	if session.ExpiryTime, err = time.Parse(time.Layout, expiry); err != nil {
 		sr.errorLog.Println(err)
-		return entity.Session{}, err
 	}
-	return session, nil
+	session.AbsoluteExpiry, _ = time.Parse(time.Layout, absolute)
+	session.CreatedAt, _ = time.Parse(time.Layout, created)
+	session.LastSeen, _ = time.Parse(time.Layout, lastSeen)
+	return session
 }
 
 func (sr *SessionsRepository) Store(ctx context.Context, session entity.Session) (entity.Session, error) {
@@ -98,19 +108,30 @@ func (sr *SessionsRepository) Store(ctx context.Context, session entity.Session)
 		return entity.Session{}, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO sessions(user_id, token, expiry_date) VALUES (?, ?, ?);")
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO sessions(user_id, token, expiry_date, absolute_expiry, expires_at, persistent, role, user_agent, ip, created_at, last_seen) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);")
 	if err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
 	}
 	defer stmt.Close()
 
-	expiryTime := time.Now().Add(sessionExpiry).Format(time.Layout)
-	_, err = stmt.ExecContext(ctx, session.UserId, session.Token, expiryTime)
+	now := time.Now()
+	expiryTime, absoluteExpiry := session.ExpiryTime.Format(time.Layout), session.AbsoluteExpiry.Format(time.Layout)
+	if session.Role == "" {
+		session.Role = entity.RoleUser
+	}
+	res, err := stmt.ExecContext(ctx, session.UserId, session.Token, expiryTime, absoluteExpiry, session.EndsAt().Unix(), session.Persistent, session.Role, session.UserAgent, session.IP, now.Format(time.Layout), now.Format(time.Layout))
 	if err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
 	}
+	if session.Id, err = res.LastInsertId(); err != nil {
+		sr.errorLog.Println(err)
+		return entity.Session{}, err
+	}
+	session.CreatedAt, _ = time.Parse(time.Layout, now.Format(time.Layout))
+	session.LastSeen = session.CreatedAt
+	session.AbsoluteExpiry, _ = time.Parse(time.Layout, absoluteExpiry)
 	session.ExpiryTime, err = time.Parse(time.Layout, expiryTime)
 	if err != nil {
 		sr.errorLog.Println(err)
@@ -123,6 +144,7 @@ func (sr *SessionsRepository) Store(ctx context.Context, session entity.Session)
 	return session, err
 }
 
+// Update records the activity of the session and moves its idle expiry to session.ExpiryTime
 func (sr *SessionsRepository) Update(ctx context.Context, session entity.Session) (entity.Session, error) {
 	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
@@ -130,15 +152,16 @@ func (sr *SessionsRepository) Update(ctx context.Context, session entity.Session
 		return entity.Session{}, err
 	}
 	defer tx.Rollback()
-	stmt, err := tx.PrepareContext(ctx, "UPDATE sessions SET expiry_date=? WHERE token =?;")
+	stmt, err := tx.PrepareContext(ctx, "UPDATE sessions SET expiry_date=?, expires_at=?, last_seen=? WHERE token =?;")
 	if err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
 	}
 	defer stmt.Close()
 
-	expiryTime := time.Now().Add(sessionExpiry).Format(time.Layout)
-	_, err = stmt.ExecContext(ctx, expiryTime, session.Token)
+	now := time.Now()
+	expiryTime := session.ExpiryTime.Format(time.Layout)
+	_, err = stmt.ExecContext(ctx, expiryTime, session.EndsAt().Unix(), now.Format(time.Layout), session.Token)
 	if err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
@@ -148,6 +171,7 @@ func (sr *SessionsRepository) Update(ctx context.Context, session entity.Session
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
 	}
+	session.LastSeen, _ = time.Parse(time.Layout, now.Format(time.Layout))
 	if err = tx.Commit(); err != nil {
 		sr.errorLog.Println(err)
 		return entity.Session{}, err
@@ -179,3 +203,74 @@ func (sr *SessionsRepository) Delete(ctx context.Context, session entity.Session
 	}
 	return nil
 }
+
+// DeleteByUserId signs the user out everywhere
+func (sr *SessionsRepository) DeleteByUserId(ctx context.Context, userId int64) error {
+	_, err := sr.delete(ctx, "DELETE FROM sessions WHERE user_id=?;", userId)
+	return err
+}
+
+// DeleteById signs out one session of the user, ErrNotFound means the user has no such session
+func (sr *SessionsRepository) DeleteById(ctx context.Context, userId, id int64) error {
+	n, err := sr.delete(ctx, "DELETE FROM sessions WHERE user_id=? AND id=?;", userId, id)
+	if err == nil && n == 0 {
+		return entity.ErrNotFound
+	}
+	return err
+}
+
+// DeleteOthers signs the user out everywhere except the session with the token
+func (sr *SessionsRepository) DeleteOthers(ctx context.Context, userId int64, token string) error {
+	_, err := sr.delete(ctx, "DELETE FROM sessions WHERE user_id=? AND token<>?;", userId, token)
+	return err
+}
+
+func (sr *SessionsRepository) delete(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	defer stmt.Close()
+	res, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	return n, nil
+}
+
+// DeleteExpired removes the sessions that are expired at now and returns how many were removed
+func (sr *SessionsRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := sr.db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at <= ?;", now.Unix())
+	if err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CountActive returns how many sessions are not expired at now
+func (sr *SessionsRepository) CountActive(ctx context.Context, now time.Time) (int64, error) {
+	var active int64
+	if err := sr.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions WHERE expires_at > ?;", now.Unix()).Scan(&active); err != nil {
+		sr.errorLog.Println(err)
+		return 0, err
+	}
+	return active, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"forum_auth/internal/entity"
+	"log"
+	"time"
+)
+
+type TokensRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewTokensRepository(db *sql.DB, errorLog *log.Logger) *TokensRepository {
+	return &TokensRepository{db, errorLog}
+}
+
+// Store replaces the tokens the user already has for the same purpose, only the latest link works
+func (tr *TokensRepository) Store(ctx context.Context, token entity.OneTimeToken) error {
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM tokens WHERE user_id = ? AND purpose = ?;", token.UserId, token.Purpose); err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO tokens(token_hash, user_id, email, purpose, expiry_date) VALUES (?, ?, ?, ?, ?);")
+	if err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.ExecContext(ctx, hashToken(token.Token), token.UserId, token.Email, token.Purpose, token.ExpiryTime.Format(time.Layout))
+	if err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Consume deletes the token and returns it, unknown, expired and already used tokens are ErrInvalidToken
+func (tr *TokensRepository) Consume(ctx context.Context, value, purpose string) (entity.OneTimeToken, error) {
+	token := entity.OneTimeToken{Token: value, Purpose: purpose}
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tr.errorLog.Println(err)
+		return entity.OneTimeToken{}, err
+	}
+	defer tx.Rollback()
+	hash := hashToken(value)
+	var expiry string
+	err = tx.QueryRowContext(ctx, "SELECT user_id, email, expiry_date FROM tokens WHERE token_hash = ? AND purpose = ?;", hash, purpose).Scan(&token.UserId, &token.Email, &expiry)
+	if err == sql.ErrNoRows {
+		return entity.OneTimeToken{}, entity.ErrInvalidToken
+	} else if err != nil {
+		tr.errorLog.Println(err)
+		return entity.OneTimeToken{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM tokens WHERE token_hash = ?;", hash); err != nil {
+		tr.errorLog.Println(err)
+		return entity.OneTimeToken{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		tr.errorLog.Println(err)
+		return entity.OneTimeToken{}, err
+	}
+	if token.ExpiryTime, err = time.Parse(time.Layout, expiry); err != nil || time.Now().After(token.ExpiryTime) {
+		return entity.OneTimeToken{}, entity.ErrInvalidToken
+	}
+	return token, nil
+}
+
+// Restore puts back a consumed token whose use failed, so that the link can be followed again.
+// Unlike Store it keeps the other tokens of the user, a link sent in the meantime stays valid
+func (tr *TokensRepository) Restore(ctx context.Context, token entity.OneTimeToken) error {
+	_, err := tr.db.ExecContext(ctx, "INSERT OR IGNORE INTO tokens(token_hash, user_id, email, purpose, expiry_date) VALUES (?, ?, ?, ?, ?);",
+		hashToken(token.Token), token.UserId, token.Email, token.Purpose, token.ExpiryTime.Format(time.Layout))
+	if err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
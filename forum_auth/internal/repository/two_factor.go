@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum_auth/internal/entity"
+	"log"
+	"time"
+)
+
+// errNoRows is what affected returns when a statement changed nothing, every method turns it into its own error
+var errNoRows = errors.New("no rows affected")
+
+type TwoFactorRepository struct {
+	db       *sql.DB
+	errorLog *log.Logger
+}
+
+func NewTwoFactorRepository(db *sql.DB, errorLog *log.Logger) *TwoFactorRepository {
+	return &TwoFactorRepository{db, errorLog}
+}
+
+// Fetch returns the enrollment of the user with the number of recovery codes left, ErrNotFound means there is none
+func (tr *TwoFactorRepository) Fetch(ctx context.Context, userId int64) (entity.TwoFactor, error) {
+	tf := entity.TwoFactor{UserId: userId}
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tr.errorLog.Println(err)
+		return entity.TwoFactor{}, err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRowContext(ctx, "SELECT secret, enabled, last_step FROM two_factor WHERE user_id = ?;", userId).Scan(&tf.Secret, &tf.Enabled, &tf.LastStep)
+	if err == sql.ErrNoRows {
+		return entity.TwoFactor{}, entity.ErrNotFound
+	} else if err != nil {
+		tr.errorLog.Println(err)
+		return entity.TwoFactor{}, err
+	}
+	if err = tx.QueryRowContext(ctx, "SELECT count(*) FROM recovery_codes WHERE user_id = ?;", userId).Scan(&tf.RecoveryCodes); err != nil {
+		tr.errorLog.Println(err)
+		return entity.TwoFactor{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		tr.errorLog.Println(err)
+		return entity.TwoFactor{}, err
+	}
+	return tf, nil
+}
+
+// StoreSecret starts an enrollment, or restarts one that hasn't been enabled
+func (tr *TwoFactorRepository) StoreSecret(ctx context.Context, userId int64, secret string) error {
+	return tr.exec(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO two_factor(user_id, secret, created_at) VALUES (?, ?, ?)
+			ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at WHERE enabled = 0;`,
+			userId, secret, time.Now().Format(time.Layout))
+		return err
+	})
+}
+
+// Enable turns the enrollment on with the step of the code that confirmed it and replaces the recovery codes
+func (tr *TwoFactorRepository) Enable(ctx context.Context, userId, step int64, codes []string) error {
+	return tr.exec(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE two_factor SET enabled = 1, last_step = ? WHERE user_id = ?;", step, userId); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?;", userId); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes(code_hash, user_id) VALUES (?, ?);", hashToken(code), userId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseStep records that the code of the step was accepted, ErrInvalidCode means a code of it or a later step already was
+func (tr *TwoFactorRepository) UseStep(ctx context.Context, userId, step int64) error {
+	return noRows(tr.exec(ctx, func(tx *sql.Tx) error {
+		return affected(tx.ExecContext(ctx, "UPDATE two_factor SET last_step = ? WHERE user_id = ? AND last_step < ?;", step, userId, step))
+	}), entity.ErrInvalidCode)
+}
+
+// UseRecoveryCode deletes the recovery code, ErrInvalidCode means the user doesn't have it
+func (tr *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userId int64, code string) error {
+	return noRows(tr.exec(ctx, func(tx *sql.Tx) error {
+		return affected(tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?;", userId, hashToken(code)))
+	}), entity.ErrInvalidCode)
+}
+
+// Delete removes the enrollment and the recovery codes of the user, ErrNotFound means there was none
+func (tr *TwoFactorRepository) Delete(ctx context.Context, userId int64) error {
+	err := tr.exec(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?;", userId); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pending_sign_ins WHERE user_id = ?;", userId); err != nil {
+			return err
+		}
+		return affected(tx.ExecContext(ctx, "DELETE FROM two_factor WHERE user_id = ?;", userId))
+	})
+	return noRows(err, entity.ErrNotFound)
+}
+
+// StorePending replaces the pending sign ins of the user, only the latest one can be finished. The wrong codes
+// of the replaced ones carry over, or signing in with the password again would buy more guesses
+func (tr *TwoFactorRepository) StorePending(ctx context.Context, pending entity.PendingSignIn) error {
+	return tr.exec(ctx, func(tx *sql.Tx) error {
+		var attempts int
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(attempts), 0) FROM pending_sign_ins WHERE user_id = ?;", pending.UserId).Scan(&attempts)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM pending_sign_ins WHERE user_id = ?;", pending.UserId); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO pending_sign_ins(token_hash, user_id, persistent, attempts, expiry_date) VALUES (?, ?, ?, ?, ?);",
+			hashToken(pending.Token), pending.UserId, pending.Persistent, attempts, pending.ExpiryTime.Format(time.Layout))
+		return err
+	})
+}
+
+// FetchPending finds the pending sign in of the token, unknown and expired ones are ErrInvalidToken
+func (tr *TwoFactorRepository) FetchPending(ctx context.Context, token string) (entity.PendingSignIn, error) {
+	pending := entity.PendingSignIn{Token: token}
+	var expiry string
+	err := tr.db.QueryRowContext(ctx, "SELECT user_id, persistent, attempts, expiry_date FROM pending_sign_ins WHERE token_hash = ?;", hashToken(token)).
+		Scan(&pending.UserId, &pending.Persistent, &pending.Attempts, &expiry)
+	if err == sql.ErrNoRows {
+		return entity.PendingSignIn{}, entity.ErrInvalidToken
+	} else if err != nil {
+		tr.errorLog.Println(err)
+		return entity.PendingSignIn{}, err
+	}
+	if pending.ExpiryTime, err = time.Parse(time.Layout, expiry); err != nil || time.Now().After(pending.ExpiryTime) {
+		return entity.PendingSignIn{}, entity.ErrInvalidToken
+	}
+	return pending, nil
+}
+
+// FailPending counts a wrong code, the pending sign in is dropped after MaxTwoFactorAttempts of them
+func (tr *TwoFactorRepository) FailPending(ctx context.Context, token string) error {
+	return tr.exec(ctx, func(tx *sql.Tx) error {
+		hash := hashToken(token)
+		if _, err := tx.ExecContext(ctx, "UPDATE pending_sign_ins SET attempts = attempts + 1 WHERE token_hash = ?;", hash); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM pending_sign_ins WHERE token_hash = ? AND attempts >= ?;", hash, entity.MaxTwoFactorAttempts)
+		return err
+	})
+}
+
+// DeletePending finishes the pending sign in, ErrInvalidToken means it was already finished
+func (tr *TwoFactorRepository) DeletePending(ctx context.Context, token string) error {
+	err := tr.exec(ctx, func(tx *sql.Tx) error {
+		return affected(tx.ExecContext(ctx, "DELETE FROM pending_sign_ins WHERE token_hash = ?;", hashToken(token)))
+	})
+	return noRows(err, entity.ErrInvalidToken)
+}
+
+// exec runs apply in a transaction
+func (tr *TwoFactorRepository) exec(ctx context.Context, apply func(tx *sql.Tx) error) error {
+	tx, err := tr.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+	if err = apply(tx); err != nil {
+		if err != errNoRows {
+			tr.errorLog.Println(err)
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		tr.errorLog.Println(err)
+		return err
+	}
+	return nil
+}
+
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errNoRows
+	}
+	return nil
+}
+
+func noRows(err, replacement error) error {
+	if err == errNoRows {
+		return replacement
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"forum_auth/internal/entity"
+	"forum_auth/pkg/sqlite3"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite3.New(filepath.Join(t.TempDir(), "session.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestTwoFactor(t *testing.T) *TwoFactorRepository {
+	return NewTwoFactorRepository(newTestDB(t), log.New(io.Discard, "", 0))
+}
+
+// A code of the app is accepted once, the step it belongs to and the ones before it can't be used again
+func TestUseStep(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestTwoFactor(t)
+	if err := tr.StoreSecret(ctx, 1, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Enable(ctx, 1, 100, []string{"code1"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		step int64
+		want error
+	}{
+		{"step of enabling", 100, entity.ErrInvalidCode},
+		{"next step", 101, nil},
+		{"same step again", 101, entity.ErrInvalidCode},
+		{"step before", 100, entity.ErrInvalidCode},
+		{"later step", 103, nil},
+	}
+	for _, tt := range tests {
+		if err := tr.UseStep(ctx, 1, tt.step); err != tt.want {
+			t.Errorf("%s: UseStep(%d) = %v, want %v", tt.name, tt.step, err, tt.want)
+		}
+	}
+	if err := tr.UseStep(ctx, 2, 200); err != entity.ErrInvalidCode {
+		t.Errorf("UseStep of a user without two-factor = %v", err)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestTwoFactor(t)
+	if err := tr.StoreSecret(ctx, 1, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Enable(ctx, 1, 100, []string{"code1", "code2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.UseRecoveryCode(ctx, 1, "code1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.UseRecoveryCode(ctx, 1, "code1"); err != entity.ErrInvalidCode {
+		t.Errorf("second use of a recovery code = %v", err)
+	}
+	tf, err := tr.Fetch(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tf.RecoveryCodes != 1 {
+		t.Errorf("recovery codes left = %d, want 1", tf.RecoveryCodes)
+	}
+}
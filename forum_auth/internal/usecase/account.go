@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mailTimeout bounds the storing and sending of a reset link, which outlives the request
+const mailTimeout = 30 * time.Second
+
+// ForgotPassword mails a reset link when the email belongs to a user, unknown emails are
+// not reported so the endpoint can't be used to find out who is registered. The reply is sent
+// once the user is looked up, before the link is stored and mailed, so that known emails don't
+// take longer to answer than unknown ones
+func (au *AuthUsecase) ForgotPassword(ctx context.Context, credentials entity.Credentials, err chan error) {
+	user, e := au.fetchUser(ctx, fmt.Sprintf("/user/email?email=%s", url.QueryEscape(credentials.Email)))
+	if e == entity.ErrNotFound {
+		err <- nil
+		return
+	} else if e != nil {
+		err <- e
+		return
+	}
+	err <- nil
+	ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+	defer cancel()
+	link := "%s/reset-password?token=%s"
+	body := "Someone asked to reset the password of your forum account. Follow the link to choose a new one:\n%s\n\nThe link works once and expires in an hour. If it wasn't you, ignore this mail."
+	if e = au.sendToken(ctx, user, entity.PurposePasswordReset, "Password reset", link, body); e != nil {
+		au.errLog.Println(e)
+	}
+}
+
+// ResetPassword sets the new password and ends every session of the user
+func (au *AuthUsecase) ResetPassword(ctx context.Context, reset entity.PasswordReset, err chan error) {
+	token, e := au.tokensRepo.Consume(ctx, reset.Token, entity.PurposePasswordReset)
+	if e != nil {
+		err <- e
+		return
+	}
+	hashedPassword, e := bcrypt.GenerateFromPassword([]byte(reset.Password), bcrypt.DefaultCost)
+	if e != nil {
+		err <- e
+		return
+	}
+	if e = au.putUser(ctx, "/user/password", entity.Credentials{Id: token.UserId, Password: string(hashedPassword)}); e != nil {
+		au.restoreToken(token)
+		err <- e
+		return
+	}
+	// the password is changed already, sessions left over are logged rather than failing the reset
+	if e = au.sessionRepo.DeleteByUserId(ctx, token.UserId); e != nil {
+		au.errLog.Println(e)
+	}
+	err <- nil
+}
+
+// SendVerification mails an email confirmation link to the user
+func (au *AuthUsecase) SendVerification(ctx context.Context, req entity.VerificationRequest, err chan error) {
+	user, e := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", req.UserId))
+	if e != nil {
+		err <- e
+		return
+	}
+	err <- au.sendVerification(ctx, user)
+}
+
+func (au *AuthUsecase) sendVerification(ctx context.Context, user entity.Credentials) error {
+	if user.Verified {
+		return nil
+	}
+	link := "%s/verify-email?token=%s"
+	body := "Confirm the email of your forum account by following the link:\n%s\n\nThe link expires in a day."
+	return au.sendToken(ctx, user, entity.PurposeEmailVerification, "Confirm your email", link, body)
+}
+
+func (au *AuthUsecase) VerifyEmail(ctx context.Context, req entity.VerificationRequest, err chan error) {
+	token, e := au.tokensRepo.Consume(ctx, req.Token, entity.PurposeEmailVerification)
+	if e != nil {
+		err <- e
+		return
+	}
+	e = au.putUser(ctx, "/user/verify", entity.Credentials{Id: token.UserId, Email: token.Email})
+	if e == entity.ErrNotFound { // the email changed after the link was sent
+		e = entity.ErrInvalidToken
+	} else if e != nil {
+		au.restoreToken(token)
+	}
+	err <- e
+}
+
+// restoreToken gives back a token that was consumed but not used because of a failure,
+// the link keeps working for a retry instead of being lost with the error. The failure may
+// be the deadline of the request, so the token is put back outside of it
+func (au *AuthUsecase) restoreToken(token entity.OneTimeToken) {
+	if err := au.tokensRepo.Restore(context.Background(), token); err != nil {
+		au.errLog.Println(err)
+	}
+}
+
+// sendToken issues a token for the purpose and mails the link built from the link and body formats
+func (au *AuthUsecase) sendToken(ctx context.Context, user entity.Credentials, purpose, subject, link, body string) error {
+	value, err := newToken()
+	if err != nil {
+		return err
+	}
+	token := entity.OneTimeToken{
+		Token:      value,
+		UserId:     user.Id,
+		Email:      user.Email,
+		Purpose:    purpose,
+		ExpiryTime: time.Now().Add(entity.TokenLifetime(purpose)),
+	}
+	if err = au.tokensRepo.Store(ctx, token); err != nil {
+		return err
+	}
+	mail := entity.Mail{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, fmt.Sprintf(link, au.gatewayURL, url.QueryEscape(value))),
+	}
+	return au.mailer.Send(ctx, mail)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (au *AuthUsecase) fetchUser(ctx context.Context, path string) (entity.Credentials, error) {
+	response, err := au.app.Do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return entity.Credentials{}, err
+	}
+	switch response.StatusCode {
+	case 200:
+	case 404:
+		return entity.Credentials{}, entity.ErrNotFound
+	case 408:
+		return entity.Credentials{}, entity.ErrRequestTimeout
+	default:
+		return entity.Credentials{}, entity.ErrInternalServer
+	}
+	user, err := getUser(response.Body)
+	if err == nil && user.Id == 0 {
+		return entity.Credentials{}, entity.ErrNotFound
+	}
+	return user, err
+}
+
+func (au *AuthUsecase) putUser(ctx context.Context, path string, user entity.Credentials) error {
+	requestBody, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	response, err := au.app.Do(ctx, http.MethodPut, path, requestBody)
+	if err != nil {
+		return err
+	}
+	switch response.StatusCode {
+	case 204:
+		return nil
+	case 404:
+		return entity.ErrNotFound
+	case 408:
+		return entity.ErrRequestTimeout
+	default:
+		return entity.ErrInternalServer
+	}
+}
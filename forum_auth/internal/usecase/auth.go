@@ -1,15 +1,16 @@
 package usecase
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"forum_auth/internal/client"
 	"forum_auth/internal/entity"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -17,20 +18,36 @@ import (
 )
 
 type AuthUsecase struct {
-	sessionRepo SessionsRepo
-	errLog      *log.Logger
+	sessionRepo    SessionsRepo
+	tokensRepo     TokensRepo
+	identitiesRepo IdentitiesRepo
+	twoFactorRepo  TwoFactorRepo
+	guard          *LoginGuard
+	mailer         MailSender
+	app            *client.App
+	gatewayURL     string
+	lifetime       entity.SessionLifetime
+	errLog         *log.Logger
 }
 
-func NewAuthUsecase(sessionRepo SessionsRepo, errLog *log.Logger) *AuthUsecase {
-	return &AuthUsecase{sessionRepo: sessionRepo, errLog: errLog}
+func NewAuthUsecase(sessionRepo SessionsRepo, tokensRepo TokensRepo, identitiesRepo IdentitiesRepo, twoFactorRepo TwoFactorRepo, guard *LoginGuard, mailer MailSender, app *client.App, gatewayURL string, lifetime entity.SessionLifetime, errLog *log.Logger) *AuthUsecase {
+	return &AuthUsecase{sessionRepo: sessionRepo, tokensRepo: tokensRepo, identitiesRepo: identitiesRepo, twoFactorRepo: twoFactorRepo, guard: guard, mailer: mailer, app: app, gatewayURL: gatewayURL, lifetime: lifetime, errLog: errLog}
 }
 
+// dummyHash is compared against when nobody has the email, so that unknown emails take as long as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("no such user"), bcrypt.DefaultCost)
+
 func (au *AuthUsecase) SignIn(ctx context.Context, credentials entity.Credentials, sessionRes chan entity.SessionResult) {
-	response, err := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/user/email?email=%s", credentials.Email), nil)
+	if lockedUntil, err := au.guard.Check(ctx, credentials.Email, credentials.IP); err != nil {
+		sessionRes <- entity.SessionResult{LockedUntil: lockedUntil, Err: err}
+		return
+	}
+	response, err := au.app.Do(ctx, http.MethodGet, fmt.Sprintf("/user/email?email=%s", url.QueryEscape(credentials.Email)), nil)
 	if err != nil {
 		sessionRes <- entity.SessionResult{Err: err}
 		return
 	}
+	var user entity.Credentials
 	switch response.StatusCode {
 	case 405, 400, 500:
 		sessionRes <- entity.SessionResult{Err: entity.ErrInternalServer}
@@ -39,29 +56,35 @@ func (au *AuthUsecase) SignIn(ctx context.Context, credentials entity.Credential
 		sessionRes <- entity.SessionResult{Err: entity.ErrRequestTimeout}
 		return
 	case 404:
-		sessionRes <- entity.SessionResult{Err: entity.ErrNotFound}
-		return
-	}
-	user, err := getUser(response.Body)
-	if err != nil {
-		sessionRes <- entity.SessionResult{Err: entity.ErrInternalServer}
-		return
+	default:
+		if user, err = getUser(response.Body); err != nil {
+			sessionRes <- entity.SessionResult{Err: entity.ErrInternalServer}
+			return
+		}
 	}
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credentials.Password)); err != nil {
-		sessionRes <- entity.SessionResult{Err: entity.ErrInvalidPassword}
-		return
+	hash := []byte(user.Password)
+	if user.Password == "" {
+		hash = dummyHash
 	}
-	token, err := uuid.NewV4()
-	if err != nil {
-		sessionRes <- entity.SessionResult{Err: entity.ErrInternalServer}
+	if err = bcrypt.CompareHashAndPassword(hash, []byte(credentials.Password)); err != nil || user.Password == "" {
+		lockedUntil, err := au.guard.Fail(ctx, credentials.Email, credentials.IP)
+		if err != nil {
+			sessionRes <- entity.SessionResult{Err: err}
+			return
+		}
+		if !lockedUntil.IsZero() {
+			sessionRes <- entity.SessionResult{LockedUntil: lockedUntil, Err: entity.ErrLockedOut}
+			return
+		}
+		sessionRes <- entity.SessionResult{Err: entity.ErrInvalidCredentials}
 		return
 	}
-	session := entity.Session{UserId: user.Id, Token: token.String()}
-	if session, err = au.sessionRepo.Store(ctx, session); err != nil {
-		sessionRes <- entity.SessionResult{Err: err}
-		return
+	user.UserAgent, user.IP, user.RememberMe = credentials.UserAgent, credentials.IP, credentials.RememberMe
+	res := au.startSession(ctx, user)
+	if res.Err == nil && res.Pending.Token == "" { // with two-factor authentication the failures are forgotten after the code
+		au.guard.Succeed(ctx, credentials.Email)
 	}
-	sessionRes <- entity.SessionResult{Session: session}
+	sessionRes <- res
 }
 
 func (au *AuthUsecase) SignUp(ctx context.Context, credentials entity.Credentials, credsRes chan entity.CredentialsResult) {
@@ -71,12 +94,13 @@ func (au *AuthUsecase) SignUp(ctx context.Context, credentials entity.Credential
 		return
 	}
 	credentials.Password = string(hashedPassword)
+	credentials.Verified = false
 	requestBody, err := json.Marshal(credentials)
 	if err != nil {
 		credsRes <- entity.CredentialsResult{Err: err}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/user/save", requestBody)
+	response, err := au.app.Do(ctx, http.MethodPost, "/user/save", requestBody)
 	if err != nil {
 		credsRes <- entity.CredentialsResult{Err: err}
 		return
@@ -106,6 +130,11 @@ func (au *AuthUsecase) SignUp(ctx context.Context, credentials entity.Credential
 		credsRes <- entity.CredentialsResult{Err: err}
 		return
 	}
+	// the account exists by now, a lost confirmation mail can be sent again
+	user.Name, user.Email = credentials.Name, credentials.Email
+	if err = au.sendVerification(ctx, user); err != nil {
+		au.errLog.Println(err)
+	}
 	credsRes <- entity.CredentialsResult{Credentials: user}
 }
 
@@ -119,16 +148,19 @@ func (au *AuthUsecase) Authenticate(ctx context.Context, session entity.Session,
 		authStatus <- entity.AuthStatusResult{Status: entity.NonAuthorised, Err: errors.New("session doesn't exist")}
 		return
 	}
-	if time.Now().After(session.ExpiryTime) {
+	now := time.Now()
+	if session.Expired(now) {
 		if err = au.sessionRepo.Delete(ctx, session); err != nil {
 			au.errLog.Println(err)
 		}
 		authStatus <- entity.AuthStatusResult{Status: entity.NonAuthorised, Err: errors.New("session expired")}
 		return
 	}
+	session.ExpiryTime = au.idleExpiry(session, now)
 	session, err = au.sessionRepo.Update(ctx, session)
 	if err != nil {
 		authStatus <- entity.AuthStatusResult{Status: entity.NonAuthorised, Err: err}
+		return
 	}
 	authStatus <- entity.AuthStatusResult{Status: entity.Authorised, Session: session}
 }
@@ -137,43 +169,68 @@ func (au *AuthUsecase) SignOut(ctx context.Context, session entity.Session, err
 	err <- au.sessionRepo.Delete(ctx, session)
 }
 
+// OauthSignIn signs in the user the OAuth account is linked to, linking or creating one on first sign in
 func (au *AuthUsecase) OauthSignIn(ctx context.Context, credentials entity.Credentials, sessionRes chan entity.SessionResult) {
-	response, err := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/user/email?email=%s", credentials.Email), nil)
+	user, err := au.identityUser(ctx, credentials)
 	if err != nil {
 		sessionRes <- entity.SessionResult{Err: err}
 		return
 	}
-	user, err := getUser(response.Body)
-	if err != nil {
-		sessionRes <- entity.SessionResult{Err: err}
-		return
+	user.UserAgent, user.IP = credentials.UserAgent, credentials.IP
+	sessionRes <- au.startSession(ctx, user)
+}
+
+// identityUser finds the user of an OAuth account. An account that isn't linked yet is only linked to the user
+// with the same email when both the provider and the forum have verified it, otherwise anyone could take over an
+// account by registering its email at some provider, the owner has to connect it from their profile instead.
+// A user without a password, who could only ever sign in by OAuth, is linked on a verified email too.
+// A user is created when nobody has the email.
+func (au *AuthUsecase) identityUser(ctx context.Context, credentials entity.Credentials) (entity.Credentials, error) {
+	identity, err := au.identitiesRepo.Fetch(ctx, credentials.Provider, credentials.Subject)
+	if err == nil {
+		return au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", identity.UserId))
+	} else if err != entity.ErrNotFound {
+		return entity.Credentials{}, err
 	}
-	if user.Id == 0 { // if user doesn't exist, it should be stored
-		res := storeUser(ctx, credentials)
+	if credentials.Email == "" { // nothing to find or create the user by
+		return entity.Credentials{}, entity.ErrNotFound
+	}
+	user, err := au.fetchUser(ctx, fmt.Sprintf("/user/email?email=%s", url.QueryEscape(credentials.Email)))
+	switch {
+	case err == entity.ErrNotFound:
+		res := au.storeUser(ctx, entity.Credentials{Name: credentials.Name, Email: credentials.Email, Verified: credentials.Verified})
+		if res.Err == nil && res.Credentials.Id == 0 {
+			res.Err = entity.ErrInternalServer
+		}
 		if res.Err != nil {
-			sessionRes <- entity.SessionResult{Err: res.Err}
-			return
+			return entity.Credentials{}, res.Err
 		}
 		user = res.Credentials
+	case err != nil:
+		return entity.Credentials{}, err
+	case credentials.Verified && !user.Verified && user.Password == "":
+		// accounts created by OAuth sign in before identities were recorded have no password and were never
+		// verified, the verified email of the provider is the only way they have to sign in
+		if err = au.putUser(ctx, "/user/verify", entity.Credentials{Id: user.Id, Email: user.Email}); err != nil {
+			return entity.Credentials{}, err
+		}
+		user.Verified = true
+	case !credentials.Verified || !user.Verified:
+		return entity.Credentials{}, entity.ErrIdentityConflict
 	}
-	sessionRes <- au.createSession(ctx, user)
-}
-
-func getAPIResponse(ctx context.Context, method string, url string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
+	identity = entity.Identity{Provider: credentials.Provider, Subject: credentials.Subject, UserId: user.Id, Email: credentials.Email}
+	if _, err = au.identitiesRepo.Store(ctx, identity); err != nil {
+		return entity.Credentials{}, err
 	}
-	client := http.Client{}
-	return client.Do(req)
+	return user, nil
 }
 
-func storeUser(ctx context.Context, credentials entity.Credentials) entity.CredentialsResult {
+func (au *AuthUsecase) storeUser(ctx context.Context, credentials entity.Credentials) entity.CredentialsResult {
 	requestBody, err := json.Marshal(credentials)
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/user/save", requestBody)
+	response, err := au.app.Do(ctx, http.MethodPost, "/user/save", requestBody)
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
@@ -210,9 +267,29 @@ func (au *AuthUsecase) createSession(ctx context.Context, credentials entity.Cre
 	if err != nil {
 		return entity.SessionResult{Err: err}
 	}
-	session := entity.Session{UserId: credentials.Id, Token: token.String()}
+	session := entity.Session{
+		UserId:     credentials.Id,
+		Token:      token.String(),
+		Role:       credentials.Role,
+		UserAgent:  truncate(credentials.UserAgent, maxUserAgent),
+		IP:         credentials.IP,
+		Persistent: credentials.RememberMe,
+	}
+	now := time.Now()
+	_, absolute := au.lifetime.Timeouts(session.Persistent)
+	session.AbsoluteExpiry = now.Add(absolute)
+	session.ExpiryTime = au.idleExpiry(session, now)
 	if session, err = au.sessionRepo.Store(ctx, session); err != nil {
 		return entity.SessionResult{Err: err}
 	}
 	return entity.SessionResult{Session: session}
 }
+
+// idleExpiry slides the idle expiry of the session from now, never past its absolute expiry
+func (au *AuthUsecase) idleExpiry(session entity.Session, now time.Time) time.Time {
+	idle, _ := au.lifetime.Timeouts(session.Persistent)
+	if expiry := now.Add(idle); expiry.Before(session.AbsoluteExpiry) {
+		return expiry
+	}
+	return session.AbsoluteExpiry
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"forum_auth/internal/entity"
+	"net/url"
+)
+
+// Identities lists the OAuth accounts linked to the owner of the token
+func (au *AuthUsecase) Identities(ctx context.Context, session entity.Session, identitiesRes chan entity.IdentitiesResult) {
+	current, err := au.activeSession(ctx, session.Token)
+	if err != nil {
+		identitiesRes <- entity.IdentitiesResult{Err: err}
+		return
+	}
+	identities, err := au.identitiesRepo.FetchByUserId(ctx, current.UserId)
+	identitiesRes <- entity.IdentitiesResult{Identities: identities, Err: err}
+}
+
+// LinkIdentity connects the OAuth account to the owner of the token, whatever its email is
+func (au *AuthUsecase) LinkIdentity(ctx context.Context, req entity.IdentityRequest, err chan error) {
+	current, e := au.activeSession(ctx, req.Token)
+	if e != nil {
+		err <- e
+		return
+	}
+	creds := req.Credentials
+	identity, e := au.identitiesRepo.Fetch(ctx, creds.Provider, creds.Subject)
+	switch {
+	case e == nil && identity.UserId == current.UserId:
+		err <- nil
+		return
+	case e == nil:
+		err <- entity.ErrIdentityLinked
+		return
+	case e != entity.ErrNotFound:
+		err <- e
+		return
+	}
+	_, e = au.identitiesRepo.Store(ctx, entity.Identity{Provider: creds.Provider, Subject: creds.Subject, UserId: current.UserId, Email: creds.Email})
+	err <- e
+}
+
+// UnlinkIdentity disconnects the account of the provider, unless it is the only way left for its user to sign in
+func (au *AuthUsecase) UnlinkIdentity(ctx context.Context, req entity.IdentityRequest, err chan error) {
+	current, e := au.activeSession(ctx, req.Token)
+	if e != nil {
+		err <- e
+		return
+	}
+	identities, e := au.identitiesRepo.FetchByUserId(ctx, current.UserId)
+	if e != nil {
+		err <- e
+		return
+	}
+	linked := false
+	for _, identity := range identities {
+		linked = linked || identity.Provider == req.Provider
+	}
+	if !linked {
+		err <- entity.ErrNotFound
+		return
+	}
+	if len(identities) == 1 {
+		hasPassword, e := au.hasPassword(ctx, current.UserId)
+		if e != nil {
+			err <- e
+			return
+		}
+		if !hasPassword {
+			err <- entity.ErrLastIdentity
+			return
+		}
+	}
+	err <- au.identitiesRepo.Delete(ctx, current.UserId, req.Provider)
+}
+
+// hasPassword reports whether the user can sign in with a password, users created by OAuth have none
+func (au *AuthUsecase) hasPassword(ctx context.Context, userId int64) (bool, error) {
+	user, err := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", userId))
+	if err != nil {
+		return false, err
+	}
+	// only the lookup by email returns the password hash
+	user, err = au.fetchUser(ctx, fmt.Sprintf("/user/email?email=%s", url.QueryEscape(user.Email)))
+	if err != nil {
+		return false, err
+	}
+	return user.Password != "", nil
+}
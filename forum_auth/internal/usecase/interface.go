@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"forum_auth/internal/entity"
+	"time"
 )
 
 type SessionsRepo interface {
@@ -10,4 +11,48 @@ type SessionsRepo interface {
 	Store(ctx context.Context, session entity.Session) (entity.Session, error)
 	Update(ctx context.Context, session entity.Session) (entity.Session, error)
 	Delete(ctx context.Context, session entity.Session) error
+	DeleteByUserId(ctx context.Context, userId int64) error
+	FetchByUserId(ctx context.Context, userId int64) ([]entity.Session, error)
+	DeleteById(ctx context.Context, userId, id int64) error
+	DeleteOthers(ctx context.Context, userId int64, token string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	CountActive(ctx context.Context, now time.Time) (int64, error)
+}
+
+type TokensRepo interface {
+	Store(ctx context.Context, token entity.OneTimeToken) error
+	Consume(ctx context.Context, token, purpose string) (entity.OneTimeToken, error)
+	Restore(ctx context.Context, token entity.OneTimeToken) error
+}
+
+type IdentitiesRepo interface {
+	Fetch(ctx context.Context, provider, subject string) (entity.Identity, error)
+	FetchByUserId(ctx context.Context, userId int64) ([]entity.Identity, error)
+	Store(ctx context.Context, identity entity.Identity) (entity.Identity, error)
+	Delete(ctx context.Context, userId int64, provider string) error
+}
+
+type TwoFactorRepo interface {
+	Fetch(ctx context.Context, userId int64) (entity.TwoFactor, error)
+	StoreSecret(ctx context.Context, userId int64, secret string) error
+	Enable(ctx context.Context, userId, step int64, codes []string) error
+	UseStep(ctx context.Context, userId, step int64) error
+	UseRecoveryCode(ctx context.Context, userId int64, code string) error
+	Delete(ctx context.Context, userId int64) error
+	StorePending(ctx context.Context, pending entity.PendingSignIn) error
+	FetchPending(ctx context.Context, token string) (entity.PendingSignIn, error)
+	FailPending(ctx context.Context, token string) error
+	DeletePending(ctx context.Context, token string) error
+}
+
+type LoginFailuresRepo interface {
+	Fetch(ctx context.Context, kind, value string) (entity.LoginFailure, error)
+	Fail(ctx context.Context, kind, value string, now time.Time, window time.Duration) (entity.LoginFailure, error)
+	Lock(ctx context.Context, lockout entity.Lockout) error
+	Reset(ctx context.Context, kind, value string) error
+	FetchLockouts(ctx context.Context, limit int) ([]entity.Lockout, error)
+}
+
+type MailSender interface {
+	Send(ctx context.Context, mail entity.Mail) error
 }
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"forum_auth/internal/entity"
+	"log"
+	"strings"
+	"time"
+)
+
+// lockoutsLimit is how many of the latest lockouts admins get to see
+const lockoutsLimit = 100
+
+// LoginGuard counts failed sign ins per account and per IP and locks them out for longer and longer.
+// Unknown emails are counted like accounts, so that lockouts don't tell which emails are registered
+type LoginGuard struct {
+	failuresRepo LoginFailuresRepo
+	policy       entity.LoginPolicy
+	infoLog      *log.Logger
+	errLog       *log.Logger
+}
+
+func NewLoginGuard(failuresRepo LoginFailuresRepo, policy entity.LoginPolicy, infoLog, errLog *log.Logger) *LoginGuard {
+	return &LoginGuard{failuresRepo: failuresRepo, policy: policy, infoLog: infoLog, errLog: errLog}
+}
+
+// Check returns ErrLockedOut and when it ends if the account of the email or the IP is locked out
+func (lg *LoginGuard) Check(ctx context.Context, email, ip string) (time.Time, error) {
+	now := time.Now()
+	for _, key := range loginKeys(email, ip) {
+		failure, err := lg.failuresRepo.Fetch(ctx, key[0], key[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if failure.LockedUntil.After(now) {
+			return failure.LockedUntil, entity.ErrLockedOut
+		}
+	}
+	return time.Time{}, nil
+}
+
+// Fail counts a failed sign in to the account of the email from the IP and locks out those that reached their
+// threshold, it returns when the longest of the lockouts ends
+func (lg *LoginGuard) Fail(ctx context.Context, email, ip string) (time.Time, error) {
+	now := time.Now()
+	var lockedUntil time.Time
+	for _, key := range loginKeys(email, ip) {
+		failure, err := lg.failuresRepo.Fail(ctx, key[0], key[1], now, lg.policy.Window)
+		if err != nil {
+			return time.Time{}, err
+		}
+		lockout := lg.policy.Lockout(failure.Kind, failure.Failures)
+		if lockout == 0 {
+			continue
+		}
+		until := now.Add(lockout)
+		err = lg.failuresRepo.Lock(ctx, entity.Lockout{Kind: failure.Kind, Value: failure.Value, Failures: failure.Failures, IP: ip, LockedUntil: until, CreatedAt: now})
+		if err != nil {
+			return time.Time{}, err
+		}
+		lg.infoLog.Printf("sign in lockout: %s %s after %d failures from %s, until %s\n", failure.Kind, failure.Value, failure.Failures, ip, until.Format(time.RFC3339))
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+	return lockedUntil, nil
+}
+
+// Succeed forgets the failures of the account of the email. Those of the IP keep counting, or an attacker
+// could start over by signing in to an account of their own
+func (lg *LoginGuard) Succeed(ctx context.Context, email string) {
+	if err := lg.failuresRepo.Reset(ctx, entity.LoginKindAccount, normalizeEmail(email)); err != nil {
+		lg.errLog.Println(err)
+	}
+}
+
+func (lg *LoginGuard) Lockouts(ctx context.Context, lockoutsRes chan entity.LockoutsResult) {
+	lockouts, err := lg.failuresRepo.FetchLockouts(ctx, lockoutsLimit)
+	lockoutsRes <- entity.LockoutsResult{Lockouts: lockouts, Err: err}
+}
+
+// loginKeys are the kinds and values failures of the sign in count for, sign ins without an IP only
+// count for the account
+func loginKeys(email, ip string) [][2]string {
+	keys := [][2]string{{entity.LoginKindAccount, normalizeEmail(email)}}
+	if ip != "" {
+		keys = append(keys, [2]string{entity.LoginKindIP, ip})
+	}
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
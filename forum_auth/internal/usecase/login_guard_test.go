@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"forum_auth/internal/entity"
+	"forum_auth/internal/repository"
+	"forum_auth/pkg/sqlite3"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestGuard(t *testing.T, policy entity.LoginPolicy) *LoginGuard {
+	t.Helper()
+	db, err := sqlite3.New(filepath.Join(t.TempDir(), "session.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	discard := log.New(io.Discard, "", 0)
+	return NewLoginGuard(repository.NewLoginFailuresRepository(db, discard), policy, discard, discard)
+}
+
+// within checks that a lockout ends d from now, give or take the second the repository rounds to
+func within(t *testing.T, until time.Time, d time.Duration) {
+	t.Helper()
+	if diff := time.Until(until) - d; diff < -2*time.Second || diff > time.Second {
+		t.Errorf("locked until %v from now, want %v", time.Until(until).Round(time.Second), d)
+	}
+}
+
+func TestLoginGuardAccount(t *testing.T) {
+	ctx := context.Background()
+	lg := newTestGuard(t, entity.LoginPolicy{Threshold: 3, IPThreshold: 100, Window: time.Hour, BaseLockout: time.Minute, MaxLockout: 4 * time.Minute})
+	tests := []struct {
+		email string
+		want  time.Duration
+	}{
+		{"user@example.com", 0},
+		// emails are counted whatever their case and spaces
+		{" USER@example.com", 0},
+		{"user@example.com", time.Minute},
+		{"user@example.com", 2 * time.Minute},
+		{"user@example.com", 4 * time.Minute},
+		{"user@example.com", 4 * time.Minute},
+	}
+	for i, tt := range tests {
+		until, err := lg.Fail(ctx, tt.email, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == 0 {
+			if !until.IsZero() {
+				t.Errorf("failure %d locked out until %v", i+1, until)
+			}
+			if _, err = lg.Check(ctx, tt.email, "192.0.2.1"); err != nil {
+				t.Errorf("check after failure %d = %v", i+1, err)
+			}
+			continue
+		}
+		within(t, until, tt.want)
+		locked, err := lg.Check(ctx, "User@Example.com", "198.51.100.1")
+		if err != entity.ErrLockedOut || !locked.Equal(until.Truncate(time.Second)) {
+			t.Errorf("check after failure %d = %v, %v, want a lockout until %v", i+1, locked, err, until)
+		}
+	}
+	if _, err := lg.Check(ctx, "other@example.com", "192.0.2.1"); err != nil {
+		t.Errorf("another account from the same IP = %v", err)
+	}
+	lockouts := make(chan entity.LockoutsResult, 1)
+	lg.Lockouts(ctx, lockouts)
+	res := <-lockouts
+	if res.Err != nil || len(res.Lockouts) != 4 || res.Lockouts[0].Value != "user@example.com" || res.Lockouts[0].Failures != 6 {
+		t.Errorf("lockouts = %+v, %v", res.Lockouts, res.Err)
+	}
+}
+
+// A success forgets the failures of the account but not those of the IP
+func TestLoginGuardIP(t *testing.T) {
+	ctx := context.Background()
+	lg := newTestGuard(t, entity.LoginPolicy{Threshold: 3, IPThreshold: 4, Window: time.Hour, BaseLockout: time.Minute, MaxLockout: time.Hour})
+	for _, email := range []string{"a@example.com", "a@example.com", "b@example.com"} {
+		if until, err := lg.Fail(ctx, email, "192.0.2.1"); err != nil || !until.IsZero() {
+			t.Fatalf("Fail(%s) = %v, %v", email, until, err)
+		}
+	}
+	lg.Succeed(ctx, "a@example.com")
+	if until, err := lg.Fail(ctx, "a@example.com", ""); err != nil || !until.IsZero() {
+		t.Errorf("the failures of the account weren't forgotten: %v, %v", until, err)
+	}
+	until, err := lg.Fail(ctx, "c@example.com", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	within(t, until, time.Minute)
+	if _, err = lg.Check(ctx, "d@example.com", "192.0.2.1"); err != entity.ErrLockedOut {
+		t.Errorf("check from the locked out IP = %v", err)
+	}
+	if _, err = lg.Check(ctx, "d@example.com", "192.0.2.2"); err != nil {
+		t.Errorf("check from another IP = %v", err)
+	}
+	// sign ins without an IP only count for the account
+	if _, err = lg.Check(ctx, "d@example.com", ""); err != nil {
+		t.Errorf("check without an IP = %v", err)
+	}
+}
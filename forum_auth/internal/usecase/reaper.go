@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"forum_auth/internal/entity"
+	"log"
+	"sync"
+	"time"
+)
+
+const sweepTimeout = time.Minute
+
+// SessionReaper periodically deletes the expired sessions that Authenticate never gets to see
+type SessionReaper struct {
+	sessionRepo SessionsRepo
+	interval    time.Duration
+	infoLog     *log.Logger
+	errLog      *log.Logger
+	mu          sync.Mutex
+	stats       entity.ReaperStats
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func NewSessionReaper(sessionRepo SessionsRepo, interval time.Duration, infoLog, errLog *log.Logger) *SessionReaper {
+	return &SessionReaper{sessionRepo: sessionRepo, interval: interval, infoLog: infoLog, errLog: errLog, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start sweeps once and then every interval until Stop
+func (sr *SessionReaper) Start() {
+	go func() {
+		defer close(sr.done)
+		ticker := time.NewTicker(sr.interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), sweepTimeout)
+			sr.sweep(ctx)
+			cancel()
+			select {
+			case <-sr.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for a running sweep to finish
+func (sr *SessionReaper) Stop() {
+	close(sr.stop)
+	<-sr.done
+}
+
+func (sr *SessionReaper) Sweep(ctx context.Context, reaperRes chan entity.ReaperResult) {
+	err := sr.sweep(ctx)
+	reaperRes <- entity.ReaperResult{Stats: sr.snapshot(), Err: err}
+}
+
+func (sr *SessionReaper) Stats(ctx context.Context, reaperRes chan entity.ReaperResult) {
+	reaperRes <- entity.ReaperResult{Stats: sr.snapshot()}
+}
+
+// Active counts the sessions that are not expired yet
+func (sr *SessionReaper) Active(ctx context.Context) (int64, error) {
+	return sr.sessionRepo.CountActive(ctx, time.Now())
+}
+
+func (sr *SessionReaper) sweep(ctx context.Context) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	now := time.Now()
+	purged, err := sr.sessionRepo.DeleteExpired(ctx, now)
+	sr.stats.Sweeps++
+	sr.stats.LastSweep = now
+	sr.stats.LastPurged = purged
+	sr.stats.Purged += purged
+	sr.stats.LastError = ""
+	if err != nil {
+		sr.errLog.Println(err)
+		sr.stats.LastError = err.Error()
+		return err
+	}
+	if purged > 0 {
+		sr.infoLog.Printf("session reaper purged %d expired sessions\n", purged)
+	}
+	return nil
+}
+
+func (sr *SessionReaper) snapshot() entity.ReaperStats {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.stats
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"forum_auth/internal/entity"
+	"time"
+	"unicode/utf8"
+)
+
+const maxUserAgent = 255
+
+// Sessions lists the devices the owner of the token is signed in on, without their tokens
+func (au *AuthUsecase) Sessions(ctx context.Context, session entity.Session, sessionsRes chan entity.SessionsResult) {
+	current, err := au.activeSession(ctx, session.Token)
+	if err != nil {
+		sessionsRes <- entity.SessionsResult{Err: err}
+		return
+	}
+	sessions, err := au.sessionRepo.FetchByUserId(ctx, current.UserId)
+	if err != nil {
+		sessionsRes <- entity.SessionsResult{Err: err}
+		return
+	}
+	active := []entity.Session{}
+	for _, s := range sessions {
+		if s.Expired(time.Now()) {
+			continue
+		}
+		s.Current = s.Token == current.Token
+		s.Token = ""
+		active = append(active, s)
+	}
+	sessionsRes <- entity.SessionsResult{Sessions: active}
+}
+
+func (au *AuthUsecase) RevokeSession(ctx context.Context, revoke entity.SessionRevoke, err chan error) {
+	current, e := au.activeSession(ctx, revoke.Token)
+	if e != nil {
+		err <- e
+		return
+	}
+	err <- au.sessionRepo.DeleteById(ctx, current.UserId, revoke.Id)
+}
+
+// RevokeOtherSessions signs the owner of the token out everywhere else
+func (au *AuthUsecase) RevokeOtherSessions(ctx context.Context, session entity.Session, err chan error) {
+	current, e := au.activeSession(ctx, session.Token)
+	if e != nil {
+		err <- e
+		return
+	}
+	err <- au.sessionRepo.DeleteOthers(ctx, current.UserId, current.Token)
+}
+
+func (au *AuthUsecase) activeSession(ctx context.Context, token string) (entity.Session, error) {
+	session, err := au.sessionRepo.Fetch(ctx, token)
+	if err != nil {
+		return entity.Session{}, err
+	}
+	if session.Token == "" || session.Expired(time.Now()) {
+		return entity.Session{}, entity.ErrInvalidSession
+	}
+	return session, nil
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// RequireRole lets through only the owner of an active session with role
+func (au *AuthUsecase) RequireRole(ctx context.Context, session entity.Session, role string, err chan error) {
+	_, e := au.sessionWithRole(ctx, session.Token, role)
+	err <- e
+}
+
+// sessionWithRole returns the active session of the token if its user has role now. The role copied into the
+// session at sign in isn't trusted, since a demotion on forum_app doesn't reach the sessions already open
+func (au *AuthUsecase) sessionWithRole(ctx context.Context, token, role string) (entity.Session, error) {
+	current, err := au.activeSession(ctx, token)
+	if err != nil {
+		return entity.Session{}, err
+	}
+	user, err := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", current.UserId))
+	if err == entity.ErrNotFound {
+		err = entity.ErrForbidden
+	}
+	if err != nil {
+		return entity.Session{}, err
+	}
+	if user.Role != role {
+		return entity.Session{}, entity.ErrForbidden
+	}
+	return current, nil
+}
@@ -0,0 +1,293 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"forum_auth/internal/entity"
+	"forum_auth/pkg/totp"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer = "Forum"
+	// totpSkew accepts the codes of the step before and after the current one
+	totpSkew = 1
+)
+
+// startSession opens a session for a user who has proven who they are, users with two-factor authentication
+// get a pending sign in instead that the code of their app turns into a session
+func (au *AuthUsecase) startSession(ctx context.Context, user entity.Credentials) entity.SessionResult {
+	tf, err := au.twoFactorRepo.Fetch(ctx, user.Id)
+	if err != nil && err != entity.ErrNotFound {
+		return entity.SessionResult{Err: err}
+	}
+	if !tf.Enabled {
+		return au.createSession(ctx, user)
+	}
+	token, err := newToken()
+	if err != nil {
+		return entity.SessionResult{Err: err}
+	}
+	pending := entity.PendingSignIn{
+		Token:      token,
+		UserId:     user.Id,
+		Persistent: user.RememberMe,
+		ExpiryTime: time.Now().Add(entity.PendingSignInLifetime),
+	}
+	if err = au.twoFactorRepo.StorePending(ctx, pending); err != nil {
+		return entity.SessionResult{Err: err}
+	}
+	return entity.SessionResult{Pending: pending}
+}
+
+// FinishSignIn turns the pending sign in of the token into a session with a code of the app or a recovery code
+func (au *AuthUsecase) FinishSignIn(ctx context.Context, req entity.TwoFactorRequest, sessionRes chan entity.SessionResult) {
+	pending, err := au.twoFactorRepo.FetchPending(ctx, req.Token)
+	if err != nil {
+		sessionRes <- entity.SessionResult{Err: err}
+		return
+	}
+	tf, err := au.twoFactorRepo.Fetch(ctx, pending.UserId)
+	if err == entity.ErrNotFound { // reset by an admin meanwhile, signing in again doesn't need a code
+		err = entity.ErrInvalidToken
+	}
+	if err != nil {
+		sessionRes <- entity.SessionResult{Err: err}
+		return
+	}
+	user, err := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", pending.UserId))
+	if err != nil {
+		sessionRes <- entity.SessionResult{Err: err}
+		return
+	}
+	if lockedUntil, err := au.guard.Check(ctx, user.Email, req.IP); err != nil {
+		sessionRes <- entity.SessionResult{LockedUntil: lockedUntil, Err: err}
+		return
+	}
+	if err = au.checkCode(ctx, tf, req.Code); err != nil {
+		if err == entity.ErrInvalidCode {
+			sessionRes <- au.failCode(ctx, req, user.Email)
+			return
+		}
+		sessionRes <- entity.SessionResult{Err: err}
+		return
+	}
+	if err = au.twoFactorRepo.DeletePending(ctx, req.Token); err != nil {
+		sessionRes <- entity.SessionResult{Err: err}
+		return
+	}
+	au.guard.Succeed(ctx, user.Email)
+	user.UserAgent, user.IP, user.RememberMe = req.UserAgent, req.IP, pending.Persistent
+	sessionRes <- au.createSession(ctx, user)
+}
+
+// failCode counts a wrong code against the pending sign in and, like a wrong password, against the account and
+// the IP, so that the codes can't be guessed past the lockouts
+func (au *AuthUsecase) failCode(ctx context.Context, req entity.TwoFactorRequest, email string) entity.SessionResult {
+	if err := au.twoFactorRepo.FailPending(ctx, req.Token); err != nil {
+		au.errLog.Println(err)
+	}
+	lockedUntil, err := au.guard.Fail(ctx, email, req.IP)
+	if err != nil {
+		return entity.SessionResult{Err: err}
+	}
+	if !lockedUntil.IsZero() {
+		return entity.SessionResult{LockedUntil: lockedUntil, Err: entity.ErrLockedOut}
+	}
+	return entity.SessionResult{Err: entity.ErrInvalidCode}
+}
+
+// TwoFactor tells the owner of the token whether two-factor authentication is on and how many recovery codes are left
+func (au *AuthUsecase) TwoFactor(ctx context.Context, session entity.Session, tfRes chan entity.TwoFactorResult) {
+	current, err := au.activeSession(ctx, session.Token)
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	tf, err := au.twoFactorRepo.Fetch(ctx, current.UserId)
+	if err == entity.ErrNotFound {
+		err = nil
+	}
+	tfRes <- entity.TwoFactorResult{TwoFactor: tf, Err: err}
+}
+
+// SetupTwoFactor returns the secret the app of the owner of the token is set up with, an enrollment that isn't
+// enabled yet keeps its secret so that the user can retry the code
+func (au *AuthUsecase) SetupTwoFactor(ctx context.Context, session entity.Session, tfRes chan entity.TwoFactorResult) {
+	current, err := au.activeSession(ctx, session.Token)
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	tf, err := au.twoFactorRepo.Fetch(ctx, current.UserId)
+	switch {
+	case err == entity.ErrNotFound:
+		if tf.Secret, err = totp.NewSecret(); err == nil {
+			err = au.twoFactorRepo.StoreSecret(ctx, current.UserId, tf.Secret)
+		}
+	case err == nil && tf.Enabled:
+		err = entity.ErrTwoFactorEnabled
+	}
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	user, err := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", current.UserId))
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	tfRes <- entity.TwoFactorResult{Setup: entity.TwoFactorSetup{Secret: tf.Secret, URI: totp.URI(totpIssuer, user.Email, tf.Secret)}}
+}
+
+// EnableTwoFactor turns the enrollment on once the app has shown it generates the right codes, the recovery
+// codes are returned this once and only their hashes are kept
+func (au *AuthUsecase) EnableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, tfRes chan entity.TwoFactorResult) {
+	current, err := au.activeSession(ctx, req.Token)
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	tf, err := au.twoFactorRepo.Fetch(ctx, current.UserId)
+	if err == nil && tf.Enabled {
+		err = entity.ErrTwoFactorEnabled
+	}
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	step, ok := totp.Validate(tf.Secret, normalizeCode(req.Code), time.Now(), totpSkew)
+	if !ok {
+		tfRes <- entity.TwoFactorResult{Err: entity.ErrInvalidCode}
+		return
+	}
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	normalized := make([]string, len(codes))
+	for i, code := range codes {
+		normalized[i] = normalizeCode(code)
+	}
+	if err = au.twoFactorRepo.Enable(ctx, current.UserId, step, normalized); err != nil {
+		tfRes <- entity.TwoFactorResult{Err: err}
+		return
+	}
+	tfRes <- entity.TwoFactorResult{Setup: entity.TwoFactorSetup{RecoveryCodes: codes}}
+}
+
+// DisableTwoFactor turns two-factor authentication off for the owner of the token. It takes the password and a code
+// like signing in, and wrong ones count toward the same lockouts, so that a stolen session can't guess its way
+// to turning it off
+func (au *AuthUsecase) DisableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, err chan error) {
+	current, e := au.activeSession(ctx, req.Token)
+	if e != nil {
+		err <- e
+		return
+	}
+	tf, e := au.twoFactorRepo.Fetch(ctx, current.UserId)
+	if e == nil && !tf.Enabled {
+		e = entity.ErrNotFound
+	}
+	if e != nil {
+		err <- e
+		return
+	}
+	user, e := au.fetchUser(ctx, fmt.Sprintf("/user?id=%d", current.UserId))
+	if e == nil {
+		// only the lookup by email carries the password hash
+		user, e = au.fetchUser(ctx, fmt.Sprintf("/user/email?email=%s", url.QueryEscape(user.Email)))
+	}
+	if e != nil {
+		err <- e
+		return
+	}
+	if _, e = au.guard.Check(ctx, user.Email, req.IP); e != nil {
+		err <- e
+		return
+	}
+	// accounts made through OAuth have no password, the code is all they can give
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		err <- au.failFactor(ctx, user.Email, req.IP, entity.ErrInvalidCredentials)
+		return
+	}
+	if e = au.checkCode(ctx, tf, req.Code); e != nil {
+		if e == entity.ErrInvalidCode {
+			e = au.failFactor(ctx, user.Email, req.IP, e)
+		}
+		err <- e
+		return
+	}
+	au.guard.Succeed(ctx, user.Email)
+	err <- au.twoFactorRepo.Delete(ctx, current.UserId)
+}
+
+// failFactor counts a wrong password or code given by a signed in user like a failed sign in, it returns
+// ErrLockedOut once that locks the account or the IP out and wrong otherwise
+func (au *AuthUsecase) failFactor(ctx context.Context, email, ip string, wrong error) error {
+	lockedUntil, err := au.guard.Fail(ctx, email, ip)
+	if err != nil {
+		return err
+	}
+	if !lockedUntil.IsZero() {
+		return entity.ErrLockedOut
+	}
+	return wrong
+}
+
+// ResetTwoFactor lets an admin turn off the two-factor authentication of a user who has lost their app and codes
+func (au *AuthUsecase) ResetTwoFactor(ctx context.Context, req entity.TwoFactorRequest, err chan error) {
+	if _, e := au.sessionWithRole(ctx, req.Token, entity.RoleAdmin); e != nil {
+		err <- e
+		return
+	}
+	err <- au.twoFactorRepo.Delete(ctx, req.UserId)
+}
+
+// checkCode accepts a code of the app once, or uses up a recovery code
+func (au *AuthUsecase) checkCode(ctx context.Context, tf entity.TwoFactor, code string) error {
+	code = normalizeCode(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(tf.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return entity.ErrInvalidCode
+		}
+		return au.twoFactorRepo.UseStep(ctx, tf.UserId, step)
+	}
+	if code == "" {
+		return entity.ErrInvalidCode
+	}
+	return au.twoFactorRepo.UseRecoveryCode(ctx, tf.UserId, code)
+}
+
+// normalizeCode drops the spaces and dashes users type or copy along with the code
+func normalizeCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, code)
+}
+
+// newRecoveryCodes returns codes like "k3f9a-2bq7x", ten base32 characters are 50 random bits
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, entity.RecoveryCodeCount)
+	b := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
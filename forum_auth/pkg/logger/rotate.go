@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a log file that is renamed to path.1 once it reaches maxSize bytes, the older files being
+// shifted to path.2 and so on, and the ones past maxBackups removed
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	// limit is the size the next rotation is tried at, a rotation that failed is tried again maxSize bytes later
+	limit int64
+}
+
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file != nil && rf.size > 0 && rf.size+int64(len(p)) > rf.limit {
+		if err := rf.rotate(); err != nil {
+			fmt.Fprintln(os.Stderr, "rotating the log file:", err)
+			rf.limit = rf.size + rf.maxSize
+		}
+	}
+	// the file is missing only when it couldn't be opened again after a rotation
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	return rf.file.Close()
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file, rf.size, rf.limit = file, info.Size(), rf.maxSize
+	return nil
+}
+
+// rotate shifts the files and opens a new one. When the shift fails the current file is opened again,
+// the log goes on in it rather than in a closed file
+func (rf *RotatingFile) rotate() error {
+	err := rf.file.Close()
+	if err == nil {
+		err = rf.shift()
+	}
+	rf.file = nil
+	if openErr := rf.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+func (rf *RotatingFile) shift() error {
+	if rf.maxBackups == 0 {
+		if err := os.Remove(rf.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	os.Remove(backup(rf.path, rf.maxBackups))
+	for i := rf.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(backup(rf.path, i), backup(rf.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(rf.path, backup(rf.path, 1))
+}
+
+func backup(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// InstrumentMux records the duration of every request served by mux by route, method and status. The route is
+// the pattern of mux that matched, so paths with IDs and queries don't each make their own series
+func (r *Registry) InstrumentMux(mux *http.ServeMux) http.Handler {
+	duration := r.NewHistogram("http_request_duration_seconds", "Duration of the HTTP requests served, by route, method and status.",
+		DefaultBuckets, "route", "method", "status")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, req)
+		_, route := mux.Handler(req)
+		if route == "" {
+			route = "unmatched"
+		}
+		duration.Observe(time.Since(start).Seconds(), route, method(req.Method), strconv.Itoa(rec.status))
+	})
+}
+
+// method keeps the methods clients make up out of the labels
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "other"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
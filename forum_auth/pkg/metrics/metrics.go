@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of request durations
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics of a service and serves them in the Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry returns a Registry that already holds the number of goroutines
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.NewGaugeFunc("go_goroutines", "Goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	return r
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Handler serves the metrics at every scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		metrics := append([]metric{}, r.metrics...)
+		r.mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		for _, m := range metrics {
+			m.write(buf)
+		}
+		buf.Flush()
+	})
+}
+
+// desc is what every metric has: its name, its help text, its type and the names of its labels
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind)
+}
+
+// key joins label values into a map key, values never hold the separator since it can't be typed
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// series writes the labels of values, with extra appended, like {route="/posts",le="0.5"}
+func (d desc) series(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, d.labels[i]+`="`+escapeValue(v)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeValue(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a value per set of labels that only goes up
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name, help, "counter", labels}, values: map[string]*counterValue{}}
+	r.register(c)
+	return c
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(delta float64, labels ...string) {
+	key := c.key(labels)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: append([]string{}, labels...)}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.header(w)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.series(v.labels), formatFloat(v.value))
+	}
+}
+
+// Histogram counts observations per set of labels in cumulative buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{desc: desc{name, help, "histogram", labels}, buckets: buckets, values: map[string]*histogramValue{}}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	key := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{labels: append([]string{}, labels...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.series(v.labels, "le", formatFloat(bound)), v.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.series(v.labels, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.series(v.labels), formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.series(v.labels), v.count)
+	}
+}
+
+// Func is a metric without labels read at every scrape, like the size of a pool
+type Func struct {
+	desc
+	read func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, read func() float64) {
+	r.register(&Func{desc{name, help, "gauge", nil}, read})
+}
+
+func (r *Registry) NewCounterFunc(name, help string, read func() float64) {
+	r.register(&Func{desc{name, help, "counter", nil}, read})
+}
+
+func (f *Func) write(w *bufio.Writer) {
+	f.header(w)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(f.read()))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
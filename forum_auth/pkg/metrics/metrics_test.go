@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func assertLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestExposition(t *testing.T) {
+	r := &Registry{}
+	c := r.NewCounter("requests_total", "Requests.\nBy code.", "code")
+	c.Inc("200")
+	c.Add(2, "200")
+	c.Inc(`a"b\c`)
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "query")
+	h.Observe(0.5, "query")
+	h.Observe(3, "query")
+	r.NewGaugeFunc("up", "Up.", func() float64 { return 1 })
+
+	assertLines(t, scrape(t, r),
+		`# HELP requests_total Requests.\nBy code.`,
+		`# TYPE requests_total counter`,
+		`requests_total{code="200"} 3`,
+		`requests_total{code="a\"b\\c"} 1`,
+		`# TYPE latency_seconds histogram`,
+		`latency_seconds_bucket{op="query",le="0.1"} 1`,
+		`latency_seconds_bucket{op="query",le="1"} 2`,
+		`latency_seconds_bucket{op="query",le="+Inf"} 3`,
+		`latency_seconds_sum{op="query"} 3.55`,
+		`latency_seconds_count{op="query"} 3`,
+		`# TYPE up gauge`,
+		`up 1`,
+	)
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic with a missing label value")
+		}
+	}()
+	(&Registry{}).NewCounter("c_total", "C.", "a", "b").Inc("x")
+}
+
+func TestInstrumentMux(t *testing.T) {
+	r := &Registry{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/posts/", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusRequestTimeout)
+	})
+	mux.HandleFunc("/exact", func(w http.ResponseWriter, req *http.Request) {})
+	handler := r.InstrumentMux(mux)
+	for _, target := range []string{"/posts/1", "/posts/2?page=3", "/exact", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/exact", nil))
+
+	assertLines(t, scrape(t, r),
+		`http_request_duration_seconds_count{route="/posts/",method="GET",status="408"} 2`,
+		`http_request_duration_seconds_count{route="/exact",method="GET",status="200"} 1`,
+		`http_request_duration_seconds_count{route="unmatched",method="GET",status="404"} 1`,
+		`http_request_duration_seconds_count{route="/exact",method="other",status="200"} 1`,
+	)
+}
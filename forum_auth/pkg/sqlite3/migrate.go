@@ -0,0 +1,205 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrNewerSchema = errors.New("database schema is newer than this build supports")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(*sql.Tx) error
+	Down    func(*sql.Tx) error
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db, migrations}
+}
+
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) init() error {
+	_, err := m.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	);`)
+	return err
+}
+
+func (m *Migrator) Version() (int, error) {
+	if err := m.init(); err != nil {
+		return 0, err
+	}
+	var version int
+	err := m.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations;`).Scan(&version)
+	return version, err
+}
+
+// Check refuses a database migrated by a newer build, its schema can't be trusted
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("%w: schema version %d, latest known %d", ErrNewerSchema, version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies pending migrations up to and including target
+func (m *Migrator) Up(target int) error {
+	if err := m.Check(); err != nil {
+		return err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if migration.Version <= version || migration.Version > target {
+			continue
+		}
+		if err = m.apply(migration, migration.Up, true); err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// Down reverts applied migrations newer than target
+func (m *Migrator) Down(target int) error {
+	if err := m.Check(); err != nil {
+		return err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	for ix := len(m.migrations) - 1; ix >= 0; ix-- {
+		migration := m.migrations[ix]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+		if err = m.apply(migration, migration.Down, false); err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+	applied := map[int]string{}
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt string
+		)
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	statuses := []MigrationStatus{}
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{migration, ok, appliedAt})
+	}
+	return statuses, rows.Err()
+}
+
+// apply runs a migration step on a dedicated connection with foreign keys disabled,
+// so that tables can be rebuilt without cascading deletes, and checks the keys before commit
+func (m *Migrator) apply(migration Migration, step func(*sql.Tx) error, up bool) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF;`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON;`)
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = step(tx); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`PRAGMA foreign_key_check;`)
+	if err != nil {
+		return err
+	}
+	violation := rows.Next()
+	rows.Close()
+	if violation {
+		return errors.New("foreign key constraint failed")
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations(version, name, applied_at) VALUES(?, ?, ?);`, migration.Version, migration.Name, time.Now().Format("2006-01-02 15:04:05"))
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?;`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func execSQL(queries ...string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, query := range queries {
+			if _, err := tx.Exec(query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// rebuildTable recreates table with the given column definitions and copies columns over,
+// the way SQLite changes constraints of existing columns
+func rebuildTable(tx *sql.Tx, table, definition string, columns ...string) error {
+	cols := strings.Join(columns, ", ")
+	return execSQL(
+		fmt.Sprintf(`CREATE TABLE %s_new (%s);`, table, definition),
+		fmt.Sprintf(`INSERT INTO %s_new(%s) SELECT %s FROM %s;`, table, cols, cols, table),
+		fmt.Sprintf(`DROP TABLE %s;`, table),
+		fmt.Sprintf(`ALTER TABLE %s_new RENAME TO %s;`, table, table),
+	)(tx)
+}
@@ -0,0 +1,217 @@
+package sqlite3
+
+import (
+	"database/sql"
+	"time"
+)
+
+// migrations are applied in order and never edited once released, schema changes go into a new one
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			user_id INTEGER NOT NULL UNIQUE,
+			token TEXT NOT NULL UNIQUE,
+			expiry_date TEXT NOT NULL
+		);`),
+		Down: execSQL(`DROP TABLE IF EXISTS sessions;`),
+	},
+	{
+		Version: 2,
+		Name:    "session roles",
+		Up:      execSQL(`ALTER TABLE sessions ADD COLUMN role TEXT NOT NULL DEFAULT 'user';`),
+		Down:    execSQL(`ALTER TABLE sessions DROP COLUMN role;`),
+	},
+	{
+		Version: 3,
+		Name:    "one-time tokens",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS tokens (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			email TEXT NOT NULL,
+			purpose TEXT NOT NULL,
+			expiry_date TEXT NOT NULL
+		);`,
+			`CREATE INDEX IF NOT EXISTS tokens_user_id ON tokens(user_id, purpose);`,
+		),
+		Down: execSQL(`DROP TABLE IF EXISTS tokens;`),
+	},
+	{
+		Version: 4,
+		Name:    "multiple sessions",
+		Up: func(tx *sql.Tx) error {
+			err := rebuildTable(tx, "sessions", `
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token TEXT NOT NULL UNIQUE,
+			expiry_date TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT 'user',
+			user_agent TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT '',
+			last_seen TEXT NOT NULL DEFAULT ''`, "user_id", "token", "expiry_date", "role")
+			if err != nil {
+				return err
+			}
+			return execSQL(`CREATE INDEX IF NOT EXISTS sessions_user_id ON sessions(user_id);`)(tx)
+		},
+		// going down keeps only the newest session of every user
+		Down: func(tx *sql.Tx) error {
+			err := execSQL(
+				`DROP INDEX IF EXISTS sessions_user_id;`,
+				`DELETE FROM sessions WHERE id NOT IN (SELECT MAX(id) FROM sessions GROUP BY user_id);`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			return rebuildTable(tx, "sessions", `
+			user_id INTEGER NOT NULL UNIQUE,
+			token TEXT NOT NULL UNIQUE,
+			expiry_date TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT 'user'`, "user_id", "token", "expiry_date", "role")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "absolute session expiry",
+		// existing sessions end at their current idle expiry
+		Up: execSQL(
+			`ALTER TABLE sessions ADD COLUMN absolute_expiry TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE sessions ADD COLUMN persistent INTEGER NOT NULL DEFAULT 0;`,
+			`UPDATE sessions SET absolute_expiry = expiry_date;`,
+		),
+		Down: execSQL(
+			`ALTER TABLE sessions DROP COLUMN persistent;`,
+			`ALTER TABLE sessions DROP COLUMN absolute_expiry;`,
+		),
+	},
+	{
+		Version: 6,
+		Name:    "login identities",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS identities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL DEFAULT '',
+			UNIQUE(provider, subject),
+			UNIQUE(user_id, provider)
+		);`),
+		Down: execSQL(`DROP TABLE IF EXISTS identities;`),
+	},
+	{
+		Version: 7,
+		Name:    "two-factor authentication",
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS two_factor (
+			user_id INTEGER PRIMARY KEY,
+			secret TEXT NOT NULL,
+			enabled INTEGER NOT NULL DEFAULT 0,
+			last_step INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL DEFAULT ''
+		);`, `
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			code_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL
+		);`,
+			`CREATE INDEX IF NOT EXISTS recovery_codes_user_id ON recovery_codes(user_id);`, `
+		CREATE TABLE IF NOT EXISTS pending_sign_ins (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			persistent INTEGER NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expiry_date TEXT NOT NULL
+		);`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS pending_sign_ins;`,
+			`DROP TABLE IF EXISTS recovery_codes;`,
+			`DROP TABLE IF EXISTS two_factor;`,
+		),
+	},
+	{
+		Version: 8,
+		Name:    "sign in lockouts",
+		// times are unix seconds here so that stale counters can be purged in SQL
+		Up: execSQL(`
+		CREATE TABLE IF NOT EXISTS login_failures (
+			kind TEXT NOT NULL,
+			value TEXT NOT NULL,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure INTEGER NOT NULL DEFAULT 0,
+			locked_until INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (kind, value)
+		);`, `
+		CREATE TABLE IF NOT EXISTS lockouts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			value TEXT NOT NULL,
+			failures INTEGER NOT NULL,
+			ip TEXT NOT NULL DEFAULT '',
+			locked_until INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		);`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS lockouts;`,
+			`DROP TABLE IF EXISTS login_failures;`,
+		),
+	},
+	{
+		Version: 9,
+		Name:    "sortable session expiry",
+		// expires_at is when the session ends in unix seconds, the earlier of its idle and absolute expiry, so that
+		// expired sessions can be found in SQL
+		Up: func(tx *sql.Tx) error {
+			err := execSQL(
+				`ALTER TABLE sessions ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;`,
+				`CREATE INDEX IF NOT EXISTS sessions_expires_at ON sessions(expires_at);`,
+			)(tx)
+			if err != nil {
+				return err
+			}
+			return backfillExpiresAt(tx)
+		},
+		Down: execSQL(
+			`DROP INDEX IF EXISTS sessions_expires_at;`,
+			`ALTER TABLE sessions DROP COLUMN expires_at;`,
+		),
+	},
+}
+
+// backfillExpiresAt fills expires_at from the text dates of the sessions, those that don't parse end right away
+func backfillExpiresAt(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, expiry_date, absolute_expiry FROM sessions;`)
+	if err != nil {
+		return err
+	}
+	ends := map[int64]int64{}
+	for rows.Next() {
+		var id int64
+		var expiry, absolute string
+		if err = rows.Scan(&id, &expiry, &absolute); err != nil {
+			rows.Close()
+			return err
+		}
+		end, _ := time.Parse(time.Layout, expiry)
+		if a, err := time.Parse(time.Layout, absolute); err == nil && a.Before(end) {
+			end = a
+		}
+		ends[id] = end.Unix()
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for id, end := range ends {
+		if _, err = tx.Exec(`UPDATE sessions SET expires_at = ? WHERE id = ?;`, end, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package sqlite3
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Every migration of forum_auth reverts cleanly and applies again, one at a time
+func TestAuthMigrations(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "session.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m := NewAuthMigrator(db)
+	if err = m.Up(m.Latest()); err != nil {
+		t.Fatal(err)
+	}
+	for v := m.Latest() - 1; v >= 0; v-- {
+		if err = m.Down(v); err != nil {
+			t.Fatalf("down to %d: %v", v, err)
+		}
+		if err = m.Up(v + 1); err != nil {
+			t.Fatalf("up to %d again: %v", v+1, err)
+		}
+		if err = m.Down(v); err != nil {
+			t.Fatalf("down to %d again: %v", v, err)
+		}
+	}
+	if version, err := m.Version(); err != nil || version != 0 {
+		t.Errorf("version = %d, %v", version, err)
+	}
+	if err = m.Up(m.Latest()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,26 +3,45 @@ package sqlite3
 import (
 	"database/sql"
 
-	_ "github.com/mattn/go-sqlite3"
+	sqlite "github.com/mattn/go-sqlite3"
 )
 
-func New() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "./session.db")
+// New opens the database at path and applies pending migrations, observe times the queries unless it is nil
+func New(path string, observe Observer) (*sql.DB, error) {
+	db, err := open(path, observe)
 	if err != nil {
 		return nil, err
 	}
-	if err := db.Ping(); err != nil {
+	migrator := NewAuthMigrator(db)
+	if err = migrator.Up(migrator.Latest()); err != nil {
+		db.Close()
 		return nil, err
 	}
-	sessions := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		user_id INTEGER NOT NULL UNIQUE,
-		token TEXT NOT NULL UNIQUE,
-		expiry_date TEXT NOT NULL
-	);`
-	_, err = db.Exec(sessions)
+	return db, nil
+}
+
+func Open(path string) (*sql.DB, error) {
+	return open(path, nil)
+}
+
+func open(path string, observe Observer) (*sql.DB, error) {
+	if observe != nil {
+		return ping(sql.OpenDB(timedConnector{path, &sqlite.SQLiteDriver{}, observe}))
+	}
+	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, err
 	}
+	return ping(db)
+}
+
+func ping(db *sql.DB) (*sql.DB, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
 	return db, nil
 }
+
+func NewAuthMigrator(db *sql.DB) *Migrator {
+	return NewMigrator(db, migrations)
+}
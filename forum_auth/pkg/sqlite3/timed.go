@@ -0,0 +1,100 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	sqlite "github.com/mattn/go-sqlite3"
+)
+
+// Observer is told how long every query and exec took, op being "query" or "exec". A query lasts until its
+// rows are closed, since SQLite steps through them as they are read
+type Observer func(op string, d time.Duration)
+
+// timedConnector opens connections whose statements are timed for observe
+type timedConnector struct {
+	dsn     string
+	driver  *sqlite.SQLiteDriver
+	observe Observer
+}
+
+func (c timedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn.(*sqlite.SQLiteConn), c.observe}, nil
+}
+
+func (c timedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type timedConn struct {
+	*sqlite.SQLiteConn
+	observe Observer
+}
+
+func (c *timedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{stmt.(*sqlite.SQLiteStmt), c.observe}, nil
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	c.observe("exec", time.Since(start))
+	return result, err
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		c.observe("query", time.Since(start))
+		return nil, err
+	}
+	return &timedRows{rows.(*sqlite.SQLiteRows), start, c.observe}, nil
+}
+
+type timedStmt struct {
+	*sqlite.SQLiteStmt
+	observe Observer
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := s.SQLiteStmt.ExecContext(ctx, args)
+	s.observe("exec", time.Since(start))
+	return result, err
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.SQLiteStmt.QueryContext(ctx, args)
+	if err != nil {
+		s.observe("query", time.Since(start))
+		return nil, err
+	}
+	return &timedRows{rows.(*sqlite.SQLiteRows), start, s.observe}, nil
+}
+
+type timedRows struct {
+	*sqlite.SQLiteRows
+	start   time.Time
+	observe Observer
+}
+
+func (r *timedRows) Close() error {
+	err := r.SQLiteRows.Close()
+	r.observe("query", time.Since(r.start))
+	return err
+}
@@ -0,0 +1,84 @@
+// Package totp implements the time-based one-time passwords of RFC 6238 with the parameters every
+// authenticator app supports: HMAC-SHA1, 6 digits and 30 second steps
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random 160 bit secret encoded in base32, the way authenticator apps take it
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URI is the otpauth URI apps scan from a QR code or open as a link to add the account
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(Period))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// Step is the number of the time step t falls in
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code generates the code of the secret for the time step
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%1000000), nil
+}
+
+// Validate looks for the code in the steps up to skew steps around now, to allow for clock drift and
+// typing time, and returns the step it belongs to so that callers can refuse to accept it twice
+func Validate(secret, code string, now time.Time, skew int64) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(now)
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,125 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of RFC 6238 Appendix B, "12345678901234567890", in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The appendix lists 8 digit codes, the 6 digit ones are their last 6 digits
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSecretForms(t *testing.T) {
+	for _, secret := range []string{"gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfcSecret + "===="} {
+		got, err := Code(secret, Step(time.Unix(59, 0)))
+		if err != nil || got != "287082" {
+			t.Errorf("Code(%q) = %q, %v", secret, got, err)
+		}
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted a secret that isn't base32")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	tests := []struct {
+		name string
+		step int64
+		ok   bool
+	}{
+		{"current step", current, true},
+		{"previous step", current - 1, true},
+		{"next step", current + 1, true},
+		{"two steps before", current - 2, false},
+		{"two steps after", current + 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, code(tt.step), now, 1)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && step != tt.step {
+				t.Errorf("step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+	if _, ok := Validate(rfcSecret, code(current-1), now, 0); ok {
+		t.Error("a code of the previous step passed without skew")
+	}
+}
+
+func TestValidateEdgesOfStep(t *testing.T) {
+	// 1111111109 and 1111111111 fall in neighbouring steps, each code is accepted at the other time with skew 1
+	first, second := time.Unix(1111111109, 0), time.Unix(1111111111, 0)
+	if Step(first)+1 != Step(second) {
+		t.Fatalf("steps %d and %d aren't neighbours", Step(first), Step(second))
+	}
+	if _, ok := Validate(rfcSecret, "081804", second, 1); !ok {
+		t.Error("the code of the step before was refused")
+	}
+	if _, ok := Validate(rfcSecret, "050471", first, 1); !ok {
+		t.Error("the code of the step after was refused")
+	}
+	if _, ok := Validate(rfcSecret, "081804", second, 0); ok {
+		t.Error("the code of the step before passed without skew")
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate accepted %q", code)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 32 || a == b {
+		t.Errorf("secrets %q and %q", a, b)
+	}
+	if _, err = Code(a, 1); err != nil {
+		t.Errorf("a new secret doesn't decode: %v", err)
+	}
+}
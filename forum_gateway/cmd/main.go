@@ -1,12 +1,28 @@
 package main
 
 import (
+	"flag"
 	"forum_gateway/internal/app"
+	"forum_gateway/internal/config"
+	"log"
+	"os"
 )
 
 func init() {
-	app.SetEnv()
+	config.SetEnv()
 }
 func main() {
-	app.Run()
+	cfg, _, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+	if cfg.PrintConfig {
+		if err = cfg.Print(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	app.Run(cfg)
 }
@@ -0,0 +1,33 @@
+// Command mockoidc runs a local OpenID Connect provider that signs everyone in as one user, for example
+//
+//	go run ./cmd/mockoidc -addr localhost:9000
+//	OIDC_PROVIDERS=mock OIDC_MOCK_ISSUER=http://localhost:9000 OIDC_MOCK_CLIENT_ID=forum OIDC_MOCK_CLIENT_SECRET=secret go run ./cmd
+package main
+
+import (
+	"flag"
+	"forum_gateway/pkg/mockoidc"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "address to listen on")
+	issuer := flag.String("issuer", "", "issuer URL, http://{addr} by default")
+	clientId := flag.String("client-id", "forum", "client id of the gateway")
+	clientSecret := flag.String("client-secret", "secret", "client secret of the gateway")
+	subject := flag.String("sub", "mock-user", "subject of the user")
+	email := flag.String("email", "mock@example.com", "email of the user")
+	verified := flag.Bool("email-verified", true, "whether the email of the user is verified")
+	name := flag.String("name", "mockuser", "name of the user")
+	flag.Parse()
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+	server, err := mockoidc.New(*issuer, *clientId, *clientSecret, mockoidc.User{Subject: *subject, Email: *email, EmailVerified: *verified, Name: *name})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Printf("mock OpenID Connect provider %s for client %s\n", *issuer, *clientId)
+	log.Fatal(http.ListenAndServe(*addr, server.Handler()))
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+// FORGOT PASSWORD
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == true {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.APIResponse(w, http.StatusOK, entity.Response{Body: map[string]interface{}{}}, "templates/forgot_password.html")
+	case http.MethodPost:
+		h.postForgotPassword(w, r)
+	default:
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+	}
+}
+
+func (h *Handler) postForgotPassword(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	email := r.FormValue("email")
+	if !entity.ValidEmail(email) {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Invalid email format", Body: map[string]interface{}{}}, "templates/forgot_password.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.ForgotPassword(ctx, email, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err := <-errChan:
+		if err != nil {
+			h.postModificationResponse(w, r, err, "")
+			return
+		}
+		// the same answer for every email, whether it is registered is not disclosed
+		h.APIResponse(w, http.StatusOK, entity.Response{Body: map[string]interface{}{"sent": true, "email": email}}, "templates/forgot_password.html")
+	}
+}
+
+// RESET PASSWORD
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		token := r.URL.Query().Get("token")
+		h.APIResponse(w, http.StatusOK, entity.Response{Body: map[string]interface{}{"token": token}}, "templates/reset_password.html")
+	case http.MethodPost:
+		h.postResetPassword(w, r)
+	default:
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+	}
+}
+
+func (h *Handler) postResetPassword(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	reset := entity.PasswordReset{Token: r.FormValue("token"), Password: r.FormValue("password")}
+	body := map[string]interface{}{"token": reset.Token}
+	if ok, message := reset.Validate(r.FormValue("confirm_password")); !ok {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: message, Body: body}, "templates/reset_password.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.ResetPassword(ctx, reset, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err := <-errChan:
+		switch err {
+		case nil:
+			// the reset has ended every session, including the one of this browser
+			http.SetCookie(w, sessionCookie(entity.Session{}))
+			http.Redirect(w, r, "/sign-in", http.StatusSeeOther)
+		case entity.ErrInvalidToken:
+			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "The link is invalid or has expired, request a new one", Body: map[string]interface{}{}}, "templates/reset_password.html")
+		default:
+			h.postModificationResponse(w, r, err, "")
+		}
+	}
+}
+
+// VERIFY EMAIL
+// the link of the email only opens a confirmation page, the token is spent by its form,
+// so that mail scanners prefetching links don't verify addresses on their own
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		response := entity.Response{Body: map[string]interface{}{}}
+		h.setUserInfo(r, &response)
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			response.ErrorMessage = "The link is invalid or has expired"
+			h.APIResponse(w, http.StatusBadRequest, response, "templates/verify_email.html")
+			return
+		}
+		response.Body = map[string]interface{}{"token": token}
+		h.APIResponse(w, http.StatusOK, response, "templates/confirm_email.html")
+	case http.MethodPost:
+		h.postVerifyEmail(w, r)
+	default:
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+	}
+}
+
+func (h *Handler) postVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	response := entity.Response{Body: map[string]interface{}{}}
+	h.setUserInfo(r, &response)
+	token := r.FormValue("token")
+	if token == "" {
+		response.ErrorMessage = "The link is invalid or has expired"
+		h.APIResponse(w, http.StatusBadRequest, response, "templates/verify_email.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.VerifyEmail(ctx, token, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err := <-errChan:
+		switch err {
+		case nil:
+			response.Body = map[string]interface{}{"verified": true}
+			h.APIResponse(w, http.StatusOK, response, "templates/verify_email.html")
+		case entity.ErrInvalidToken:
+			response.ErrorMessage = "The link is invalid or has expired"
+			h.APIResponse(w, http.StatusBadRequest, response, "templates/verify_email.html")
+		default:
+			h.postModificationResponse(w, r, err, "")
+		}
+	}
+}
+
+func (h *Handler) SendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	userId, _ := r.Context().Value("user_id").(int64)
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.SendVerification(ctx, userId, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err := <-errChan:
+		if err != nil {
+			h.postModificationResponse(w, r, err, "")
+			return
+		}
+		response := entity.Response{Body: map[string]interface{}{"sent": true}}
+		h.setUserInfo(r, &response)
+		h.APIResponse(w, http.StatusOK, response, "templates/verify_email.html")
+	}
+}
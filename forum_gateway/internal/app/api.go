@@ -0,0 +1,273 @@
+package app
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"forum_gateway/internal/entity"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// apiPrefix is the root of the JSON API, a new version gets a new tree next to it
+const apiPrefix = "/api/v1"
+
+// apiMaxBody is the largest JSON body the API decodes
+const apiMaxBody = 1 << 20
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// bearerPattern is the token68 syntax of RFC 6750, anything else can't be a session token
+var bearerPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~+/]+=*$`)
+
+// apiData and apiError are the envelopes every API response comes in
+type apiData struct {
+	Data interface{} `json:"data"`
+}
+
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// API wraps the handlers of the JSON API. It reads the session from the Authorization header only,
+// never from the cookie, so requests from other sites carry no session and need no CSRF token
+func (h *Handler) API(hf http.HandlerFunc) http.HandlerFunc {
+	return h.APIRateLimit(h.BearerAuth(hf))
+}
+
+func (h *Handler) APIRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := h.rateLimiter.GetLimiter(getIp(r.RemoteAddr))
+		if !limiter.Allow() {
+			h.rateLimited.Inc("api")
+			h.JSONError(w, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BearerAuth sets the same context values as Authenticate. Requests without a token go on anonymously,
+// a token that isn't valid is refused rather than ignored
+func (h *Handler) BearerAuth(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "authorised", false)
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			h.apiUnauthorised(w, "Invalid or expired token")
+			return
+		}
+		authCtx, cancel := getTimeout(r.Context())
+		defer cancel()
+		authResChan := make(chan entity.AuthStatusResult, 1)
+		go h.auUcase.Authenticate(authCtx, token, authResChan)
+		select {
+		case <-authCtx.Done():
+			h.logError(ctx, authCtx.Err())
+			h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+		case authRes := <-authResChan:
+			switch {
+			case authRes.Status == entity.Authorised:
+				ctx = context.WithValue(context.WithValue(ctx, "authorised", true), "user_id", authRes.Session.UserId)
+				ctx = context.WithValue(ctx, "role", authRes.Session.Role)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			case authRes.Err != nil:
+				h.apiErrorResponse(w, r, authRes.Err)
+			default:
+				h.apiUnauthorised(w, "Invalid or expired token")
+			}
+		}
+	})
+}
+
+// bearerToken returns the session token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, bearerPattern.MatchString(token)
+}
+
+// apiUserId returns the id of the signed in user, or refuses the request
+func (h *Handler) apiUserId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userId, ok := r.Context().Value("user_id").(int64)
+	if r.Context().Value("authorised") != true || !ok {
+		h.apiUnauthorised(w, "Sign in required")
+		return 0, false
+	}
+	return userId, true
+}
+
+func (h *Handler) apiUnauthorised(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="forum"`)
+	h.JSONError(w, http.StatusUnauthorized, message)
+}
+
+// JSONResponse sends data in the success envelope, 204 has no body
+func (h *Handler) JSONResponse(w http.ResponseWriter, r *http.Request, code int, data interface{}) {
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+	b, err := json.Marshal(apiData{Data: data})
+	if err != nil {
+		h.logError(r.Context(), err)
+		code, b = http.StatusInternalServerError, []byte(`{"error":{"status":500,"message":"Internal Server Error"}}`)
+	}
+	writeJSON(w, code, b)
+}
+
+func (h *Handler) JSONError(w http.ResponseWriter, code int, message string) {
+	// the envelope holds a number and a string, it always marshals
+	b, _ := json.Marshal(apiError{Error: apiErrorBody{Status: code, Message: message}})
+	writeJSON(w, code, b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, b []byte) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// apiErrorResponse answers with the status an error of the usecases stands for
+func (h *Handler) apiErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	code, message := apiStatus(err)
+	if code == http.StatusInternalServerError {
+		h.logError(r.Context(), err)
+	}
+	h.JSONError(w, code, message)
+}
+
+func apiStatus(err error) (int, string) {
+	switch err {
+	case entity.ErrBadRequest, entity.ErrEmptyComment, entity.ErrInvalidToken:
+		return http.StatusBadRequest, err.Error()
+	case entity.ErrInvalidCredentials, entity.ErrInvalidCode:
+		return http.StatusUnauthorized, err.Error()
+	case entity.ErrForbidden:
+		return http.StatusForbidden, err.Error()
+	case entity.ErrNotFound:
+		return http.StatusNotFound, err.Error()
+	case entity.ErrRequestTimeout:
+		return http.StatusRequestTimeout, err.Error()
+	case entity.ErrEmailExists, entity.ErrTwoFactorEnabled:
+		return http.StatusConflict, err.Error()
+	case entity.ErrLockedOut:
+		return http.StatusTooManyRequests, err.Error()
+	}
+	return http.StatusInternalServerError, "Internal Server Error"
+}
+
+func (h *Handler) apiMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	h.JSONError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+}
+
+// decodeJSON reads the body into v, or answers 400
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody)).Decode(v); err != nil {
+		h.logError(r.Context(), err)
+		h.JSONError(w, http.StatusBadRequest, "Invalid JSON body")
+		return false
+	}
+	return true
+}
+
+// apiPath splits the path under collection into the id and what follows it, "/api/v1/posts/3/comments"
+// is 3 and "comments"
+func apiPath(r *http.Request, collection string) (int, string, bool) {
+	rest := strings.TrimPrefix(r.URL.Path, apiPrefix+"/"+collection+"/")
+	segment, sub, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(segment)
+	if err != nil || id <= 0 || strings.Contains(sub, "/") {
+		return 0, "", false
+	}
+	return id, sub, true
+}
+
+// apiFetch answers with the body fetch gets from the usecase
+func (h *Handler) apiFetch(w http.ResponseWriter, r *http.Request, fetch func(context.Context, chan entity.Response)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	responseChan := make(chan entity.Response, 1)
+	go fetch(ctx, responseChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+		h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+	case response := <-responseChan:
+		if response.Err != nil {
+			h.apiErrorResponse(w, r, response.Err)
+			return
+		}
+		h.JSONResponse(w, r, http.StatusOK, response.Body)
+	}
+}
+
+// apiCommand answers 204 once apply is done
+func (h *Handler) apiCommand(w http.ResponseWriter, r *http.Request, apply func(context.Context, chan error)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go apply(ctx, errChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+		h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+	case err := <-errChan:
+		if err != nil {
+			h.apiErrorResponse(w, r, err)
+			return
+		}
+		h.JSONResponse(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// apiStore answers 201 with the id of what store created, location formats the id into its URL
+func (h *Handler) apiStore(w http.ResponseWriter, r *http.Request, store func(context.Context, chan entity.Result), location func(int) string) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	resChan := make(chan entity.Result, 1)
+	go store(ctx, resChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+		h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+	case res := <-resChan:
+		if res.Err != nil {
+			h.apiErrorResponse(w, r, res.Err)
+			return
+		}
+		w.Header().Set("Location", location(res.Id))
+		h.JSONResponse(w, r, http.StatusCreated, map[string]int{"id": res.Id})
+	}
+}
+
+// OpenAPIHandler serves the description of the API
+func (h *Handler) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openAPI)
+}
+
+// APINotFoundHandler answers the paths of the API nothing else matched
+func (h *Handler) APINotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	h.JSONError(w, http.StatusNotFound, "Not Found")
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"forum_gateway/internal/entity"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (h *Handler) APISignUpHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var input entity.SignUpInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	credentials := entity.Credentials{Name: input.Name, Email: input.Email, Password: input.Password}
+	if ok, message := credentials.ValidateSignUp(input.ConfirmPassword); !ok {
+		h.JSONError(w, http.StatusBadRequest, message)
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.SignUp(ctx, credentials, errChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+		h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+	case err := <-errChan:
+		if err != nil {
+			h.apiErrorResponse(w, r, err)
+			return
+		}
+		h.JSONResponse(w, r, http.StatusCreated, map[string]string{"email": credentials.Email})
+	}
+}
+
+// APISignInHandler answers with the session token to send as "Authorization: Bearer", or with 202 and
+// a two_factor_token for /auth/two-factor when the user has two-factor authentication
+func (h *Handler) APISignInHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var input entity.SignInInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	credentials := entity.Credentials{Email: input.Email, Password: input.Password, RememberMe: input.RememberMe}
+	credentials.UserAgent, credentials.IP = r.UserAgent(), getIp(r.RemoteAddr)
+	if ok, message := credentials.ValidateSignIn(); !ok {
+		h.JSONError(w, http.StatusBadRequest, message)
+		return
+	}
+	h.apiSession(w, r, func(ctx context.Context, sessionChan chan entity.SessionResult) {
+		h.auUcase.SignIn(ctx, credentials, sessionChan)
+	})
+}
+
+func (h *Handler) APITwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var input entity.TwoFactorInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Token == "" || input.Code == "" {
+		h.JSONError(w, http.StatusBadRequest, "two_factor_token and code are required")
+		return
+	}
+	req := entity.TwoFactorRequest{Token: input.Token, Code: input.Code, UserAgent: r.UserAgent(), IP: getIp(r.RemoteAddr)}
+	h.apiSession(w, r, func(ctx context.Context, sessionChan chan entity.SessionResult) {
+		h.auUcase.VerifyTwoFactor(ctx, req, sessionChan)
+	})
+}
+
+// apiSession answers with the session or the pending sign in start opens
+func (h *Handler) apiSession(w http.ResponseWriter, r *http.Request, start func(context.Context, chan entity.SessionResult)) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	sessionChan := make(chan entity.SessionResult, 1)
+	go start(ctx, sessionChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+		h.JSONError(w, http.StatusRequestTimeout, "Request Timeout")
+	case sessionRes := <-sessionChan:
+		switch {
+		case sessionRes.Err == entity.ErrLockedOut:
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(sessionRes.LockedUntil).Seconds())+1))
+			h.apiErrorResponse(w, r, sessionRes.Err)
+		case sessionRes.Err != nil:
+			h.apiErrorResponse(w, r, sessionRes.Err)
+		case sessionRes.Pending.Token != "":
+			h.JSONResponse(w, r, http.StatusAccepted, sessionRes.Pending)
+		case sessionRes.Session.Token != "":
+			session := sessionRes.Session
+			h.JSONResponse(w, r, http.StatusOK, entity.SessionOutput{Token: session.Token, UserId: session.UserId, Role: session.Role, ExpiryTime: session.ExpiryTime, Persistent: session.Persistent})
+		default:
+			h.apiErrorResponse(w, r, entity.ErrInternalServer)
+		}
+	}
+}
+
+// APISignOutHandler ends the session of the bearer token
+func (h *Handler) APISignOutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if _, ok := h.apiUserId(w, r); !ok {
+		return
+	}
+	token, _ := bearerToken(r)
+	h.apiCommand(w, r, func(ctx context.Context, errChan chan error) {
+		h.auUcase.SignOut(ctx, entity.Session{Token: token}, errChan)
+	})
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+// APIPostsHandler lists posts and creates them
+func (h *Handler) APIPostsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+			h.forumUcase.FetchPosts(ctx, entity.GetPage(r), responseChan)
+		})
+	case http.MethodPost:
+		userId, ok := h.apiUserId(w, r)
+		if !ok {
+			return
+		}
+		var input entity.PostInput
+		if !h.decodeJSON(w, r, &input) {
+			return
+		}
+		post, err := input.Post(false)
+		if err != nil {
+			h.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		post.User.Id = userId
+		h.apiStore(w, r, func(ctx context.Context, resChan chan entity.Result) {
+			h.forumUcase.StorePost(ctx, post, resChan)
+		}, func(id int) string {
+			return fmt.Sprintf("%s/posts/%d", apiPrefix, id)
+		})
+	default:
+		h.apiMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// APIPostHandler serves a post and what hangs off it: its comments and reactions
+func (h *Handler) APIPostHandler(w http.ResponseWriter, r *http.Request) {
+	postId, sub, ok := apiPath(r, "posts")
+	if !ok {
+		h.JSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	switch sub {
+	case "":
+		h.apiPost(w, r, postId)
+	case "comments":
+		h.apiCreateComment(w, r, postId)
+	case "reactions":
+		h.apiPostReaction(w, r, postId)
+	default:
+		h.JSONError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (h *Handler) apiPost(w http.ResponseWriter, r *http.Request, postId int) {
+	if r.Method == http.MethodGet {
+		h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+			h.forumUcase.FetchPost(ctx, postId, responseChan)
+		})
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		h.apiMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+		return
+	}
+	userId, ok := h.apiUserId(w, r)
+	if !ok {
+		return
+	}
+	post := entity.Post{}
+	if r.Method != http.MethodDelete {
+		var input entity.PostInput
+		if !h.decodeJSON(w, r, &input) {
+			return
+		}
+		var err error
+		if post, err = input.Post(true); err != nil {
+			h.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	post.Id, post.User.Id = postId, userId
+	h.apiCommand(w, r, func(ctx context.Context, errChan chan error) {
+		if r.Method == http.MethodDelete {
+			h.forumUcase.DeletePost(ctx, post, errChan)
+		} else {
+			h.forumUcase.UpdatePost(ctx, post, errChan)
+		}
+	})
+}
+
+func (h *Handler) apiCreateComment(w http.ResponseWriter, r *http.Request, postId int) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	userId, ok := h.apiUserId(w, r)
+	if !ok {
+		return
+	}
+	var input entity.CommentInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	comment, err := input.Comment(postId, userId)
+	if err != nil {
+		h.apiErrorResponse(w, r, err)
+		return
+	}
+	h.apiStore(w, r, func(ctx context.Context, resChan chan entity.Result) {
+		h.forumUcase.StoreComment(ctx, comment, resChan)
+	}, func(int) string {
+		return fmt.Sprintf("%s/posts/%d", apiPrefix, postId)
+	})
+}
+
+func (h *Handler) apiPostReaction(w http.ResponseWriter, r *http.Request, postId int) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	userId, ok := h.apiUserId(w, r)
+	if !ok {
+		return
+	}
+	var input entity.ReactionInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Like == nil {
+		h.JSONError(w, http.StatusBadRequest, "like is required")
+		return
+	}
+	reaction := entity.PostReaction{Reaction: entity.Reaction{Like: *input.Like, User: entity.User{Id: userId}}, Post: entity.Post{Id: postId}}
+	h.apiCommand(w, r, func(ctx context.Context, errChan chan error) {
+		h.forumUcase.PostReaction(ctx, reaction, errChan)
+	})
+}
+
+// APICommentHandler reacts to comments, the only thing done to a comment on its own
+func (h *Handler) APICommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentId, sub, ok := apiPath(r, "comments")
+	if !ok || sub != "reactions" {
+		h.JSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	userId, ok := h.apiUserId(w, r)
+	if !ok {
+		return
+	}
+	var input entity.ReactionInput
+	if !h.decodeJSON(w, r, &input) {
+		return
+	}
+	if input.Like == nil || input.PostId <= 0 {
+		h.JSONError(w, http.StatusBadRequest, "like and post_id are required")
+		return
+	}
+	reaction := entity.CommentReaction{
+		Reaction: entity.Reaction{Like: *input.Like, User: entity.User{Id: userId}},
+		Comment:  entity.Comment{Id: commentId, Post: entity.Post{Id: input.PostId}},
+	}
+	h.apiCommand(w, r, func(ctx context.Context, errChan chan error) {
+		h.forumUcase.CommentReaction(ctx, reaction, errChan)
+	})
+}
+
+func (h *Handler) APIUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	h.apiFetch(w, r, publicProfiles(h.forumUcase.FetchUsers, &[]entity.PublicUser{}))
+}
+
+func (h *Handler) APIUserHandler(w http.ResponseWriter, r *http.Request) {
+	userId, sub, ok := apiPath(r, "users")
+	if !ok || sub != "" {
+		h.JSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	h.apiFetch(w, r, publicProfiles(func(ctx context.Context, responseChan chan entity.Response) {
+		h.forumUcase.FetchUser(ctx, userId, responseChan)
+	}, &entity.PublicUser{}))
+}
+
+// publicProfiles turns the users fetch answers with into public, which has the shape of one or a list of
+// PublicUser, so that the API never hands out what only the owner may see, like the email
+func publicProfiles(fetch func(context.Context, chan entity.Response), public interface{}) func(context.Context, chan entity.Response) {
+	return func(ctx context.Context, responseChan chan entity.Response) {
+		fetched := make(chan entity.Response, 1)
+		fetch(ctx, fetched)
+		response := <-fetched
+		if response.Err == nil {
+			b, err := json.Marshal(response.Body)
+			if err == nil {
+				err = json.Unmarshal(b, public)
+			}
+			response.Body, response.Err = public, err
+		}
+		responseChan <- response
+	}
+}
+
+// APIMeHandler serves the profile of the owner of the token, the only one with the email
+func (h *Handler) APIMeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	userId, ok := h.apiUserId(w, r)
+	if !ok {
+		return
+	}
+	h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+		h.forumUcase.FetchUser(ctx, int(userId), responseChan)
+	})
+}
+
+func (h *Handler) APICategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	// admins see the archived categories too, like on the page
+	role, _ := r.Context().Value("role").(string)
+	h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+		h.forumUcase.FetchCategories(ctx, entity.HasRole(role, entity.RoleAdmin), responseChan)
+	})
+}
+
+func (h *Handler) APICategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryId, sub, ok := apiPath(r, "categories")
+	if !ok || sub != "" {
+		h.JSONError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+		h.forumUcase.FetchCategory(ctx, categoryId, entity.GetPage(r), responseChan)
+	})
+}
+
+func (h *Handler) APISearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	h.apiFetch(w, r, func(ctx context.Context, responseChan chan entity.Response) {
+		h.forumUcase.Search(ctx, entity.GetSearchQuery(r), responseChan)
+	})
+}
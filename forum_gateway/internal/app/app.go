@@ -1,65 +1,158 @@
 package app
 
 import (
+	"context"
+	"forum_gateway/internal/client"
+	"forum_gateway/internal/config"
+	"forum_gateway/internal/entity"
 	"forum_gateway/internal/usecase"
+	"forum_gateway/pkg/logger"
+	"forum_gateway/pkg/metrics"
 	"io"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Run() {
+func Run(cfg *config.Config) {
 	mux := http.NewServeMux()
-	infoLog, errLog, file := getLogs("log.txt")
-	defer file.Close()
-	auUcase := usecase.NewAuthUsecase(errLog, infoLog)
-	forumUcase := usecase.NewForumUsecase(errLog)
-	h := NewHandler(errLog, infoLog, auUcase, forumUcase)
+	logs, file := newLogger(cfg)
+	if file != nil {
+		defer file.Close()
+	}
+	infoLog, errLog := logs.Std(logger.LevelInfo), logs.Std(logger.LevelError)
+	duration = time.Duration(cfg.Timeout)
+	reg := metrics.NewRegistry()
+	upstream := reg.NewHistogram("upstream_request_duration_seconds", "Duration of the calls to forum_app and forum_auth, by service, method, path and status.",
+		metrics.DefaultBuckets, "service", "method", "path", "status")
+	authOptions, appOptions := cfg.Client.Options(cfg.AuthURL), cfg.Client.Options(cfg.AppURL)
+	authOptions.Latency, appOptions.Latency = upstream, upstream
+	auUcase := usecase.NewAuthUsecase(client.NewAuth(authOptions), errLog, infoLog)
+	forumUcase := usecase.NewForumUsecase(client.NewApp(appOptions), errLog)
+	h := NewHandler(cfg, logs, reg, auUcase, forumUcase)
 	// auth
 	mux.Handle("/sign-up", h.MultipleMiddleware(h.SignUpHandler))
 	mux.Handle("/sign-in", h.MultipleMiddleware(h.SignInHandler))
 	mux.Handle("/sign-out", h.MultipleMiddleware(h.SignOutHandler))
+	mux.Handle("/forgot-password", h.MultipleMiddleware(h.ForgotPasswordHandler))
+	mux.Handle("/reset-password", h.MultipleMiddleware(h.ResetPasswordHandler))
+	mux.Handle("/verify-email", h.MultipleMiddleware(h.VerifyEmailHandler))
+	mux.Handle("/verify-email/send", h.MultipleMiddleware(h.SendVerificationHandler))
+	mux.Handle("/two-factor", h.MultipleMiddleware(h.TwoFactorHandler))
 
-	mux.Handle("/sign-in/google", h.MultipleMiddleware(h.SignInOAuthHandler))
-	mux.HandleFunc("/callback/google", h.CallBackHandler)
-
-	mux.Handle("/sign-in/github", h.MultipleMiddleware(h.SignInOAuthHandler))
-	mux.HandleFunc("/callback/github", h.CallBackHandler)
+	mux.Handle("/sign-in/", h.MultipleMiddleware(h.SignInOAuthHandler))
+	mux.Handle("/connect/", h.MultipleMiddleware(h.ConnectHandler))
+	mux.HandleFunc("/callback/", h.CallBackHandler)
 
 	// forum
 	mux.Handle("/", h.MultipleMiddleware(h.PostsHandler))
 	mux.Handle("/posts", h.MultipleMiddleware(h.PostsHandler))
 	mux.Handle("/posts/", h.MultipleMiddleware(h.PostHandler))
 	mux.Handle("/posts/new", h.MultipleMiddleware(h.CreatePostHandler))
+	mux.Handle("/posts/edit/", h.MultipleMiddleware(h.EditPostHandler))
+	mux.Handle("/posts/delete", h.MultipleMiddleware(h.DeletePostHandler))
 	mux.Handle("/comments/new", h.MultipleMiddleware(h.CreateCommentHandler))
 	mux.Handle("/users", h.MultipleMiddleware(h.UsersHandler))
 	mux.Handle("/users/", h.MultipleMiddleware(h.UserHandler))
 	mux.Handle("/categories", h.MultipleMiddleware(h.CategoriesHandler))
 	mux.Handle("/categories/", h.MultipleMiddleware(h.CategoryHandler))
+	mux.Handle("/search", h.MultipleMiddleware(h.SearchHandler))
 	mux.Handle("/post-reactions/new", h.MultipleMiddleware(h.PostReactionHandler))
 	mux.Handle("/comment-reactions/new", h.MultipleMiddleware(h.CommentReactionHandler))
+	mux.Handle("/reports/new", h.MultipleMiddleware(h.ReportHandler))
+	mux.Handle("/notifications", h.MultipleMiddleware(h.NotificationsHandler))
+	mux.Handle("/notifications/read", h.MultipleMiddleware(h.ReadNotificationsHandler))
+	mux.Handle("/devices", h.MultipleMiddleware(h.DevicesHandler))
+	mux.Handle("/devices/revoke", h.MultipleMiddleware(h.RevokeDeviceHandler))
+	mux.Handle("/identities", h.MultipleMiddleware(h.IdentitiesHandler))
+	mux.Handle("/identities/unlink", h.MultipleMiddleware(h.UnlinkIdentityHandler))
+	mux.Handle("/security", h.MultipleMiddleware(h.SecurityHandler))
+	mux.Handle("/security/setup", h.MultipleMiddleware(h.SetupTwoFactorHandler))
+	mux.Handle("/security/enable", h.MultipleMiddleware(h.EnableTwoFactorHandler))
+	mux.Handle("/security/disable", h.MultipleMiddleware(h.DisableTwoFactorHandler))
+
+	// moderation
+	mux.Handle("/moderation", h.MultipleMiddleware(h.RequireRole(entity.RoleModerator)(h.ModerationHandler)))
+	mux.Handle("/moderation/action", h.MultipleMiddleware(h.RequireRole(entity.RoleModerator)(h.ModerateHandler)))
+	mux.Handle("/users/role", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.RoleHandler)))
+	mux.Handle("/users/two-factor/reset", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.ResetTwoFactorHandler)))
+	mux.Handle("/categories/new", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.CreateCategoryHandler)))
+	mux.Handle("/categories/update", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.UpdateCategoryHandler)))
+	mux.Handle("/categories/move", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.MoveCategoryHandler)))
+	mux.Handle("/categories/archive", h.MultipleMiddleware(h.RequireRole(entity.RoleAdmin)(h.ArchiveCategoryHandler)))
+
+	// JSON API
+	mux.Handle(apiPrefix+"/", h.API(h.APINotFoundHandler))
+	mux.HandleFunc(apiPrefix+"/openapi.yaml", h.OpenAPIHandler)
+	mux.Handle(apiPrefix+"/auth/sign-up", h.API(h.APISignUpHandler))
+	mux.Handle(apiPrefix+"/auth/sign-in", h.API(h.APISignInHandler))
+	mux.Handle(apiPrefix+"/auth/two-factor", h.API(h.APITwoFactorHandler))
+	mux.Handle(apiPrefix+"/auth/sign-out", h.API(h.APISignOutHandler))
+	mux.Handle(apiPrefix+"/me", h.API(h.APIMeHandler))
+	mux.Handle(apiPrefix+"/posts", h.API(h.APIPostsHandler))
+	mux.Handle(apiPrefix+"/posts/", h.API(h.APIPostHandler))
+	mux.Handle(apiPrefix+"/comments/", h.API(h.APICommentHandler))
+	mux.Handle(apiPrefix+"/users", h.API(h.APIUsersHandler))
+	mux.Handle(apiPrefix+"/users/", h.API(h.APIUserHandler))
+	mux.Handle(apiPrefix+"/categories", h.API(h.APICategoriesHandler))
+	mux.Handle(apiPrefix+"/categories/", h.API(h.APICategoryHandler))
+	mux.Handle(apiPrefix+"/search", h.API(h.APISearchHandler))
+
+	// probes
+	mux.HandleFunc("/healthz", h.HealthzHandler)
+	mux.HandleFunc("/readyz", h.ReadyzHandler)
 
 	mux.Handle("/templates/css/", http.StripPrefix("/templates/css/", http.FileServer(http.Dir("templates/css"))))
 	mux.Handle("/templates/img/", http.StripPrefix("/templates/img/", http.FileServer(http.Dir("templates/img"))))
 
 	srv := &http.Server{
-		Addr:    ":8082",
-		Handler: mux,
+		Addr:     cfg.Addr,
+		ErrorLog: errLog,
+		Handler:  logs.Middleware(reg.InstrumentMux(mux)),
+	}
+	go func() {
+		infoLog.Println("Listening on " + cfg.Addr)
+		if err := srv.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey); err != http.ErrServerClosed {
+			errLog.Fatal(err)
+		}
+	}()
+	// the metrics tell about routes, errors and rate limits, they get their own address kept off the public one
+	var metricsSrv *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", reg.Handler())
+		metricsSrv = &http.Server{Addr: cfg.MetricsAddr, ErrorLog: errLog, Handler: metricsMux}
+		go func() {
+			infoLog.Println("Serving metrics on " + cfg.MetricsAddr)
+			if err := metricsSrv.ListenAndServe(); err != http.ErrServerClosed {
+				errLog.Fatal(err)
+			}
+		}()
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	infoLog.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	if metricsSrv != nil {
+		metricsSrv.Close()
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		errLog.Println(err)
 	}
-	infoLog.Println("Listening on localhost:8082")
-	err := srv.ListenAndServeTLS("crt/localhost/localhost.crt", "crt/localhost/localhost.decrypted.key")
-	log.Fatal(err)
 }
 
-func getLogs(filename string) (*log.Logger, *log.Logger, *os.File) {
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Llongfile)
-	f, err := os.OpenFile("log.txt", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+// newLogger logs to stderr and to the log file, which is rotated, or only to stderr when the file can't be opened
+func newLogger(cfg *config.Config) (*logger.Logger, io.Closer) {
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	file, err := logger.OpenRotatingFile(cfg.LogFile, int64(cfg.LogMaxSize)<<20, cfg.LogMaxBackups)
 	if err != nil {
-		errorLog.Println("Log file doesn't open")
+		logs := logger.New(os.Stderr, level).With("service", "forum_gateway")
+		logs.Error(context.Background(), "Log file doesn't open", "error", err)
+		return logs, nil
 	}
-	wrt := io.MultiWriter(os.Stderr, f)
-	errorLog.SetOutput(wrt)
-	infoLog.SetOutput(wrt)
-	return infoLog, errorLog, f
+	return logger.New(io.MultiWriter(os.Stderr, file), level).With("service", "forum_gateway"), file
 }
@@ -1,12 +1,13 @@
 package app
 
 import (
+	"fmt"
 	"forum_gateway/internal/entity"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-var oauthStateString = "pseudo-random"
-
 // SIGN UP
 func (h *Handler) SignUpHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Context().Value("authorised") == true {
@@ -33,19 +34,19 @@ func (h *Handler) postSignUp(w http.ResponseWriter, r *http.Request) {
 	confirm_password := r.FormValue("confirm_password")
 	ok, message := credentials.ValidateSignUp(confirm_password)
 	if !ok {
-		h.errLog.Println(message)
+		h.logError(r.Context(), message)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: message}, "templates/registration.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	var err error
 	go h.auUcase.SignUp(ctx, credentials, errChan)
 	select {
 	case err = <-errChan:
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			switch err {
 			case entity.ErrEmailExists:
 				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "user with a given email already exists"}, "templates/registration.html")
@@ -58,7 +59,7 @@ func (h *Handler) postSignUp(w http.ResponseWriter, r *http.Request) {
 		}
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	}
@@ -88,15 +89,16 @@ func (h *Handler) getSignIn(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) postSignIn(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	credentials := entity.GetCredentials(r)
+	credentials.UserAgent, credentials.IP = r.UserAgent(), getIp(r.RemoteAddr)
 	ok, message := credentials.ValidateSignIn()
 	if !ok {
-		h.errLog.Println(message)
+		h.logError(r.Context(), message)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: message}, "templates/login.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	sessionChan := make(chan entity.SessionResult)
+	sessionChan := make(chan entity.SessionResult, 1)
 	var sessionRes entity.SessionResult
 
 	go h.auUcase.SignIn(ctx, credentials, sessionChan)
@@ -104,18 +106,20 @@ func (h *Handler) postSignIn(w http.ResponseWriter, r *http.Request) {
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case sessionRes = <-sessionChan:
 		err := sessionRes.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			switch err {
-			case entity.ErrNotFound:
-				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "User with a given email doesn't exist"}, "templates/login.html")
-			case entity.ErrInvalidPassword:
-				h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: "Invalid password"}, "templates/login.html")
+			case entity.ErrInvalidCredentials:
+				h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: "Invalid email or password"}, "templates/login.html")
+			case entity.ErrLockedOut:
+				wait := time.Until(sessionRes.LockedUntil)
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				h.APIResponse(w, http.StatusTooManyRequests, entity.Response{ErrorMessage: fmt.Sprintf("Too many failed sign in attempts, try again in %d min", int(wait.Minutes())+1)}, "templates/login.html")
 			case entity.ErrRequestTimeout:
 				h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 			default:
@@ -124,14 +128,12 @@ func (h *Handler) postSignIn(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if sessionRes.Pending.Token != "" {
+		askTwoFactor(w, r, sessionRes.Pending)
+		return
+	}
 	if sessionRes.Session.Token != "" {
-		cookie := http.Cookie{
-			Name:    "token",
-			Expires: sessionRes.Session.ExpiryTime,
-			Value:   sessionRes.Session.Token,
-			Path:    "/",
-		}
-		http.SetCookie(w, &cookie)
+		http.SetCookie(w, sessionCookie(sessionRes.Session))
 		http.Redirect(w, r, "/posts", http.StatusSeeOther)
 		return
 	}
@@ -157,12 +159,12 @@ func (h *Handler) SignOutHandler(w http.ResponseWriter, r *http.Request) {
 	session := entity.Session{Token: token}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	go h.auUcase.SignOut(ctx, session, errChan)
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case err = <-errChan:
@@ -170,6 +172,7 @@ func (h *Handler) SignOutHandler(w http.ResponseWriter, r *http.Request) {
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.go")
 		case nil:
+			http.SetCookie(w, sessionCookie(entity.Session{}))
 			http.Redirect(w, r, "/posts", 303)
 		default:
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.go")
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.forumUcase.StoreCategory, func(change entity.CategoryChange) bool {
+		return change.Title != ""
+	})
+}
+
+func (h *Handler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.forumUcase.UpdateCategory, func(change entity.CategoryChange) bool {
+		return change.Id != 0 && change.Title != ""
+	})
+}
+
+func (h *Handler) ArchiveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.changeCategory(w, r, h.forumUcase.ArchiveCategory, func(change entity.CategoryChange) bool {
+		return change.Id != 0
+	})
+}
+
+func (h *Handler) MoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	up := r.FormValue("direction") == "up"
+	move := func(ctx context.Context, change entity.CategoryChange, errChan chan error) {
+		h.forumUcase.MoveCategory(ctx, change, up, errChan)
+	}
+	h.changeCategory(w, r, move, func(change entity.CategoryChange) bool {
+		return change.Id != 0
+	})
+}
+
+// changeCategory handles the admin forms on the categories page and returns to it
+func (h *Handler) changeCategory(w http.ResponseWriter, r *http.Request, apply func(context.Context, entity.CategoryChange, chan error), valid func(entity.CategoryChange) bool) {
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	change, err := entity.GetCategoryChange(r)
+	if err != nil || !valid(change) {
+		h.logError(r.Context(), "bad request")
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go apply(ctx, change, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, "/categories")
+	}
+}
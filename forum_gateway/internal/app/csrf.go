@@ -0,0 +1,99 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+const (
+	csrfField  = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	// csrfCookie identifies visitors without a session, so that the sign in and sign up forms are protected too
+	csrfCookie = "csrf"
+)
+
+// csrfWriter hands the token of the request over to APIResponse, which puts it into the forms
+type csrfWriter struct {
+	http.ResponseWriter
+	token string
+}
+
+// CSRF rejects state changing requests that don't carry the synchronizer token of their session
+func (h *Handler) CSRF(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := csrfSessionId(w, r)
+		if err != nil {
+			h.logError(r.Context(), err)
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+			return
+		}
+		token := h.csrfToken(id)
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			sent := r.Header.Get(csrfHeader)
+			if sent == "" {
+				sent = r.FormValue(csrfField)
+			}
+			if !hmac.Equal([]byte(sent), []byte(token)) {
+				h.logError(r.Context(), fmt.Sprintf("invalid csrf token: %s %s", r.Method, r.URL.Path))
+				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+				return
+			}
+		}
+		next.ServeHTTP(&csrfWriter{ResponseWriter: w, token: token}, r)
+	})
+}
+
+// csrfSessionId is the session token for signed in users and the csrf cookie, set on first visit, for the rest
+func csrfSessionId(w http.ResponseWriter, r *http.Request) (string, error) {
+	if r.Context().Value("authorised") == true {
+		if cookie, err := r.Cookie("token"); err == nil {
+			return cookie.Value, nil
+		}
+	}
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	id, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id, nil
+}
+
+func (h *Handler) csrfToken(id string) string {
+	mac := hmac.New(sha256.New, h.csrfKey)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newCSRFKey uses the configured secret, without one tokens are only valid until the gateway restarts
+func newCSRFKey(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	key, err := randomHex(32)
+	return []byte(key), err
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
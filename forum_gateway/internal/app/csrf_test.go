@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"forum_gateway/internal/config"
+	"forum_gateway/pkg/logger"
+	"forum_gateway/pkg/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// inModuleRoot runs the test from the root of the module, where the error page template is
+func inModuleRoot(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestCSRFToken(t *testing.T) {
+	h := &Handler{csrfKey: []byte("key")}
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("visitor"))
+	if got, want := h.csrfToken("visitor"), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("csrfToken = %s, want the HMAC-SHA256 of the id, %s", got, want)
+	}
+	if h.csrfToken("visitor") == h.csrfToken("other") {
+		t.Error("two ids share a token")
+	}
+	if h.csrfToken("visitor") == (&Handler{csrfKey: []byte("another key")}).csrfToken("visitor") {
+		t.Error("two keys give the same token")
+	}
+}
+
+func TestNewCSRFKey(t *testing.T) {
+	key, err := newCSRFKey("configured")
+	if err != nil || string(key) != "configured" {
+		t.Errorf("newCSRFKey(configured) = %q, %v", key, err)
+	}
+	a, errA := newCSRFKey("")
+	b, errB := newCSRFKey("")
+	if errA != nil || errB != nil || len(a) != 64 || string(a) == string(b) {
+		t.Errorf("random keys %q and %q, %v %v", a, b, errA, errB)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	inModuleRoot(t)
+	cfg := config.Default()
+	cfg.CSRFSecret = "key"
+	h := NewHandler(cfg, logger.New(io.Discard, logger.LevelError), metrics.NewRegistry(), nil, nil)
+	visitor, session := h.csrfToken("visitor"), h.csrfToken("session")
+	tests := []struct {
+		name     string
+		method   string
+		signedIn bool
+		cookies  map[string]string
+		header   string
+		field    string
+		want     int
+		// token is the one handed to the page, empty when the request is refused
+		token string
+	}{
+		{name: "first visit", method: http.MethodGet, want: http.StatusOK},
+		{name: "read without token", method: http.MethodGet, cookies: map[string]string{csrfCookie: "visitor"}, want: http.StatusOK, token: visitor},
+		{name: "head without token", method: http.MethodHead, cookies: map[string]string{csrfCookie: "visitor"}, want: http.StatusOK, token: visitor},
+		{name: "post without token", method: http.MethodPost, cookies: map[string]string{csrfCookie: "visitor"}, want: http.StatusForbidden},
+		{name: "post without cookie", method: http.MethodPost, header: visitor, want: http.StatusForbidden},
+		{name: "wrong token", method: http.MethodPost, cookies: map[string]string{csrfCookie: "visitor"}, header: session, want: http.StatusForbidden},
+		{name: "token in the header", method: http.MethodPost, cookies: map[string]string{csrfCookie: "visitor"}, header: visitor, want: http.StatusOK, token: visitor},
+		{name: "token in the form", method: http.MethodPost, cookies: map[string]string{csrfCookie: "visitor"}, field: visitor, want: http.StatusOK, token: visitor},
+		{name: "token cut short", method: http.MethodDelete, cookies: map[string]string{csrfCookie: "visitor"}, header: visitor[:63], want: http.StatusForbidden},
+		{name: "signed in", method: http.MethodPost, signedIn: true, cookies: map[string]string{"token": "session", csrfCookie: "visitor"}, field: session, want: http.StatusOK, token: session},
+		{name: "signed in with the token of the visit", method: http.MethodPost, signedIn: true, cookies: map[string]string{"token": "session", csrfCookie: "visitor"}, field: visitor, want: http.StatusForbidden},
+		{name: "session cookie not signed in", method: http.MethodPost, cookies: map[string]string{"token": "session", csrfCookie: "visitor"}, field: session, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.field != "" {
+				form.Set(csrfField, tt.field)
+			}
+			r := httptest.NewRequest(tt.method, "/post/create", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				r.Header.Set(csrfHeader, tt.header)
+			}
+			for name, value := range tt.cookies {
+				r.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			r = r.WithContext(context.WithValue(r.Context(), "authorised", tt.signedIn))
+			var token string
+			called := false
+			rec := httptest.NewRecorder()
+			h.CSRF(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				token = w.(*csrfWriter).token
+			})(rec, r)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if called != (tt.want == http.StatusOK) {
+				t.Fatalf("next called = %v", called)
+			}
+			if tt.token != "" && token != tt.token {
+				t.Errorf("token = %s, want %s", token, tt.token)
+			}
+			set := rec.Result().Cookies()
+			if tt.cookies[csrfCookie] == "" {
+				// a visitor without the cookie gets one, with the token that belongs to it
+				if len(set) != 1 || set[0].Name != csrfCookie || !set[0].HttpOnly || !set[0].Secure {
+					t.Fatalf("cookies set = %v", set)
+				}
+				if called && token != h.csrfToken(set[0].Value) {
+					t.Error("the token doesn't belong to the new cookie")
+				}
+			} else if len(set) != 0 {
+				t.Errorf("cookies set = %v", set)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"forum_gateway/internal/entity"
+	"net/http"
+	"strconv"
+)
+
+// DEVICES
+func (h *Handler) DevicesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	response := entity.Response{}
+	responseChan := make(chan entity.Response, 1)
+	go h.auUcase.FetchSessions(ctx, cookie.Value, responseChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case response = <-responseChan:
+		switch response.Err {
+		case nil:
+			response.Body = map[string]interface{}{"sessions": response.Body}
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, http.StatusOK, response, "templates/devices.html")
+		case entity.ErrForbidden:
+			h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		case entity.ErrRequestTimeout:
+			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		default:
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		}
+	}
+}
+
+// RevokeDeviceHandler signs out the session with the id of the form, or every other session when others is set
+func (h *Handler) RevokeDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	others := r.FormValue("others") != ""
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if !others && (err != nil || id <= 0) {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	if others {
+		go h.auUcase.RevokeOtherSessions(ctx, cookie.Value, errChan)
+	} else {
+		go h.auUcase.RevokeSession(ctx, entity.SessionRevoke{Token: cookie.Value, Id: id}, errChan)
+	}
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, "/devices")
+	}
+}
@@ -21,12 +21,12 @@ func (h *Handler) PostsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
-	go h.forumUcase.FetchPosts(ctx, responseChan)
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchPosts(ctx, entity.GetPage(r), responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -36,16 +36,10 @@ func (h *Handler) PostsHandler(w http.ResponseWriter, r *http.Request) {
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		case entity.ErrBadRequest:
+			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/index.html")
 		}
 	}
@@ -58,19 +52,19 @@ func (h *Handler) PostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	post_id, err := getID(r.URL.String(), "posts")
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(r.Context(), err)
 		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
+	responseChan := make(chan entity.Response, 1)
 	go h.forumUcase.FetchPost(ctx, post_id, responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -83,15 +77,7 @@ func (h *Handler) PostHandler(w http.ResponseWriter, r *http.Request) {
 		case entity.ErrNotFound:
 			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/post.html")
 		}
 	}
@@ -116,12 +102,12 @@ func (h *Handler) getCreatePost(w http.ResponseWriter, r *http.Request, errMessa
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
-	go h.forumUcase.FetchCategories(ctx, responseChan)
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchCategories(ctx, false, responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -132,15 +118,7 @@ func (h *Handler) getCreatePost(w http.ResponseWriter, r *http.Request, errMessa
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "template/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			if errMessage != "" {
 				response.ErrorMessage = errMessage
 			}
@@ -160,13 +138,13 @@ func (h *Handler) postCreatePost(w http.ResponseWriter, r *http.Request) {
 	post.User.Id = id.(int64)
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	resChan := make(chan entity.Result)
+	resChan := make(chan entity.Result, 1)
 	var res entity.Result
 	go h.forumUcase.StorePost(ctx, post, resChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case res = <-resChan:
@@ -184,6 +162,152 @@ func (h *Handler) postCreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *Handler) EditPostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	post_id, err := getID(r.URL.String(), "edit")
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.getEditPost(w, r, post_id, "")
+	case http.MethodPost:
+		h.postEditPost(w, r, post_id)
+	default:
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+	}
+}
+
+func (h *Handler) getEditPost(w http.ResponseWriter, r *http.Request, post_id int, errMessage string) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	response := entity.Response{}
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchPost(ctx, post_id, responseChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case response = <-responseChan:
+		err := response.Err
+		switch err {
+		case entity.ErrInternalServer:
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		case entity.ErrRequestTimeout:
+			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		case entity.ErrNotFound:
+			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
+		case nil:
+			h.setUserInfo(r, &response)
+			if !isAuthor(response.Body, response.UserId) {
+				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+				return
+			}
+			if errMessage != "" {
+				response.ErrorMessage = errMessage
+			}
+			h.APIResponse(w, http.StatusOK, response, "templates/edit_post.html")
+		}
+	}
+}
+
+func (h *Handler) postEditPost(w http.ResponseWriter, r *http.Request, post_id int) {
+	r.ParseForm()
+	post, err := entity.GetPostUpdate(r)
+	if err != nil {
+		h.getEditPost(w, r, post_id, err.Error())
+		return
+	}
+	var id interface{} = r.Context().Value("user_id")
+	post.Id = post_id
+	post.User.Id = id.(int64)
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.forumUcase.UpdatePost(ctx, post, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, fmt.Sprintf("/posts/%d", post.Id))
+	}
+}
+
+func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	post_id, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	var id interface{} = r.Context().Value("user_id")
+	post := entity.Post{Id: post_id, User: entity.User{Id: id.(int64)}}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.forumUcase.DeletePost(ctx, post, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, "/posts")
+	}
+}
+
+func (h *Handler) postModificationResponse(w http.ResponseWriter, r *http.Request, err error, redirect string) {
+	switch err {
+	case nil:
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+	case entity.ErrBadRequest:
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+	case entity.ErrForbidden:
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+	case entity.ErrNotFound:
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
+	case entity.ErrRequestTimeout:
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	default:
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+	}
+}
+
+// isAuthor reports whether the post in a decoded forum_app response belongs to the user
+func isAuthor(body interface{}, userId int64) bool {
+	post, ok := body.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	user, ok := post["user"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	id, ok := user["id"].(float64)
+	return ok && userId != 0 && int64(id) == userId
+}
+
 func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Context().Value("authorised") == false {
 		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
@@ -196,7 +320,7 @@ func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	commentRes := entity.GetComment(r)
 	if commentRes.Err != nil {
-		h.errLog.Println(commentRes.Err)
+		h.logError(r.Context(), commentRes.Err)
 		if commentRes.Err == entity.ErrEmptyComment {
 			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Empty comment"}, "templates/errors.html")
 		} else {
@@ -207,13 +331,13 @@ func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	resChan := make(chan entity.Result)
+	resChan := make(chan entity.Result, 1)
 	var res entity.Result
 	go h.forumUcase.StoreComment(ctx, commentRes.Comment, resChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case res = <-resChan:
@@ -222,6 +346,10 @@ func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
 		case nil:
 			http.Redirect(w, r, fmt.Sprintf("/posts/%d", commentRes.Comment.Post.Id), 303)
+		case entity.ErrForbidden:
+			h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Post is locked"}, "templates/errors.html")
+		case entity.ErrNotFound:
+			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		default:
@@ -238,12 +366,12 @@ func (h *Handler) UsersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
+	responseChan := make(chan entity.Response, 1)
 	go h.forumUcase.FetchUsers(ctx, responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -254,15 +382,7 @@ func (h *Handler) UsersHandler(w http.ResponseWriter, r *http.Request) {
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "template/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/users.html")
 		}
 	}
@@ -275,19 +395,19 @@ func (h *Handler) UserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	user_id, err := getID(r.URL.String(), "users")
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(r.Context(), err)
 		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
+	responseChan := make(chan entity.Response, 1)
 	go h.forumUcase.FetchUser(ctx, user_id, responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -300,15 +420,7 @@ func (h *Handler) UserHandler(w http.ResponseWriter, r *http.Request) {
 		case entity.ErrNotFound:
 			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/user.html")
 		}
 	}
@@ -322,12 +434,14 @@ func (h *Handler) CategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
-	go h.forumUcase.FetchCategories(ctx, responseChan)
+	responseChan := make(chan entity.Response, 1)
+	// admins manage archived categories on the same page
+	role, _ := r.Context().Value("role").(string)
+	go h.forumUcase.FetchCategories(ctx, entity.HasRole(role, entity.RoleAdmin), responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -338,15 +452,7 @@ func (h *Handler) CategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "template/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/categories.html")
 		}
 	}
@@ -357,21 +463,21 @@ func (h *Handler) CategoryHandler(w http.ResponseWriter, r *http.Request) {
 		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
 		return
 	}
-	category_id, err := getID(r.URL.String(), "categories")
+	category_id, err := getID(r.URL.Path, "categories")
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(r.Context(), err)
 		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
 	response := entity.Response{}
-	responseChan := make(chan entity.Response)
-	go h.forumUcase.FetchCategory(ctx, category_id, responseChan)
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchCategory(ctx, category_id, entity.GetPage(r), responseChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case response = <-responseChan:
@@ -381,18 +487,12 @@ func (h *Handler) CategoryHandler(w http.ResponseWriter, r *http.Request) {
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
 		case entity.ErrRequestTimeout:
 			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		case entity.ErrBadRequest:
+			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
 		case entity.ErrNotFound:
 			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
 		case nil:
-			var (
-				auth interface{} = r.Context().Value("authorised")
-				id   interface{} = r.Context().Value("user_id")
-			)
-			response.AuthStatus, _ = auth.(bool)
-			user_id, ok := id.(int64)
-			if ok {
-				response.UserId = user_id
-			}
+			h.setUserInfo(r, &response)
 			h.APIResponse(w, http.StatusOK, response, "templates/category.html")
 		}
 	}
@@ -418,18 +518,18 @@ func (h *Handler) PostReactionHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	postReaction, err := entity.GetPostReaction(r)
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(r.Context(), err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{}, "templates/errors.go")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	go h.forumUcase.PostReaction(ctx, postReaction, errChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case err = <-errChan:
@@ -451,18 +551,18 @@ func (h *Handler) CommentReactionHandler(w http.ResponseWriter, r *http.Request)
 	r.ParseForm()
 	commentReaction, err := entity.GetCommentReaction(r)
 	if err != nil {
-		h.errLog.Println(err)
+		h.logError(r.Context(), err)
 		h.APIResponse(w, http.StatusBadRequest, entity.Response{}, "templates/errors.html")
 		return
 	}
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	go h.forumUcase.CommentReaction(ctx, commentReaction, errChan)
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case err = <-errChan:
@@ -470,7 +570,7 @@ func (h *Handler) CommentReactionHandler(w http.ResponseWriter, r *http.Request)
 		case nil:
 			http.Redirect(w, r, fmt.Sprintf("/posts/%d#%d", commentReaction.Post.Id, commentReaction.Comment.Id), 303)
 		default:
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
 		}
 	}
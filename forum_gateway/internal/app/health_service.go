@@ -0,0 +1,34 @@
+package app
+
+import (
+	"net/http"
+)
+
+// HealthzHandler answers as long as the process serves requests
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	h.JSONResponse(w, r, http.StatusOK, "ok")
+}
+
+// ReadyzHandler answers once forum_app and forum_auth are both ready
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	appChan, authChan := make(chan error, 1), make(chan error, 1)
+	go h.forumUcase.Ready(ctx, appChan)
+	go h.auUcase.Ready(ctx, authChan)
+	for _, errChan := range []chan error{appChan, authChan} {
+		select {
+		case <-ctx.Done():
+			h.logError(ctx, ctx.Err())
+			h.JSONError(w, http.StatusServiceUnavailable, "Service Unavailable")
+			return
+		case err := <-errChan:
+			if err != nil {
+				h.logError(ctx, err)
+				h.JSONError(w, http.StatusServiceUnavailable, "Service Unavailable")
+				return
+			}
+		}
+	}
+	h.JSONResponse(w, r, http.StatusOK, "ok")
+}
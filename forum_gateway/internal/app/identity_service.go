@@ -0,0 +1,106 @@
+package app
+
+import (
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+// IDENTITIES
+func (h *Handler) IdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	response := entity.Response{}
+	responseChan := make(chan entity.Response, 1)
+	go h.auUcase.FetchIdentities(ctx, cookie.Value, responseChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case response = <-responseChan:
+		switch response.Err {
+		case nil:
+			response.Body = h.groupIdentities(response.Body)
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, http.StatusOK, response, "templates/identities.html")
+		case entity.ErrForbidden:
+			h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		case entity.ErrRequestTimeout:
+			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		default:
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		}
+	}
+}
+
+// groupIdentities keys the linked identities by provider, those of providers that are no longer configured
+// are listed apart so they can still be disconnected
+func (h *Handler) groupIdentities(body interface{}) map[string]interface{} {
+	linked, unavailable := map[string]interface{}{}, []interface{}{}
+	identities, _ := body.([]interface{})
+	for _, i := range identities {
+		identity, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		provider, _ := identity["provider"].(string)
+		if _, ok = h.oauths[provider]; ok {
+			linked[provider] = identity
+		} else {
+			unavailable = append(unavailable, identity)
+		}
+	}
+	return map[string]interface{}{"linked": linked, "unavailable": unavailable}
+}
+
+// UnlinkIdentityHandler disconnects the account of the provider of the form from the user
+func (h *Handler) UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	provider := r.FormValue("provider")
+	if provider == "" {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.UnlinkIdentity(ctx, entity.IdentityRequest{Token: cookie.Value, Provider: provider}, errChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err = <-errChan:
+		if err == entity.ErrLastIdentity {
+			h.APIResponse(w, http.StatusConflict, entity.Response{ErrorMessage: "This is the only way to sign in to your account. Set a password by resetting it, or connect another account first"}, "templates/errors.html")
+			return
+		}
+		h.postModificationResponse(w, r, err, "/identities")
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"forum_gateway/internal/config"
+	"forum_gateway/pkg/logger"
+	"forum_gateway/pkg/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The requests the limiter turns away are counted apart for the pages and the API, per client address
+func TestRateLimitMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	h := NewHandler(config.Default(), logger.New(io.Discard, logger.LevelError), reg, nil, nil)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	web, api := h.RateLimit(ok), h.APIRateLimit(ok)
+	send := func(handler http.HandlerFunc, remote string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+	// the burst is 5, the pages and the API share it
+	for i := 0; i < 5; i++ {
+		if code := send(web, "192.0.2.1:1000"); code != http.StatusOK {
+			t.Fatalf("request %d = %d", i, code)
+		}
+	}
+	if code := send(web, "192.0.2.1:1001"); code != http.StatusTooManyRequests {
+		t.Errorf("page over the burst = %d", code)
+	}
+	if code := send(api, "192.0.2.1:1002"); code != http.StatusTooManyRequests {
+		t.Errorf("API over the burst = %d", code)
+	}
+	if code := send(api, "192.0.2.2:1000"); code != http.StatusOK {
+		t.Errorf("another address = %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`rate_limit_rejections_total{scope="web"} 1`,
+		`rate_limit_rejections_total{scope="api"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, rec.Body)
+		}
+	}
+}
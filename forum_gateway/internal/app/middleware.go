@@ -3,8 +3,9 @@ package app
 import (
 	"context"
 	"forum_gateway/internal/entity"
+	"net"
 	"net/http"
-	"strings"
+	"time"
 )
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
@@ -20,29 +21,25 @@ func (h *Handler) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 		ctx, cancel := getTimeout(r.Context())
 		defer cancel()
 
-		authResChan := make(chan entity.AuthStatusResult)
+		authResChan := make(chan entity.AuthStatusResult, 1)
 		go h.auUcase.Authenticate(ctx, cookier.Value, authResChan)
 		select {
 		case authRes := <-authResChan:
 			if authRes.Status == entity.Authorised {
-				cookie := http.Cookie{
-					Name:  "token",
-					Value: authRes.Session.Token,
-					Path:  "/",
-				}
-				http.SetCookie(w, &cookie)
+				http.SetCookie(w, sessionCookie(authRes.Session))
 				ctx := context.WithValue(context.WithValue(r.Context(), "authorised", true), "user_id", authRes.Session.UserId)
+				ctx = context.WithValue(ctx, "role", authRes.Session.Role)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			} else {
 				if authRes.Err != nil {
-					h.errLog.Println(authRes.Err)
+					h.logError(ctx, authRes.Err)
 				}
 				ctx := context.WithValue(r.Context(), "authorised", false)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			}
 		case <-ctx.Done():
 			err = ctx.Err()
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			ctx := context.WithValue(r.Context(), "authorised", false)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
@@ -50,10 +47,74 @@ func (h *Handler) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	})
 }
 
+// sessionCookie carries the token of the session and expires together with it, an empty session clears it
+func sessionCookie(session entity.Session) *http.Cookie {
+	maxAge := int(time.Until(session.ExpiryTime).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	return &http.Cookie{
+		Name:     "token",
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiryTime,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// RequireRole lets through only signed in users whose session role has at least the powers of role
+func (h *Handler) RequireRole(role string) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userRole, _ := r.Context().Value("role").(string)
+			if r.Context().Value("authorised") != true || !entity.HasRole(userRole, role) {
+				h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// countUnread asks for the counter shown in the header, a failure only hides the counter
+func (h *Handler) countUnread(ctx context.Context, userId int64) int {
+	ctx, cancel := getTimeout(ctx)
+	defer cancel()
+	unreadChan := make(chan entity.UnreadResult, 1)
+	go h.forumUcase.CountUnread(ctx, userId, unreadChan)
+	select {
+	case <-ctx.Done():
+		h.logError(ctx, ctx.Err())
+	case unreadRes := <-unreadChan:
+		if unreadRes.Err == nil {
+			return unreadRes.Unread
+		}
+		h.logError(ctx, unreadRes.Err)
+	}
+	return 0
+}
+
+// setUserInfo copies what Authenticate learnt about the user into the template data,
+// the unread counter is only asked for here since only rendered pages show it
+func (h *Handler) setUserInfo(r *http.Request, response *entity.Response) {
+	response.AuthStatus, _ = r.Context().Value("authorised").(bool)
+	if userId, ok := r.Context().Value("user_id").(int64); ok {
+		response.UserId = userId
+	}
+	response.Role, _ = r.Context().Value("role").(string)
+	if response.AuthStatus {
+		response.Unread = h.countUnread(r.Context(), response.UserId)
+	}
+}
+
 func (h *Handler) RateLimit(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		limiter := h.rateLimiter.GetLimiter(getIp(r.RemoteAddr))
 		if !limiter.Allow() {
+			h.rateLimited.Inc("web")
 			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 			return
 		}
@@ -61,9 +122,14 @@ func (h *Handler) RateLimit(next http.HandlerFunc) http.HandlerFunc {
 	})
 }
 
+// getIp is the host of a remote address, "[2001:db8::1]:443" is 2001:db8::1 and not the first group,
+// which would put whole IPv6 blocks under one rate limiter and one sign in failure counter
 func getIp(address string) string {
-	res := strings.Split(address, ":")
-	return res[0]
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
 }
 
 func (h *Handler) MultipleMiddleware(hf http.HandlerFunc) http.HandlerFunc {
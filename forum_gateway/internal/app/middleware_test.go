@@ -0,0 +1,21 @@
+package app
+
+import "testing"
+
+func TestGetIp(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"192.0.2.1:443", "192.0.2.1"},
+		{"[2001:db8::1]:443", "2001:db8::1"},
+		{"[2001:db8::2]:51000", "2001:db8::2"},
+		{"[::1]:8082", "::1"},
+		{"192.0.2.1", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		if got := getIp(tt.address); got != tt.want {
+			t.Errorf("getIp(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
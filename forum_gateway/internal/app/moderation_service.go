@@ -0,0 +1,98 @@
+package app
+
+import (
+	"fmt"
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) ModerationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	moderatorId, _ := r.Context().Value("user_id").(int64)
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	response := entity.Response{}
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchModerationQueue(ctx, moderatorId, responseChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case response = <-responseChan:
+		switch response.Err {
+		case entity.ErrForbidden:
+			h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		case entity.ErrRequestTimeout:
+			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		case nil:
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, http.StatusOK, response, "templates/moderation.html")
+		default:
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		}
+	}
+}
+
+func (h *Handler) ModerateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	action, err := entity.GetModerationAction(r)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: err.Error()}, "templates/errors.html")
+		return
+	}
+	// actions taken from a post page return to it unless the post is gone
+	redirect := "/moderation"
+	if r.FormValue("back") == "post" && action.Target == "post" && action.Action != "delete" {
+		redirect = fmt.Sprintf("/posts/%d", action.TargetId)
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.forumUcase.Moderate(ctx, action, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, redirect)
+	}
+}
+
+func (h *Handler) RoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	update, err := entity.GetRoleUpdate(r)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.forumUcase.UpdateRole(ctx, update, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, fmt.Sprintf("/users/%d", update.UserId))
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"forum_gateway/internal/entity"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		var err error
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+			return
+		}
+	}
+	userId, _ := r.Context().Value("user_id").(int64)
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	response := entity.Response{}
+	responseChan := make(chan entity.Response, 1)
+	go h.forumUcase.FetchNotifications(ctx, userId, offset, responseChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case response = <-responseChan:
+		switch response.Err {
+		case entity.ErrRequestTimeout:
+			h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		case nil:
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, http.StatusOK, response, "templates/notifications.html")
+		default:
+			h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		}
+	}
+}
+
+func (h *Handler) ReadNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	read, err := entity.GetNotificationRead(r)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.forumUcase.MarkNotificationsRead(ctx, read, errChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case err = <-errChan:
+		h.postModificationResponse(w, r, err, "/notifications")
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"forum_gateway/internal/config"
 	"forum_gateway/internal/entity"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -17,56 +19,103 @@ func (h *Handler) SignInOAuthHandler(w http.ResponseWriter, r *http.Request) {
 		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
 		return
 	}
-	method := getOAuthMethod(r.URL.String())
-	if method == invalid {
-		h.errLog.Println(errors.New("invalid OAuth method"))
-		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
+	h.redirectToProvider(w, r, "/sign-in/", false, http.StatusTemporaryRedirect)
+}
+
+// ConnectHandler sends the signed in user to the provider to link their account there to the forum one
+func (h *Handler) ConnectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
 		return
 	}
-	url := h.oauths[method].AuthUrl()
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	h.redirectToProvider(w, r, "/connect/", true, http.StatusSeeOther)
 }
 
-func getOAuthMethod(url string) method {
-	temp := strings.Split(strings.Split(url, "?")[0], "/")
+// redirectToProvider starts the authorization at the provider named by the path after prefix
+func (h *Handler) redirectToProvider(w http.ResponseWriter, r *http.Request, prefix string, link bool, code int) {
+	name, oauth, ok := h.getOAuth(r.URL.Path, prefix)
+	if !ok {
+		h.logError(r.Context(), errors.New("invalid OAuth provider"))
+		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
+		return
+	}
+	state, err := newOAuthState(name, oauth.PKCE(), link)
+	if err == nil {
+		err = h.setOAuthState(w, state)
+	}
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+		return
+	}
+	authUrl, err := oauth.AuthUrl(state)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadGateway, entity.Response{ErrorMessage: fmt.Sprintf("%s is not available, please try again later", oauth.Name())}, "templates/errors.html")
+		return
+	}
+	http.Redirect(w, r, authUrl, code)
+}
 
-	switch temp[len(temp)-1] {
-	case "google":
-		return google
-	case "github":
-		return github
-	default:
-		return invalid
+// getOAuth finds the provider named by the last segment of the path
+func (h *Handler) getOAuth(path, prefix string) (string, OAuth, bool) {
+	name := strings.TrimPrefix(path, prefix)
+	if name == "" || strings.Contains(name, "/") {
+		return "", nil, false
 	}
+	oauth, ok := h.oauths[name]
+	return name, oauth, ok
 }
 
 func (h *Handler) CallBackHandler(w http.ResponseWriter, r *http.Request) {
-	method := getOAuthMethod(r.URL.String())
-	if method == invalid {
-		h.errLog.Println("invalid OAuth method")
+	name, oauth, ok := h.getOAuth(r.URL.Path, "/callback/")
+	if !ok {
+		h.logError(r.Context(), "invalid OAuth provider")
 		h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "Not Found"}, "templates/errors.html")
 		return
 	}
-	if r.FormValue("state") != h.oauths[method].State() {
-		h.errLog.Println("invalid OAuth state")
-		http.Redirect(w, r, "/sign-up", http.StatusTemporaryRedirect)
+	state, err := h.popOAuthState(w, r, name)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "The sign in link is invalid or has expired, please try again"}, "templates/errors.html")
 		return
 	}
-	tokenRes := h.oauths[method].Token(r.FormValue("code"))
+	if providerErr := r.FormValue("error"); providerErr != "" {
+		h.logError(r.Context(), fmt.Sprintf("%s OAuth error: %s", name, providerErr))
+		h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: fmt.Sprintf("Sign in with %s was cancelled", oauth.Name())}, "templates/errors.html")
+		return
+	}
+	tokenRes := oauth.Token(r.FormValue("code"), state)
+	if tokenRes.Error == nil && tokenRes.Token.AccessToken == "" {
+		tokenRes.Error = errors.New("empty OAuth access token")
+	}
 	if tokenRes.Error != nil {
-		h.errLog.Println(tokenRes.Error)
-		http.Redirect(w, r, "/sign-up", http.StatusTemporaryRedirect)
+		h.logError(r.Context(), tokenRes.Error)
+		h.APIResponse(w, http.StatusBadGateway, entity.Response{ErrorMessage: fmt.Sprintf("Could not sign in with %s, please try again", oauth.Name())}, "templates/errors.html")
 		return
 	}
-	credsRes := h.oauths[method].Credentials(tokenRes.Token)
+	credsRes := oauth.Credentials(tokenRes.Token)
+	if credsRes.Err == nil && credsRes.Credentials.Subject == "" {
+		credsRes.Err = errors.New("OAuth account without id")
+	}
 	if credsRes.Err != nil {
-		h.errLog.Println(credsRes.Err)
-		http.Redirect(w, r, "/sign-up", http.StatusTemporaryRedirect)
+		h.logError(r.Context(), credsRes.Err)
+		h.APIResponse(w, http.StatusBadGateway, entity.Response{ErrorMessage: fmt.Sprintf("Could not get your account from %s", oauth.Name())}, "templates/errors.html")
+		return
+	}
+	credsRes.Credentials.Provider = name
+	if state.Link {
+		h.linkIdentity(w, r, oauth, credsRes.Credentials)
 		return
 	}
+	credsRes.Credentials.UserAgent, credsRes.Credentials.IP = r.UserAgent(), getIp(r.RemoteAddr)
 	ctx, cancel := getTimeout(r.Context())
 	defer cancel()
-	sessionChan := make(chan entity.SessionResult)
+	sessionChan := make(chan entity.SessionResult, 1)
 	var sessionRes entity.SessionResult
 
 	go h.auUcase.OAuth(ctx, credsRes.Credentials, sessionChan)
@@ -74,39 +123,71 @@ func (h *Handler) CallBackHandler(w http.ResponseWriter, r *http.Request) {
 	select {
 	case <-ctx.Done():
 		err := ctx.Err()
-		h.errLog.Println(err)
+		h.logError(ctx, err)
 		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
 		return
 	case sessionRes = <-sessionChan:
 		err := sessionRes.Err
 		if err != nil {
-			h.errLog.Println(err)
+			h.logError(ctx, err)
 			switch err {
 			case entity.ErrRequestTimeout:
 				h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+			case entity.ErrIdentityConflict, entity.ErrIdentityLinked:
+				h.APIResponse(w, http.StatusConflict, entity.Response{ErrorMessage: fmt.Sprintf("An account with the email of your %s account already exists. Sign in to it and connect %s from your profile", oauth.Name(), oauth.Name())}, "templates/errors.html")
+			case entity.ErrNotFound:
+				h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: fmt.Sprintf("Your %s account has no verified email. Sign up and connect %s from your profile", oauth.Name(), oauth.Name())}, "templates/errors.html")
 			default:
 				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
 			}
 			return
 		}
 	}
+	if sessionRes.Pending.Token != "" {
+		askTwoFactor(w, r, sessionRes.Pending)
+		return
+	}
 	if sessionRes.Session.Token != "" {
-		cookie := http.Cookie{
-			Name:    "token",
-			Expires: sessionRes.Session.ExpiryTime,
-			Value:   sessionRes.Session.Token,
-			Path:    "/",
-		}
-		http.SetCookie(w, &cookie)
+		http.SetCookie(w, sessionCookie(sessionRes.Session))
 		http.Redirect(w, r, "/posts", http.StatusSeeOther)
 		return
 	}
-	h.errLog.Println("internal server error")
+	h.logError(ctx, "internal server error")
 	h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
 }
 
+// linkIdentity connects the account of the callback to the user of the session cookie, the callback isn't
+// behind the middlewares so forum_auth checks the session
+func (h *Handler) linkIdentity(w http.ResponseWriter, r *http.Request, oauth OAuth, creds entity.Credentials) {
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.LinkIdentity(ctx, entity.IdentityRequest{Token: cookie.Value, Credentials: creds}, errChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err = <-errChan:
+		if err == entity.ErrIdentityLinked {
+			h.logError(ctx, err)
+			h.APIResponse(w, http.StatusConflict, entity.Response{ErrorMessage: fmt.Sprintf("This %s account is already connected to another forum account, or another %s account is connected to yours", oauth.Name(), oauth.Name())}, "templates/errors.html")
+			return
+		}
+		h.postModificationResponse(w, r, err, "/identities")
+	}
+}
+
 type Token struct {
 	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token,omitempty"`
+	// claims of the validated ID token, OpenID Connect only
+	claims *idTokenClaims
 }
 
 type TokenResult struct {
@@ -116,72 +197,77 @@ type TokenResult struct {
 
 //oauth
 type OAuth interface {
-	Token(code string) TokenResult
+	// Name is shown to users
+	Name() string
+	// AuthUrl sends the browser to the provider with the state, nonce and PKCE challenge of the sign in
+	AuthUrl(state oauthState) (string, error)
+	// Token exchanges the code of the callback
+	Token(code string, state oauthState) TokenResult
 	Credentials(token Token) entity.CredentialsResult
-	AuthUrl() string
-	State() string
+	// PKCE reports whether the provider supports PKCE
+	PKCE() bool
 }
 
-func NewOAuth(clientId, clientSecret, state string, m method) (OAuth, error) {
-	switch m {
-	case github:
-		return NewGitHub(clientId, clientSecret, state), nil
-	case google:
-		return NewGoogle(clientId, clientSecret, state), nil
+// NewOAuth builds the provider of the type of cfg, the provider redirects back to redirectURI
+func NewOAuth(cfg config.OAuthConfig, redirectURI string) (OAuth, error) {
+	switch cfg.Type {
+	case config.OAuthGitHub:
+		return NewGitHub(cfg, redirectURI), nil
+	case config.OAuthGoogle:
+		return NewGoogle(cfg, redirectURI), nil
+	case config.OAuthOIDC:
+		return NewOIDC(cfg, redirectURI), nil
 	default:
-		return nil, errors.New("invalid method")
+		return nil, fmt.Errorf("invalid OAuth provider type %q", cfg.Type)
 	}
 }
 
-type method string
-
-const (
-	github  method = "GitHub"
-	google         = "Google"
-	invalid        = "Invalid"
-)
-
 //github oauth
 type GitHub struct {
+	name         string
 	clientId     string
 	clientSecret string
-	state        string
-	authUrl      string
+	redirectURI  string
 }
 
-func NewGitHub(clientId, clientSecret, state string) *GitHub {
-	gh := GitHub{
-		clientId:     clientId,
-		clientSecret: clientSecret,
-		state:        state,
-	}
-	gh.setAuthUrl()
-	return &gh
+func NewGitHub(cfg config.OAuthConfig, redirectURI string) *GitHub {
+	return &GitHub{
+		name:         cfg.DisplayName,
+		clientId:     cfg.ClientId,
+		clientSecret: cfg.ClientSecret,
+		redirectURI:  redirectURI,
+	}
 }
 
-func (gh *GitHub) AuthUrl() string {
-	return gh.authUrl
+func (gh *GitHub) Name() string {
+	return gh.name
 }
 
-func (gh *GitHub) setAuthUrl() {
+func (gh *GitHub) AuthUrl(state oauthState) (string, error) {
 	var buf bytes.Buffer
 	buf.WriteString("https://github.com/login/oauth/authorize")
 	v := url.Values{"client_id": {gh.clientId}}
-	v.Set("redirect_uri", "http://localhost:8082/callback/github")
+	v.Set("redirect_uri", gh.redirectURI)
 	v.Set("scope", "user")
-	v.Set("state", gh.state)
+	v.Set("state", state.State)
+	setChallenge(v, state.challenge())
 	buf.WriteByte('?')
 	buf.WriteString(v.Encode())
-	gh.authUrl = buf.String()
+	return buf.String(), nil
+}
+
+func (gh *GitHub) PKCE() bool {
+	return true
 }
 
-func (gh *GitHub) Token(code string) TokenResult {
+func (gh *GitHub) Token(code string, state oauthState) TokenResult {
 	var buf bytes.Buffer
 	buf.WriteString("https://github.com/login/oauth/access_token?")
 	v := url.Values{"code": {code}}
-	v.Set("redirect_uri", "http://localhost:8082/callback/github")
+	v.Set("redirect_uri", gh.redirectURI)
 	v.Set("client_id", gh.clientId)
 	v.Set("client_secret", gh.clientSecret)
+	setVerifier(v, state.Verifier)
 	buf.WriteString(v.Encode())
 	url := buf.String()
 	req, err := http.NewRequest("POST", url, nil)
@@ -211,28 +297,40 @@ func (gh *GitHub) Credentials(token Token) entity.CredentialsResult {
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
-	creds := entity.Credentials{}
-	err = json.Unmarshal(info, &creds)
+	user := struct {
+		Id    int64  `json:"id"`
+		Login string `json:"login"`
+	}{}
+	err = json.Unmarshal(info, &user)
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
-	if creds.Email == "" {
-		info, err = gh.getScopeInfo(token, "https://api.github.com/user/emails") //gets email if not visible
-		tempCreds := []entity.Credentials{}
-		err = json.Unmarshal(info, &tempCreds)
-		if err != nil {
-			return entity.CredentialsResult{Err: err}
+	// the public email of the profile isn't necessarily verified, the verified primary one is used instead
+	info, err = gh.getScopeInfo(token, "https://api.github.com/user/emails")
+	if err != nil {
+		return entity.CredentialsResult{Err: err}
+	}
+	emails := []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}{}
+	err = json.Unmarshal(info, &emails)
+	if err != nil {
+		return entity.CredentialsResult{Err: err}
+	}
+	creds := entity.Credentials{Name: user.Login}
+	if user.Id != 0 {
+		creds.Subject = strconv.FormatInt(user.Id, 10)
+	}
+	for _, e := range emails {
+		if e.Verified && (e.Primary || creds.Email == "") {
+			creds.Email, creds.Verified = strings.ToLower(e.Email), true
 		}
-		creds.Email = tempCreds[0].Email
 	}
-	creds.Name = creds.Login
 	return entity.CredentialsResult{Credentials: creds}
 }
 
-func (gh *GitHub) State() string {
-	return gh.state
-}
-
 func (gh *GitHub) getScopeInfo(token Token, url string) ([]byte, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -253,44 +351,50 @@ func (gh *GitHub) getScopeInfo(token Token, url string) ([]byte, error) {
 
 //google oauth
 type Google struct {
+	name         string
 	clientId     string
 	clientSecret string
-	state        string
-	authUrl      string
+	redirectURI  string
 }
 
-func NewGoogle(clientId, clientSecret, state string) *Google {
-	g := Google{
-		clientId:     clientId,
-		clientSecret: clientSecret,
-		state:        state,
-	}
-	g.setAuthUrl()
-	return &g
+func NewGoogle(cfg config.OAuthConfig, redirectURI string) *Google {
+	return &Google{
+		name:         cfg.DisplayName,
+		clientId:     cfg.ClientId,
+		clientSecret: cfg.ClientSecret,
+		redirectURI:  redirectURI,
+	}
 }
-func (g *Google) AuthUrl() string {
-	return g.authUrl
+
+func (g *Google) Name() string {
+	return g.name
 }
 
-func (g *Google) setAuthUrl() {
+func (g *Google) AuthUrl(state oauthState) (string, error) {
 	var buf bytes.Buffer
 	buf.WriteString("https://accounts.google.com/o/oauth2/auth")
 	v := url.Values{"response_type": {"code"}, "client_id": {g.clientId}}
-	v.Set("redirect_uri", "http://localhost:8082/callback/google")
+	v.Set("redirect_uri", g.redirectURI)
 	v.Set("scope", "https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/userinfo.profile")
-	v.Set("state", g.state)
+	v.Set("state", state.State)
+	setChallenge(v, state.challenge())
 	buf.WriteByte('?')
 	buf.WriteString(v.Encode())
-	g.authUrl = buf.String()
+	return buf.String(), nil
+}
+
+func (g *Google) PKCE() bool {
+	return true
 }
 
-func (gh *Google) Token(code string) TokenResult {
+func (gh *Google) Token(code string, state oauthState) TokenResult {
 	var buf bytes.Buffer
 	buf.WriteString("https://oauth2.googleapis.com/token?")
 	v := url.Values{"grant_type": {"authorization_code"}, "code": {code}}
-	v.Set("redirect_uri", "http://localhost:8082/callback/google")
+	v.Set("redirect_uri", gh.redirectURI)
 	v.Set("client_id", gh.clientId)
 	v.Set("client_secret", gh.clientSecret)
+	setVerifier(v, state.Verifier)
 	buf.WriteString(v.Encode())
 	url := buf.String()
 	req, err := http.NewRequest("POST", url, nil)
@@ -324,13 +428,29 @@ func (gh *Google) Credentials(token Token) entity.CredentialsResult {
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
-	creds := entity.Credentials{}
-	err = json.Unmarshal(content, &creds)
+	user := struct {
+		Id            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+	}{}
+	err = json.Unmarshal(content, &user)
 	if err != nil {
 		return entity.CredentialsResult{Err: err}
 	}
+	creds := entity.Credentials{Name: user.Name, Email: strings.ToLower(user.Email), Subject: user.Id, Verified: user.VerifiedEmail}
 	return entity.CredentialsResult{Credentials: creds}
 }
-func (g *Google) State() string {
-	return g.state
+
+func setChallenge(v url.Values, challenge string) {
+	if challenge != "" {
+		v.Set("code_challenge", challenge)
+		v.Set("code_challenge_method", "S256")
+	}
+}
+
+func setVerifier(v url.Values, verifier string) {
+	if verifier != "" {
+		v.Set("code_verifier", verifier)
+	}
 }
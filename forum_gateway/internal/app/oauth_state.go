@@ -0,0 +1,107 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	oauthStateCookie   = "oauth_state"
+	oauthStateLifetime = 10 * time.Minute
+)
+
+var errInvalidOAuthState = errors.New("invalid OAuth state")
+
+// oauthState is what the browser has to bring back to the callback, kept in a signed cookie
+type oauthState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier,omitempty"`
+	// Link connects the account to the signed in user instead of signing in with it
+	Link    bool      `json:"link,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+func newOAuthState(provider string, pkce, link bool) (oauthState, error) {
+	s := oauthState{Provider: provider, Link: link, Expires: time.Now().Add(oauthStateLifetime)}
+	var err error
+	if s.State, err = randomHex(32); err != nil {
+		return oauthState{}, err
+	}
+	if s.Nonce, err = randomHex(16); err != nil {
+		return oauthState{}, err
+	}
+	if pkce {
+		if s.Verifier, err = randomHex(32); err != nil {
+			return oauthState{}, err
+		}
+	}
+	return s, nil
+}
+
+// challenge is the S256 PKCE code challenge of the verifier
+func (s oauthState) challenge() string {
+	if s.Verifier == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (h *Handler) setOAuthState(w http.ResponseWriter, s oauthState) error {
+	value, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(value)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    encoded + "." + h.signOAuthState(encoded),
+		Path:     "/callback/",
+		MaxAge:   int(oauthStateLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// popOAuthState reads and clears the state cookie, it has to match the provider and the state of the callback
+func (h *Handler) popOAuthState(w http.ResponseWriter, r *http.Request, provider string) (oauthState, error) {
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/callback/", MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		return oauthState{}, errInvalidOAuthState
+	}
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(h.signOAuthState(parts[0]))) {
+		return oauthState{}, errInvalidOAuthState
+	}
+	value, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return oauthState{}, errInvalidOAuthState
+	}
+	var s oauthState
+	if err = json.Unmarshal(value, &s); err != nil {
+		return oauthState{}, errInvalidOAuthState
+	}
+	state := r.FormValue("state")
+	if s.Provider != provider || time.Now().After(s.Expires) || state == "" || !hmac.Equal([]byte(state), []byte(s.State)) {
+		return oauthState{}, errInvalidOAuthState
+	}
+	return s, nil
+}
+
+func (h *Handler) signOAuthState(value string) string {
+	mac := hmac.New(sha256.New, h.csrfKey)
+	mac.Write([]byte("oauth_state:" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stateCookie is the value setOAuthState gives the state cookie
+func stateCookie(t *testing.T, h *Handler, s oauthState) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := h.setOAuthState(rec, s); err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].Path != "/callback/" {
+		t.Fatalf("cookies set = %v", cookies)
+	}
+	return cookies[0].Value
+}
+
+func callback(cookie, state string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/callback/mock?code=c&state="+state, nil)
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: cookie})
+	}
+	return r
+}
+
+func TestOAuthStateRoundTrip(t *testing.T) {
+	h := &Handler{csrfKey: []byte("key")}
+	s, err := newOAuthState("mock", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.State) != 64 || len(s.Nonce) != 32 || len(s.Verifier) != 64 || !s.Link {
+		t.Fatalf("state = %+v", s)
+	}
+	rec := httptest.NewRecorder()
+	got, err := h.popOAuthState(rec, callback(stateCookie(t, h, s), s.State), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != s.State || got.Nonce != s.Nonce || got.Verifier != s.Verifier || !got.Link || !got.Expires.Equal(s.Expires) {
+		t.Errorf("popped %+v, want %+v", got, s)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != oauthStateCookie || cookies[0].MaxAge != -1 {
+		t.Errorf("the state cookie isn't cleared: %v", cookies)
+	}
+
+	if s, err = newOAuthState("github", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if s.Verifier != "" || s.challenge() != "" {
+		t.Errorf("a provider without PKCE got verifier %q", s.Verifier)
+	}
+}
+
+// RFC 7636 Appendix B
+func TestOAuthStateChallenge(t *testing.T) {
+	s := oauthState{Verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"}
+	if got := s.challenge(); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("challenge = %s", got)
+	}
+}
+
+func TestOAuthStateRefused(t *testing.T) {
+	h := &Handler{csrfKey: []byte("key")}
+	valid := oauthState{Provider: "mock", State: "state-1", Nonce: "nonce-1", Verifier: "verifier-1", Expires: time.Now().Add(time.Minute)}
+	cookie := stateCookie(t, h, valid)
+	payload, signature, _ := strings.Cut(cookie, ".")
+	// signed is a cookie with a good signature over whatever payload
+	signed := func(payload string) string { return payload + "." + h.signOAuthState(payload) }
+	tampered := func() string {
+		b, _ := base64.RawURLEncoding.DecodeString(payload)
+		b = []byte(strings.Replace(string(b), "verifier-1", "verifier-2", 1))
+		return base64.RawURLEncoding.EncodeToString(b) + "." + signature
+	}
+	expired := valid
+	expired.Expires = time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name     string
+		cookie   string
+		state    string
+		provider string
+	}{
+		{"no cookie", "", "state-1", "mock"},
+		{"no signature", payload, "state-1", "mock"},
+		{"payload changed", tampered(), "state-1", "mock"},
+		{"signature changed", payload + "." + strings.Repeat("0", len(signature)), "state-1", "mock"},
+		{"signed with another key", payload + "." + (&Handler{csrfKey: []byte("other")}).signOAuthState(payload), "state-1", "mock"},
+		{"signed without the prefix", payload + "." + h.csrfToken(payload), "state-1", "mock"},
+		{"not base64", signed("!!!"), "state-1", "mock"},
+		{"not JSON", signed(base64.RawURLEncoding.EncodeToString([]byte("state-1"))), "state-1", "mock"},
+		{"expired", stateCookie(t, h, expired), "state-1", "mock"},
+		{"another provider", cookie, "state-1", "github"},
+		{"another state", cookie, "state-2", "mock"},
+		{"no state", cookie, "", "mock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if _, err := h.popOAuthState(rec, callback(tt.cookie, tt.state), tt.provider); err != errInvalidOAuthState {
+				t.Errorf("err = %v, want %v", err, errInvalidOAuthState)
+			}
+			if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge != -1 {
+				t.Errorf("the state cookie isn't cleared: %v", cookies)
+			}
+		})
+	}
+	if _, err := h.popOAuthState(httptest.NewRecorder(), callback(cookie, "state-1"), "mock"); err != nil {
+		t.Errorf("the untouched cookie was refused: %v", err)
+	}
+}
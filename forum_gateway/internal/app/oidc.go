@@ -0,0 +1,346 @@
+package app
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum_gateway/internal/config"
+	"forum_gateway/internal/entity"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is how far the clocks of the gateway and of the provider may disagree
+const clockSkew = time.Minute
+
+// OIDC signs in with any OpenID Connect provider, like GitLab, Keycloak or Gitea
+type OIDC struct {
+	cfg         config.OAuthConfig
+	redirectURI string
+	client      *http.Client
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]*rsa.PublicKey
+}
+
+// oidcDiscovery is the part of the provider metadata at /.well-known/openid-configuration that is used
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JwksURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+type idTokenClaims struct {
+	Issuer            string      `json:"iss"`
+	Subject           string      `json:"sub"`
+	Audience          interface{} `json:"aud"`
+	AuthorizedParty   string      `json:"azp"`
+	Expiry            int64       `json:"exp"`
+	IssuedAt          int64       `json:"iat"`
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     *bool       `json:"email_verified"`
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// NewOIDC discovers the provider on first use, so that a provider that is down doesn't stop the gateway
+func NewOIDC(cfg config.OAuthConfig, redirectURI string) *OIDC {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDC{
+		cfg:         cfg,
+		redirectURI: redirectURI,
+		client:      &http.Client{Timeout: duration},
+		keys:        map[string]*rsa.PublicKey{},
+	}
+}
+
+func (o *OIDC) Name() string {
+	return o.cfg.DisplayName
+}
+
+func (o *OIDC) PKCE() bool {
+	d, err := o.discover()
+	if err != nil {
+		return false
+	}
+	for _, m := range d.CodeChallengeMethods {
+		if m == "S256" {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *OIDC) AuthUrl(state oauthState) (string, error) {
+	d, err := o.discover()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	v := u.Query()
+	v.Set("response_type", "code")
+	v.Set("client_id", o.cfg.ClientId)
+	v.Set("redirect_uri", o.redirectURI)
+	v.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	v.Set("state", state.State)
+	v.Set("nonce", state.Nonce)
+	setChallenge(v, state.challenge())
+	u.RawQuery = v.Encode()
+	return u.String(), nil
+}
+
+// Token exchanges the code and validates the ID token that comes with the access token
+func (o *OIDC) Token(code string, state oauthState) TokenResult {
+	d, err := o.discover()
+	if err != nil {
+		return TokenResult{Error: err}
+	}
+	v := url.Values{"grant_type": {"authorization_code"}, "code": {code}}
+	v.Set("redirect_uri", o.redirectURI)
+	v.Set("client_id", o.cfg.ClientId)
+	v.Set("client_secret", o.cfg.ClientSecret)
+	setVerifier(v, state.Verifier)
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return TokenResult{Error: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var token Token
+	if err = o.getJSON(req, &token); err != nil {
+		return TokenResult{Error: err}
+	}
+	if token.IDToken == "" {
+		return TokenResult{Error: fmt.Errorf("%s: no ID token", o.cfg.Name)}
+	}
+	if token.claims, err = o.verifyIDToken(token.IDToken, state.Nonce); err != nil {
+		return TokenResult{Error: fmt.Errorf("%s: %w", o.cfg.Name, err)}
+	}
+	return TokenResult{Token: token}
+}
+
+// Credentials come from the ID token, the userinfo endpoint fills in the email when the provider left it out
+func (o *OIDC) Credentials(token Token) entity.CredentialsResult {
+	if token.claims == nil {
+		return entity.CredentialsResult{Err: errors.New("ID token is not validated")}
+	}
+	d, err := o.discover()
+	if err != nil {
+		return entity.CredentialsResult{Err: err}
+	}
+	claims := *token.claims
+	if claims.Email == "" && d.UserinfoEndpoint != "" {
+		info, err := o.userinfo(d, token)
+		if err != nil {
+			return entity.CredentialsResult{Err: err}
+		}
+		if info.Subject != claims.Subject {
+			return entity.CredentialsResult{Err: errors.New("userinfo is about another subject")}
+		}
+		claims.Email, claims.EmailVerified = info.Email, info.EmailVerified
+		if claims.Name == "" {
+			claims.Name = info.Name
+		}
+		if claims.PreferredUsername == "" {
+			claims.PreferredUsername = info.PreferredUsername
+		}
+	}
+	creds := entity.Credentials{Email: strings.ToLower(claims.Email), Name: claims.PreferredUsername, Subject: claims.Subject}
+	// forum_auth only matches accounts by emails the provider says it has verified
+	creds.Verified = creds.Email != "" && claims.EmailVerified != nil && *claims.EmailVerified
+	if creds.Name == "" {
+		creds.Name = claims.Name
+	}
+	if creds.Name == "" {
+		creds.Name = strings.Split(creds.Email, "@")[0]
+	}
+	return entity.CredentialsResult{Credentials: creds}
+}
+
+func (o *OIDC) discover() (*oidcDiscovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+	issuer := strings.TrimRight(o.cfg.Issuer, "/")
+	req, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	d := oidcDiscovery{}
+	if err = o.getJSON(req, &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%s: discovered issuer %q doesn't match %q", o.cfg.Name, d.Issuer, o.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JwksURI == "" {
+		return nil, fmt.Errorf("%s: incomplete discovery document", o.cfg.Name)
+	}
+	o.discovery = &d
+	return o.discovery, nil
+}
+
+func (o *OIDC) verifyIDToken(raw, nonce string) (*idTokenClaims, error) {
+	d, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err = decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+	key, err := o.key(d, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, errors.New("invalid ID token signature")
+	}
+	claims := idTokenClaims{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != strings.TrimRight(d.Issuer, "/"):
+		return nil, fmt.Errorf("ID token of issuer %q", claims.Issuer)
+	case !hasAudience(claims.Audience, o.cfg.ClientId):
+		return nil, errors.New("ID token for another client")
+	case claims.AuthorizedParty != "" && claims.AuthorizedParty != o.cfg.ClientId:
+		return nil, errors.New("ID token authorized for another client")
+	case now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return nil, errors.New("expired ID token")
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return nil, errors.New("ID token issued in the future")
+	case claims.Nonce != nonce:
+		return nil, errors.New("ID token nonce doesn't match")
+	case claims.Subject == "":
+		return nil, errors.New("ID token without subject")
+	}
+	return &claims, nil
+}
+
+// key finds the signing key, the key set is fetched again once when the provider has rotated its keys
+func (o *OIDC) key(d *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, d.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err = o.getJSON(req, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	o.keys = keys
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown ID token key %q", kid)
+}
+
+func (o *OIDC) userinfo(d *oidcDiscovery, token Token) (idTokenClaims, error) {
+	info := idTokenClaims{}
+	req, err := http.NewRequest(http.MethodGet, d.UserinfoEndpoint, nil)
+	if err != nil {
+		return info, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	err = o.getJSON(req, &info)
+	return info, err
+}
+
+func (o *OIDC) getJSON(req *http.Request, v interface{}) error {
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s: %d %s", o.cfg.Name, req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// hasAudience accepts the aud claim both as a string and as a list
+func hasAudience(aud interface{}, clientId string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientId
+	case []interface{}:
+		for _, v := range a {
+			if v == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"forum_gateway/internal/config"
+	"forum_gateway/pkg/mockoidc"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNonce = "nonce-1"
+
+// newTestOIDC runs a mock provider and returns it with an OIDC client of it that has discovered it
+func newTestOIDC(t *testing.T) (*mockoidc.Server, *OIDC) {
+	t.Helper()
+	srv := httptest.NewServer(nil)
+	t.Cleanup(srv.Close)
+	mock, err := mockoidc.New(srv.URL, "forum", "secret", mockoidc.User{Subject: "sub-1", Email: "mock@example.com", EmailVerified: true, Name: "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.Handler = mock.Handler()
+	o := NewOIDC(config.OAuthConfig{Name: "mock", Type: config.OAuthOIDC, ClientId: "forum", ClientSecret: "secret", Issuer: srv.URL}, "https://localhost/callback/mock")
+	if _, err = o.discover(); err != nil {
+		t.Fatal(err)
+	}
+	return mock, o
+}
+
+func sign(t *testing.T, mock *mockoidc.Server, claims map[string]interface{}) string {
+	t.Helper()
+	token, err := mock.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	mock, o := newTestOIDC(t)
+	claims, err := o.verifyIDToken(sign(t, mock, mock.Claims(testNonce)), testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "sub-1" || claims.Email != "mock@example.com" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestVerifyIDTokenRefused(t *testing.T) {
+	mock, o := newTestOIDC(t)
+	tests := []struct {
+		name   string
+		change func(claims map[string]interface{})
+		nonce  string
+		want   string
+	}{
+		{"wrong audience", func(c map[string]interface{}) { c["aud"] = "other" }, testNonce, "another client"},
+		{"audience list without client", func(c map[string]interface{}) { c["aud"] = []string{"other", "third"} }, testNonce, "another client"},
+		{"wrong authorized party", func(c map[string]interface{}) { c["azp"] = "other" }, testNonce, "authorized for another client"},
+		{"nonce mismatch", func(c map[string]interface{}) {}, "nonce-2", "nonce"},
+		{"missing nonce", func(c map[string]interface{}) { delete(c, "nonce") }, testNonce, "nonce"},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() }, testNonce, "expired"},
+		{"issued in the future", func(c map[string]interface{}) { c["iat"] = time.Now().Add(2 * clockSkew).Unix() }, testNonce, "future"},
+		{"other issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example" }, testNonce, "issuer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := mock.Claims(testNonce)
+			tt.change(claims)
+			_, err := o.verifyIDToken(sign(t, mock, claims), tt.nonce)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenBadSignature(t *testing.T) {
+	mock, o := newTestOIDC(t)
+	parts := strings.Split(sign(t, mock, mock.Claims(testNonce)), ".")
+	claims := mock.Claims(testNonce)
+	claims["sub"] = "someone-else"
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if _, err = o.verifyIDToken(forged, testNonce); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("err = %v, want an invalid signature", err)
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	mock, o := newTestOIDC(t)
+	old := sign(t, mock, mock.Claims(testNonce))
+	if _, err := o.verifyIDToken(old, testNonce); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+	// the key of the new token is unknown, so the key set is fetched again
+	if _, err := o.verifyIDToken(sign(t, mock, mock.Claims(testNonce)), testNonce); err != nil {
+		t.Fatalf("token of the rotated key: %v", err)
+	}
+	// the old key left the key set with the rotation
+	if _, err := o.verifyIDToken(old, testNonce); err == nil || !strings.Contains(err.Error(), "unknown ID token key") {
+		t.Errorf("err = %v, want an unknown key", err)
+	}
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	_, o := newTestOIDC(t)
+	state := oauthState{State: "state-1", Nonce: testNonce, Verifier: "verifier-0123456789-0123456789-0123456789"}
+	authURL, err := o.AuthUrl(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != state.State {
+		t.Fatalf("redirected to %s", location)
+	}
+	token := o.Token(location.Query().Get("code"), state)
+	if token.Error != nil {
+		t.Fatal(token.Error)
+	}
+	creds := o.Credentials(token.Token)
+	if creds.Err != nil {
+		t.Fatal(creds.Err)
+	}
+	if creds.Credentials.Subject != "sub-1" || creds.Credentials.Email != "mock@example.com" || !creds.Credentials.Verified {
+		t.Errorf("credentials = %+v", creds.Credentials)
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"forum_gateway/internal/entity"
+	"net/http"
+)
+
+func (h *Handler) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	r.ParseForm()
+	report, postId, err := entity.GetReport(r)
+	if err != nil {
+		h.logError(r.Context(), err)
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	resChan := make(chan entity.ReportResult, 1)
+	var res entity.ReportResult
+	go h.forumUcase.Report(ctx, report, resChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case res = <-resChan:
+		switch res.Err {
+		case nil:
+			// a post hidden by this report can't be shown anymore
+			redirect := fmt.Sprintf("/posts/%d", postId)
+			if res.Hidden && report.Target == "post" {
+				redirect = "/"
+			}
+			http.Redirect(w, r, redirect, http.StatusSeeOther)
+		case entity.ErrAlreadyReported:
+			h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Already reported"}, "templates/errors.html")
+		case entity.ErrForbidden:
+			h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "You can't report your own content"}, "templates/errors.html")
+		default:
+			h.postModificationResponse(w, r, res.Err, "")
+		}
+	}
+}
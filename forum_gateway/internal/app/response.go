@@ -4,10 +4,19 @@ import (
 	"forum_gateway/internal/entity"
 	"html/template"
 	"net/http"
+	"path/filepath"
+	"strings"
 )
 
+var templateFuncs = template.FuncMap{
+	"highlight": highlight,
+	"indent":    indent,
+	"flatten":   flatten,
+	"otpauth":   otpauth,
+}
+
 func (h *Handler) APIResponse(w http.ResponseWriter, code int, response entity.Response, filename string) {
-	templ, err := template.ParseFiles(filename)
+	templ, err := template.New(filepath.Base(filename)).Funcs(templateFuncs).Funcs(h.requestFuncs(w)).ParseFiles(filename)
 	if err != nil {
 		h.errLog.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -17,3 +26,51 @@ func (h *Handler) APIResponse(w http.ResponseWriter, code int, response entity.R
 	w.WriteHeader(code)
 	templ.Execute(w, response)
 }
+
+// requestFuncs gives the forms of the template the token that the CSRF middleware expects back
+// and the sign in page the configured OAuth providers
+func (h *Handler) requestFuncs(w http.ResponseWriter) template.FuncMap {
+	token := ""
+	if cw, ok := w.(*csrfWriter); ok {
+		token = cw.token
+	}
+	return template.FuncMap{
+		"csrfToken":      func() string { return token },
+		"oauthProviders": func() []oauthProvider { return h.providers },
+	}
+}
+
+// highlight escapes a search snippet and turns its match markers into <mark> tags
+func highlight(snippet string) template.HTML {
+	escaped := template.HTMLEscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, entity.SnippetStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, entity.SnippetEnd, "</mark>")
+	return template.HTML(escaped)
+}
+
+// indent prefixes nested categories in flat lists like selects
+func indent(depth interface{}) string {
+	level, _ := depth.(float64)
+	return strings.Repeat("— ", int(level))
+}
+
+// flatten lists a decoded category tree depth first, so that it can be ranged over in one loop
+func flatten(tree interface{}) []interface{} {
+	nodes, _ := tree.([]interface{})
+	flat := []interface{}{}
+	for _, node := range nodes {
+		flat = append(flat, node)
+		if category, ok := node.(map[string]interface{}); ok {
+			flat = append(flat, flatten(category["subcategories"])...)
+		}
+	}
+	return flat
+}
+
+// otpauth lets the enrollment URI into links, html/template only trusts http and mailto ones
+func otpauth(uri string) template.URL {
+	if !strings.HasPrefix(uri, "otpauth://") {
+		return ""
+	}
+	return template.URL(uri)
+}
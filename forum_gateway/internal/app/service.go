@@ -2,87 +2,139 @@ package app
 
 import (
 	"context"
+	"fmt"
+	"forum_gateway/internal/config"
 	"forum_gateway/internal/entity"
 	"forum_gateway/internal/usecase"
+	"forum_gateway/pkg/logger"
+	"forum_gateway/pkg/metrics"
 	"log"
+	"net/url"
 	"time"
 )
 
-const duration = 10 * time.Second
+// duration bounds the handling of a request, Run sets it from the config
+var duration = 10 * time.Second
 
 type Handler struct {
+	log         *logger.Logger
 	errLog      *log.Logger
-	infoLog     *log.Logger
 	auUcase     AuthUsecase
 	forumUcase  ForumUsecase
-	config      *Config
-	oauths      map[method]OAuth
+	config      *config.Config
+	oauths      map[string]OAuth
+	providers   []oauthProvider
 	rateLimiter *usecase.IPRateLimiter
+	rateLimited *metrics.Counter
 	middlewares []Middleware
+	csrfKey     []byte
 }
 
-func NewHandler(errLog, infoLog *log.Logger, auUcase AuthUsecase, forumUcase ForumUsecase) *Handler {
+// oauthProvider is a sign in option shown to users
+type oauthProvider struct {
+	Name        string
+	DisplayName string
+	Icon        string
+}
+
+func NewHandler(cfg *config.Config, logs *logger.Logger, reg *metrics.Registry, auUcase AuthUsecase, forumUcase ForumUsecase) *Handler {
 	h := Handler{
-		errLog:      errLog,
-		infoLog:     infoLog,
+		log:         logs,
+		errLog:      logs.Std(logger.LevelError),
 		auUcase:     auUcase,
 		forumUcase:  forumUcase,
-		config:      NewConfig(),
-		oauths:      map[method]OAuth{},
+		oauths:      map[string]OAuth{},
+		config:      cfg,
 		rateLimiter: usecase.NewIPRateLimiter(1, 5),
+		rateLimited: reg.NewCounter("rate_limit_rejections_total", "Requests turned away by the rate limiter, by the pages or the API.", "scope"),
 	}
-	h.setOauth([]method{github, google})
-	h.middlewares = []Middleware{h.RateLimit, h.Authenticate}
+	h.setOauth(cfg.Providers)
+	key, err := newCSRFKey(h.config.CSRFSecret)
+	if err != nil {
+		h.errLog.Fatalln(err)
+	}
+	h.csrfKey = key
+	h.middlewares = []Middleware{h.RateLimit, h.Authenticate, h.CSRF}
 	return &h
 }
 
-func (h *Handler) setOauth(methods []method) {
-	for _, m := range methods {
-		clientId, clientSecret := h.getOAuthConfig(m)
-		temp, err := NewOAuth(clientId, clientSecret, "pseudo_random", m)
+// setOauth registers the configured providers, a provider that can't be built is left out
+func (h *Handler) setOauth(providers []config.OAuthConfig) {
+	for _, p := range providers {
+		oauth, err := NewOAuth(p, h.config.GatewayURL+"/callback/"+p.Name)
 		if err != nil {
 			h.errLog.Println(err)
-		} else {
-			h.oauths[m] = temp
+			continue
 		}
+		h.oauths[p.Name] = oauth
+		h.providers = append(h.providers, oauthProvider{Name: p.Name, DisplayName: p.DisplayName, Icon: p.Icon})
 	}
 }
 
-func (h *Handler) getOAuthConfig(m method) (string, string) {
-	switch m {
-	case github:
-		return h.config.GitHub.ClientId, h.config.GitHub.ClientSecret
-	case google:
-		return h.config.Google.ClientId, h.config.Google.ClientSecret
-	default:
-		return "", ""
-	}
-}
-
+// getTimeout detaches the work of a request from its connection, the request ID is kept for the logs and
+// the calls to forum_app and forum_auth
 func getTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := logger.WithRequestID(context.Background(), logger.RequestID(ctx))
 	if deadline, ok := ctx.Deadline(); ok {
-		return context.WithDeadline(context.Background(), deadline)
+		return context.WithDeadline(detached, deadline)
 	}
-	return context.WithTimeout(context.Background(), duration)
+	return context.WithTimeout(detached, duration)
+}
+
+// logError logs v like errLog.Println, along with the request ID of ctx
+func (h *Handler) logError(ctx context.Context, v ...interface{}) {
+	h.log.Output(ctx, 2, logger.LevelError, fmt.Sprintln(v...))
 }
 
 type AuthUsecase interface {
+	Ready(context.Context, chan error)
 	SignUp(context.Context, entity.Credentials, chan error)
 	SignIn(context.Context, entity.Credentials, chan entity.SessionResult)
 	SignOut(context.Context, entity.Session, chan error)
 	Authenticate(context.Context, string, chan entity.AuthStatusResult)
 	OAuth(context.Context, entity.Credentials, chan entity.SessionResult)
+	ForgotPassword(context.Context, string, chan error)
+	ResetPassword(context.Context, entity.PasswordReset, chan error)
+	SendVerification(context.Context, int64, chan error)
+	VerifyEmail(context.Context, string, chan error)
+	FetchSessions(context.Context, string, chan entity.Response)
+	RevokeSession(context.Context, entity.SessionRevoke, chan error)
+	RevokeOtherSessions(context.Context, string, chan error)
+	FetchIdentities(context.Context, string, chan entity.Response)
+	LinkIdentity(context.Context, entity.IdentityRequest, chan error)
+	UnlinkIdentity(context.Context, entity.IdentityRequest, chan error)
+	VerifyTwoFactor(context.Context, entity.TwoFactorRequest, chan entity.SessionResult)
+	FetchTwoFactor(context.Context, string, chan entity.Response)
+	SetupTwoFactor(context.Context, string, chan entity.Response)
+	EnableTwoFactor(context.Context, entity.TwoFactorRequest, chan entity.Response)
+	DisableTwoFactor(context.Context, entity.TwoFactorRequest, chan error)
+	ResetTwoFactor(context.Context, entity.TwoFactorRequest, chan error)
 }
 
 type ForumUsecase interface {
-	FetchPosts(context.Context, chan entity.Response)
+	Ready(context.Context, chan error)
+	FetchPosts(context.Context, entity.Page, chan entity.Response)
 	FetchUsers(context.Context, chan entity.Response)
 	FetchPost(context.Context, int, chan entity.Response)
 	FetchUser(context.Context, int, chan entity.Response)
-	FetchCategories(context.Context, chan entity.Response)
-	FetchCategory(context.Context, int, chan entity.Response)
+	FetchCategories(context.Context, bool, chan entity.Response)
+	FetchCategory(context.Context, int, entity.Page, chan entity.Response)
+	Search(context.Context, url.Values, chan entity.Response)
 	StorePost(context.Context, entity.Post, chan entity.Result)
+	UpdatePost(context.Context, entity.Post, chan error)
+	DeletePost(context.Context, entity.Post, chan error)
 	StoreComment(context.Context, entity.Comment, chan entity.Result)
 	PostReaction(context.Context, entity.PostReaction, chan error)
 	CommentReaction(context.Context, entity.CommentReaction, chan error)
+	FetchModerationQueue(context.Context, int64, chan entity.Response)
+	Moderate(context.Context, entity.ModerationAction, chan error)
+	UpdateRole(context.Context, entity.RoleUpdate, chan error)
+	Report(context.Context, entity.Report, chan entity.ReportResult)
+	StoreCategory(context.Context, entity.CategoryChange, chan error)
+	UpdateCategory(context.Context, entity.CategoryChange, chan error)
+	ArchiveCategory(context.Context, entity.CategoryChange, chan error)
+	MoveCategory(context.Context, entity.CategoryChange, bool, chan error)
+	FetchNotifications(context.Context, int64, int, chan entity.Response)
+	CountUnread(context.Context, int64, chan entity.UnreadResult)
+	MarkNotificationsRead(context.Context, entity.NotificationRead, chan error)
 }
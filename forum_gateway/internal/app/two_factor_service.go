@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"forum_gateway/internal/entity"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// twoFactorCookie holds the token of a pending sign in between the password or the provider and the code
+const twoFactorCookie = "two_factor"
+
+func pendingCookie(pending entity.PendingSignIn) *http.Cookie {
+	maxAge := int(time.Until(pending.ExpiryTime).Seconds())
+	if maxAge <= 0 {
+		maxAge = -1
+	}
+	return &http.Cookie{
+		Name:     twoFactorCookie,
+		Value:    pending.Token,
+		Path:     "/two-factor",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// askTwoFactor sends a user whose sign in is pending to the page that asks for the code
+func askTwoFactor(w http.ResponseWriter, r *http.Request, pending entity.PendingSignIn) {
+	http.SetCookie(w, pendingCookie(pending))
+	http.Redirect(w, r, "/two-factor", http.StatusSeeOther)
+}
+
+// TWO-FACTOR SIGN IN
+func (h *Handler) TwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Context().Value("authorised") == true {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie(twoFactorCookie)
+	if err != nil || cookie.Value == "" {
+		http.Redirect(w, r, "/sign-in", http.StatusSeeOther)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.APIResponse(w, http.StatusOK, entity.Response{Body: map[string]interface{}{}}, "templates/two_factor.html")
+	case http.MethodPost:
+		h.postTwoFactor(w, r, cookie.Value)
+	default:
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+	}
+}
+
+func (h *Handler) postTwoFactor(w http.ResponseWriter, r *http.Request, token string) {
+	r.ParseForm()
+	req := entity.TwoFactorRequest{Token: token, Code: r.FormValue("code"), UserAgent: r.UserAgent(), IP: getIp(r.RemoteAddr)}
+	if req.Code == "" {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Enter the code", Body: map[string]interface{}{}}, "templates/two_factor.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	sessionChan := make(chan entity.SessionResult, 1)
+	var sessionRes entity.SessionResult
+	go h.auUcase.VerifyTwoFactor(ctx, req, sessionChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+		return
+	case sessionRes = <-sessionChan:
+		err := sessionRes.Err
+		if err != nil {
+			h.logError(ctx, err)
+			switch err {
+			case entity.ErrInvalidCode:
+				h.APIResponse(w, http.StatusUnauthorized, entity.Response{ErrorMessage: "Invalid code", Body: map[string]interface{}{}}, "templates/two_factor.html")
+			case entity.ErrLockedOut:
+				wait := time.Until(sessionRes.LockedUntil)
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				h.APIResponse(w, http.StatusTooManyRequests, entity.Response{ErrorMessage: fmt.Sprintf("Too many failed sign in attempts, try again in %d min", int(wait.Minutes())+1), Body: map[string]interface{}{}}, "templates/two_factor.html")
+			case entity.ErrInvalidToken:
+				http.SetCookie(w, pendingCookie(entity.PendingSignIn{}))
+				h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "The sign in has expired, please sign in again"}, "templates/login.html")
+			case entity.ErrRequestTimeout:
+				h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+			default:
+				h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+			}
+			return
+		}
+	}
+	if sessionRes.Session.Token != "" {
+		http.SetCookie(w, pendingCookie(entity.PendingSignIn{}))
+		http.SetCookie(w, sessionCookie(sessionRes.Session))
+		http.Redirect(w, r, "/posts", http.StatusSeeOther)
+		return
+	}
+	h.APIResponse(w, http.StatusInternalServerError, entity.Response{ErrorMessage: "Internal Server Error"}, "templates/errors.html")
+}
+
+// SECURITY
+func (h *Handler) SecurityHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.securityToken(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	h.securityPage(w, r, h.auUcase.FetchTwoFactor, token, http.StatusOK, "")
+}
+
+// SetupTwoFactorHandler shows the secret to add to an authenticator app, the same one until it is enabled
+func (h *Handler) SetupTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.securityToken(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	h.securityPage(w, r, h.auUcase.SetupTwoFactor, token, http.StatusOK, "")
+}
+
+// EnableTwoFactorHandler checks the first code of the app and shows the recovery codes, this once
+func (h *Handler) EnableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.securityToken(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	responseChan := make(chan entity.Response, 1)
+	go h.auUcase.EnableTwoFactor(ctx, entity.TwoFactorRequest{Token: token, Code: r.FormValue("code")}, responseChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case response := <-responseChan:
+		switch response.Err {
+		case nil:
+			setup, _ := response.Body.(map[string]interface{})
+			response.Body = map[string]interface{}{"enabled": true, "new_codes": setup["recovery_codes"]}
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, http.StatusOK, response, "templates/security.html")
+		case entity.ErrInvalidCode, entity.ErrBadRequest:
+			h.securityPage(w, r, h.auUcase.SetupTwoFactor, token, http.StatusBadRequest, "Invalid code, check the time on your device and try again")
+		case entity.ErrTwoFactorEnabled:
+			http.Redirect(w, r, "/security", http.StatusSeeOther)
+		default:
+			h.postModificationResponse(w, r, response.Err, "")
+		}
+	}
+}
+
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.securityToken(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	req := entity.TwoFactorRequest{Token: token, Code: r.FormValue("code"), Password: r.FormValue("password"), IP: getIp(r.RemoteAddr)}
+	go h.auUcase.DisableTwoFactor(ctx, req, errChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err := <-errChan:
+		switch err {
+		case entity.ErrInvalidCode, entity.ErrBadRequest:
+			h.securityPage(w, r, h.auUcase.FetchTwoFactor, token, http.StatusBadRequest, "Invalid code")
+		case entity.ErrInvalidCredentials:
+			h.securityPage(w, r, h.auUcase.FetchTwoFactor, token, http.StatusBadRequest, "Invalid password")
+		case entity.ErrLockedOut:
+			h.securityPage(w, r, h.auUcase.FetchTwoFactor, token, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+		default:
+			h.postModificationResponse(w, r, err, "/security")
+		}
+	}
+}
+
+// ResetTwoFactorHandler turns off the two-factor authentication of a user who has lost their app and codes
+func (h *Handler) ResetTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return
+	}
+	userId, err := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+	if err != nil || userId <= 0 {
+		h.APIResponse(w, http.StatusBadRequest, entity.Response{ErrorMessage: "Bad Request"}, "templates/errors.html")
+		return
+	}
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	errChan := make(chan error, 1)
+	go h.auUcase.ResetTwoFactor(ctx, entity.TwoFactorRequest{Token: cookie.Value, UserId: userId}, errChan)
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case err = <-errChan:
+		if err == entity.ErrNotFound {
+			h.APIResponse(w, http.StatusNotFound, entity.Response{ErrorMessage: "The user has no two-factor authentication"}, "templates/errors.html")
+			return
+		}
+		h.postModificationResponse(w, r, err, fmt.Sprintf("/users/%d", userId))
+	}
+}
+
+// securityToken checks that a signed in user made the request with the method and returns their session token
+func (h *Handler) securityToken(w http.ResponseWriter, r *http.Request, method string) (string, bool) {
+	if r.Context().Value("authorised") == false {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return "", false
+	}
+	if r.Method != method {
+		h.APIResponse(w, http.StatusMethodNotAllowed, entity.Response{ErrorMessage: "Invalid method"}, "templates/errors.html")
+		return "", false
+	}
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		h.APIResponse(w, http.StatusForbidden, entity.Response{ErrorMessage: "Forbidden"}, "templates/errors.html")
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// securityPage renders the status of two-factor authentication, or the secret to set it up, with a message
+func (h *Handler) securityPage(w http.ResponseWriter, r *http.Request, fetch func(ctx context.Context, token string, responseChan chan entity.Response), token string, code int, message string) {
+	ctx, cancel := getTimeout(r.Context())
+	defer cancel()
+	responseChan := make(chan entity.Response, 1)
+	go fetch(ctx, token, responseChan)
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		h.logError(ctx, err)
+		h.APIResponse(w, http.StatusRequestTimeout, entity.Response{ErrorMessage: "Request Timeout"}, "templates/errors.html")
+	case response := <-responseChan:
+		switch response.Err {
+		case nil:
+			body, _ := response.Body.(map[string]interface{})
+			if body == nil {
+				body = map[string]interface{}{}
+			}
+			response.Body, response.ErrorMessage = body, message
+			h.setUserInfo(r, &response)
+			h.APIResponse(w, code, response, "templates/security.html")
+		case entity.ErrTwoFactorEnabled:
+			http.Redirect(w, r, "/security", http.StatusSeeOther)
+		default:
+			h.postModificationResponse(w, r, response.Err, "")
+		}
+	}
+}
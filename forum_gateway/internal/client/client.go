@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"forum_gateway/pkg/logger"
+	"forum_gateway/pkg/metrics"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBody bounds the responses read into memory
+const maxBody = 10 << 20
+
+// transport is shared by all the clients, so that connections to the services are kept and reused
+var transport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 20,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// Options are where a service is and how it is called
+type Options struct {
+	BaseURL string
+	// Timeout bounds every attempt of a call
+	Timeout time.Duration
+	// Retries is how many times an idempotent call is repeated after a network error or a 502, 503 or 504
+	Retries int
+	// Backoff is the wait before the first retry, it doubles with every further one
+	Backoff time.Duration
+	// Latency records every attempt by service, method, path and status when it isn't nil
+	Latency *metrics.Histogram
+}
+
+// Client calls the JSON API of a service
+type Client struct {
+	service string
+	opts    Options
+	http    *http.Client
+}
+
+func New(service string, opts Options) *Client {
+	return &Client{service: service, opts: opts, http: &http.Client{Transport: transport}}
+}
+
+// App calls forum_app
+type App struct {
+	*Client
+}
+
+func NewApp(opts Options) *App {
+	return &App{New("forum_app", opts)}
+}
+
+// Auth calls forum_auth
+type Auth struct {
+	*Client
+}
+
+func NewAuth(opts Options) *Auth {
+	return &Auth{New("forum_auth", opts)}
+}
+
+// Do sends body to path with method. The response body is read whole before Do returns, so the connection
+// goes back to the pool right away and the body outlives the timeout of the call
+func (c *Client) Do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	backoff := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		response, err := c.do(ctx, method, path, body)
+		if attempt >= c.opts.Retries || !idempotent(method) || !retryable(response, err) {
+			return response, err
+		}
+		select {
+		case <-ctx.Done():
+			return response, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Ready asks the service whether it is ready, once, since the probe is repeated anyway
+func (c *Client) Ready(ctx context.Context) error {
+	response, err := c.do(ctx, http.MethodGet, "/readyz", nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s/readyz: %s", c.opts.BaseURL, response.Status)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	start := time.Now()
+	response, err := c.send(ctx, method, path, body)
+	if c.opts.Latency != nil {
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(response.StatusCode)
+		}
+		// the query is left out, it holds IDs
+		route, _, _ := strings.Cut(path, "?")
+		c.opts.Latency.Observe(time.Since(start).Seconds(), c.service, method, route, status)
+	}
+	return response, err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.opts.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if id := logger.RequestID(ctx); id != "" {
+		req.Header.Set(logger.RequestIDHeader, id)
+	}
+	response, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	read, err := io.ReadAll(io.LimitReader(response.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(read))
+	return response, nil
+}
+
+// idempotent leaves PUT and DELETE out, some of them spend one-time codes and tokens
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+func retryable(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"forum_gateway/pkg/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Every attempt is timed, retries included, under the path without its query
+func TestLatency(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(nil)
+	down.Close()
+
+	reg := metrics.NewRegistry()
+	latency := reg.NewHistogram("upstream_request_duration_seconds", "Calls.", metrics.DefaultBuckets, "service", "method", "path", "status")
+	opts := Options{BaseURL: srv.URL, Timeout: time.Second, Retries: 2, Backoff: time.Millisecond, Latency: latency}
+	if _, err := NewApp(opts).Do(context.Background(), http.MethodGet, "/post?id=7", nil); err != nil {
+		t.Fatal(err)
+	}
+	opts.BaseURL = down.URL
+	if _, err := NewAuth(opts).Do(context.Background(), http.MethodPost, "/signin", nil); err == nil {
+		t.Fatal("a call to a closed server succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{
+		`upstream_request_duration_seconds_count{service="forum_app",method="GET",path="/post",status="503"} 1`,
+		`upstream_request_duration_seconds_count{service="forum_app",method="GET",path="/post",status="200"} 1`,
+		// POST isn't retried
+		`upstream_request_duration_seconds_count{service="forum_auth",method="POST",path="/signin",status="error"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"forum_gateway/internal/client"
+	"forum_gateway/pkg/logger"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Addr is where the gateway serves HTTPS
+	Addr string `json:"addr"`
+	// MetricsAddr is where the metrics are served over plain HTTP, apart from the public address; empty turns them off
+	MetricsAddr string `json:"metrics_addr"`
+	LogFile     string `json:"log_file"`
+	// LogLevel is the least important level logged: debug, info, warn or error
+	LogLevel string `json:"log_level"`
+	// LogMaxSize is the size in megabytes the log file is rotated at, LogMaxBackups rotated files are kept
+	LogMaxSize    int `json:"log_max_size"`
+	LogMaxBackups int `json:"log_max_backups"`
+	// Timeout bounds the handling of a request, the calls to forum_app and forum_auth included
+	Timeout Duration `json:"timeout"`
+	TLSCert string   `json:"tls_cert"`
+	TLSKey  string   `json:"tls_key"`
+	// AppURL and AuthURL are the base URLs of forum_app and forum_auth
+	AppURL  string `json:"app_url"`
+	AuthURL string `json:"auth_url"`
+	// Client is how the other services are called
+	Client Client `json:"client"`
+	// GatewayURL is the public address of the gateway, the OAuth redirect URIs are built from it
+	GatewayURL string `json:"gateway_url"`
+	// CSRFSecret signs the CSRF tokens and the OAuth state cookie, a random one is generated when it is empty
+	CSRFSecret string `json:"csrf_secret"`
+	// Providers are listed on the sign in page in this order
+	Providers []OAuthConfig `json:"providers"`
+	// PrintConfig asks to print the config instead of running
+	PrintConfig bool `json:"-"`
+}
+
+// Client is how long an attempt of a call to another service may take, how often idempotent calls are retried
+// and how long the first retry waits
+type Client struct {
+	Timeout Duration `json:"timeout"`
+	Retries int      `json:"retries"`
+	Backoff Duration `json:"backoff"`
+}
+
+func Default() *Config {
+	return &Config{
+		Addr:          ":8082",
+		MetricsAddr:   "localhost:9082",
+		LogFile:       "forum_gateway.log",
+		LogLevel:      "info",
+		LogMaxSize:    10,
+		LogMaxBackups: 5,
+		Timeout:       Duration(10 * time.Second),
+		TLSCert:       "crt/localhost/localhost.crt",
+		TLSKey:        "crt/localhost/localhost.decrypted.key",
+		AppURL:        "http://localhost:8080",
+		AuthURL:       "http://localhost:8081",
+		Client: Client{
+			Timeout: Duration(5 * time.Second),
+			Retries: 2,
+			Backoff: Duration(100 * time.Millisecond),
+		},
+		GatewayURL: "https://localhost:8082",
+	}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", "HTTP_ADDR", "`address` to listen on", (*stringValue)(&c.Addr)},
+		{"metrics-addr", "METRICS_ADDR", "`address` metrics are served on, none when empty", (*stringValue)(&c.MetricsAddr)},
+		{"log-file", "LOG_FILE", "`file` logs are appended to", (*stringValue)(&c.LogFile)},
+		{"log-level", "LOG_LEVEL", "least important `level` logged: debug, info, warn or error", (*stringValue)(&c.LogLevel)},
+		{"log-max-size", "LOG_MAX_SIZE", "`megabytes` the log file is rotated at", (*intValue)(&c.LogMaxSize)},
+		{"log-max-backups", "LOG_MAX_BACKUPS", "rotated log files kept", (*intValue)(&c.LogMaxBackups)},
+		{"timeout", "REQUEST_TIMEOUT", "time a request may take", &c.Timeout},
+		{"tls-cert", "TLS_CERT", "`file` of the TLS certificate", (*stringValue)(&c.TLSCert)},
+		{"tls-key", "TLS_KEY", "`file` of the TLS key", (*stringValue)(&c.TLSKey)},
+		{"app-url", "APP_URL", "base `URL` of forum_app", (*stringValue)(&c.AppURL)},
+		{"auth-url", "AUTH_URL", "base `URL` of forum_auth", (*stringValue)(&c.AuthURL)},
+		{"client-timeout", "CLIENT_TIMEOUT", "time an attempt of a call to another service may take", &c.Client.Timeout},
+		{"client-retries", "CLIENT_RETRIES", "retries of idempotent calls to another service", (*intValue)(&c.Client.Retries)},
+		{"client-backoff", "CLIENT_BACKOFF", "wait before the first retry, doubled with every further one", &c.Client.Backoff},
+		{"gateway-url", "GATEWAY_URL", "public `URL` of the gateway", (*stringValue)(&c.GatewayURL)},
+		{"csrf-secret", "CSRF_SECRET", "`secret` CSRF tokens are signed with, random when empty", (*stringValue)(&c.CSRFSecret)},
+	}
+}
+
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+		return fmt.Errorf("addr: %w", err)
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			return fmt.Errorf("metrics_addr: %w", err)
+		}
+	}
+	urls := []struct {
+		name  string
+		value *string
+	}{
+		{"app_url", &c.AppURL},
+		{"auth_url", &c.AuthURL},
+		{"gateway_url", &c.GatewayURL},
+	}
+	for _, u := range urls {
+		*u.value = strings.TrimRight(*u.value, "/")
+		if err := validateURL(*u.value); err != nil {
+			return fmt.Errorf("%s: %w", u.name, err)
+		}
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	switch {
+	case c.LogFile == "":
+		return errors.New("log_file: missing file")
+	case c.LogMaxSize <= 0:
+		return errors.New("log_max_size: must be positive")
+	case c.LogMaxBackups < 0:
+		return errors.New("log_max_backups: must not be negative")
+	case c.Timeout <= 0:
+		return errors.New("timeout: must be positive")
+	case c.Client.Timeout <= 0 || c.Client.Backoff <= 0:
+		return errors.New("client: timeout and backoff must be positive")
+	case c.Client.Retries < 0:
+		return errors.New("client.retries: must not be negative")
+	case c.TLSCert == "" || c.TLSKey == "":
+		return errors.New("tls_cert and tls_key: missing file")
+	}
+	return nil
+}
+
+// validateURL accepts absolute http and https URLs
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
+	return nil
+}
+
+func (c Client) Options(baseURL string) client.Options {
+	return client.Options{BaseURL: baseURL, Timeout: time.Duration(c.Timeout), Retries: c.Retries, Backoff: time.Duration(c.Backoff)}
+}
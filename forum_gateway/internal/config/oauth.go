@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// OAuth provider types
+const (
+	OAuthGitHub = "github"
+	OAuthGoogle = "google"
+	OAuthOIDC   = "oidc"
+)
+
+// OAuthConfig describes a provider users can sign in with, it is served at /sign-in/{Name}
+type OAuthConfig struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"display_name,omitempty"`
+	Type         string `json:"type"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// Issuer is where OpenID Connect providers are discovered
+	Issuer string   `json:"issuer,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// Icon replaces the name on the sign in page
+	Icon string `json:"icon,omitempty"`
+}
+
+var providerName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// addProviders appends to the providers of the config file the builtin ones of GOOGLE_* and GITHUB_*, then
+// those of OAUTH_PROVIDERS_FILE and then the OpenID Connect providers listed in OIDC_PROVIDERS, a later
+// provider replaces one of the same name
+func (c *Config) addProviders() error {
+	providers := c.Providers
+	if id := getEnv("GOOGLE_CLIENT_ID", ""); id != "" {
+		providers = append(providers, OAuthConfig{
+			Name:         "google",
+			DisplayName:  "Google",
+			Type:         OAuthGoogle,
+			ClientId:     id,
+			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+			Icon:         "/templates/img/google_auth_icon.jpg",
+		})
+	}
+	if id := getEnv("GITHUB_CLIENT_ID", ""); id != "" {
+		providers = append(providers, OAuthConfig{
+			Name:         "github",
+			DisplayName:  "GitHub",
+			Type:         OAuthGitHub,
+			ClientId:     id,
+			ClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
+			Icon:         "/templates/img/github_auth_icon.jpg",
+		})
+	}
+	if path := getEnv("OAUTH_PROVIDERS_FILE", ""); path != "" {
+		fromFile, err := readProviders(path)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, fromFile...)
+	}
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OAuthConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"NAME", ""),
+			Type:         OAuthOIDC,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientId:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "")),
+		})
+	}
+	c.Providers = nil
+	index := map[string]int{}
+	for _, p := range providers {
+		if err := validateProvider(&p); err != nil {
+			return err
+		}
+		if i, ok := index[p.Name]; ok {
+			c.Providers[i] = p
+			continue
+		}
+		index[p.Name] = len(c.Providers)
+		c.Providers = append(c.Providers, p)
+	}
+	return nil
+}
+
+func readProviders(path string) ([]OAuthConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	providers := []OAuthConfig{}
+	if err = json.NewDecoder(file).Decode(&providers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return providers, nil
+}
+
+func validateProvider(p *OAuthConfig) error {
+	p.Name = strings.ToLower(p.Name)
+	if !providerName.MatchString(p.Name) {
+		return fmt.Errorf("invalid OAuth provider name %q", p.Name)
+	}
+	if p.DisplayName == "" {
+		p.DisplayName = p.Name
+	}
+	switch {
+	case p.Type != OAuthGitHub && p.Type != OAuthGoogle && p.Type != OAuthOIDC:
+		return fmt.Errorf("OAuth provider %s: invalid type %q", p.Name, p.Type)
+	case p.ClientId == "":
+		return fmt.Errorf("OAuth provider %s: missing client id", p.Name)
+	case p.Type == OAuthOIDC && p.Issuer == "":
+		return fmt.Errorf("OAuth provider %s: missing issuer", p.Name)
+	}
+	return nil
+}
+
+func getEnv(key string, defaultVal string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultVal
+}
+
+func SetEnv() error {
+	file, err := os.Open(".env")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.Split(scanner.Text(), "=")
+		if len(line) == 2 {
+			key, value := strings.TrimSpace(line[0]), strings.Trim(strings.TrimSpace(line[1]), "\"")
+			if key != "" && value != "" {
+				os.Setenv(key, value)
+			}
+		}
+	}
+	return scanner.Err()
+}
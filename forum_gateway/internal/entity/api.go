@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// PostInput is the JSON body the API creates and edits posts with
+type PostInput struct {
+	Title      string `json:"title"`
+	Content    string `json:"content"`
+	Categories []int  `json:"categories"`
+}
+
+// Post validates the input like the form of a new post, edits don't need categories
+func (p PostInput) Post(edit bool) (Post, error) {
+	if strings.TrimSpace(p.Title) == "" {
+		return Post{}, errors.New("Empty title")
+	}
+	post := Post{Title: p.Title, Content: p.Content}
+	if edit {
+		return post, nil
+	}
+	if len(p.Categories) == 0 {
+		return Post{}, errors.New("No category has been chosen")
+	}
+	for _, id := range p.Categories {
+		if id <= 0 {
+			return Post{}, errors.New("Invalid category")
+		}
+		post.Category = append(post.Category, Category{Id: id})
+	}
+	return post, nil
+}
+
+type CommentInput struct {
+	Content  string `json:"content"`
+	ParentId int    `json:"parent_id"`
+}
+
+func (c CommentInput) Comment(postId int, userId int64) (Comment, error) {
+	if strings.TrimSpace(c.Content) == "" {
+		return Comment{}, ErrEmptyComment
+	}
+	if c.ParentId < 0 {
+		return Comment{}, ErrBadRequest
+	}
+	return Comment{Post: Post{Id: postId}, User: User{Id: userId}, Content: c.Content, ParentId: c.ParentId}, nil
+}
+
+// ReactionInput likes or dislikes, the same reaction twice takes it back; comments also need their post
+type ReactionInput struct {
+	Like   *bool `json:"like"`
+	PostId int   `json:"post_id"`
+}
+
+// SignUpInput is Credentials with the confirmation the sign up form asks for
+type SignUpInput struct {
+	Name            string `json:"name"`
+	Email           string `json:"email"`
+	Password        string `json:"password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+type SignInInput struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me"`
+}
+
+type TwoFactorInput struct {
+	Token string `json:"two_factor_token"`
+	Code  string `json:"code"`
+}
+
+// SessionOutput is what the API tells a client about the session it signed in to
+type SessionOutput struct {
+	Token      string    `json:"token"`
+	UserId     int64     `json:"user_id"`
+	Role       string    `json:"role,omitempty"`
+	ExpiryTime time.Time `json:"expiry_time"`
+	Persistent bool      `json:"persistent"`
+}
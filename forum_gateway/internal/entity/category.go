@@ -0,0 +1,70 @@
+package entity
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type CategoryChange struct {
+	AdminId  int64  `json:"admin_id,omitempty"`
+	Id       int    `json:"id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	ParentId int    `json:"parent_id,omitempty"`
+	Archived bool   `json:"archived,omitempty"`
+	Order    []int  `json:"order,omitempty"`
+}
+
+// GetCategoryChange reads the category forms, fields a form doesn't have are left empty
+func GetCategoryChange(r *http.Request) (CategoryChange, error) {
+	change := CategoryChange{
+		Title:    strings.TrimSpace(r.FormValue("title")),
+		Archived: r.FormValue("archived") == "1",
+	}
+	change.AdminId, _ = r.Context().Value("user_id").(int64)
+	var err error
+	if id := r.FormValue("id"); id != "" {
+		if change.Id, err = strconv.Atoi(id); err != nil {
+			return CategoryChange{}, ErrBadRequest
+		}
+	}
+	if parent := r.FormValue("parent_id"); parent != "" {
+		if change.ParentId, err = strconv.Atoi(parent); err != nil {
+			return CategoryChange{}, ErrBadRequest
+		}
+	}
+	return change, nil
+}
+
+type CategoryNode struct {
+	Id            int            `json:"id,omitempty"`
+	ParentId      int            `json:"parent_id,omitempty"`
+	Subcategories []CategoryNode `json:"subcategories,omitempty"`
+}
+
+// MoveCategory swaps the category with its previous or next sibling and returns
+// the new order of the siblings, ok is false when there is nowhere to move
+func MoveCategory(tree []CategoryNode, id int, up bool) (parentId int, order []int, ok bool) {
+	for ix, node := range tree {
+		if node.Id != id {
+			if parentId, order, ok = MoveCategory(node.Subcategories, id, up); order != nil {
+				return parentId, order, ok
+			}
+			continue
+		}
+		order = make([]int, len(tree))
+		for jx := range tree {
+			order[jx] = tree[jx].Id
+		}
+		other := ix + 1
+		if up {
+			other = ix - 1
+		}
+		if other < 0 || other >= len(order) {
+			return node.ParentId, order, false
+		}
+		order[ix], order[other] = order[other], order[ix]
+		return node.ParentId, order, true
+	}
+	return 0, nil, false
+}
@@ -8,10 +8,11 @@ import (
 )
 
 type Comment struct {
-	Id      int    `json:"id,omitempty"`
-	Post    Post   `json:"post,omitempty"`
-	User    User   `json:"user,omitempty"`
-	Content string `json:"comment_content,omitempty"`
+	Id       int    `json:"id,omitempty"`
+	Post     Post   `json:"post,omitempty"`
+	User     User   `json:"user,omitempty"`
+	Content  string `json:"comment_content,omitempty"`
+	ParentId int    `json:"parent_id,omitempty"`
 }
 
 func GetComment(r *http.Request) CommentResult {
@@ -26,6 +27,12 @@ func GetComment(r *http.Request) CommentResult {
 		return CommentResult{Err: err}
 	}
 	commentRes.Comment.Post = Post{Id: post_id}
+	if parent := r.FormValue("parent_id"); parent != "" {
+		commentRes.Comment.ParentId, err = strconv.Atoi(parent)
+		if err != nil {
+			return CommentResult{Err: err}
+		}
+	}
 	commentRes.Comment.Content = r.FormValue("content")
 	if strings.TrimSpace(commentRes.Comment.Content) == "" {
 		return CommentResult{Err: ErrEmptyComment}
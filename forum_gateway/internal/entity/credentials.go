@@ -1,7 +1,6 @@
 package entity
 
 import (
-	"fmt"
 	"net/http"
 	"regexp"
 	"unicode"
@@ -12,6 +11,15 @@ type Credentials struct {
 	Login    string `json:"login,omitempty"`
 	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"`
+	// UserAgent and IP describe the device the session is opened on
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	// RememberMe asks for a long-lived persistent session
+	RememberMe bool `json:"remember_me,omitempty"`
+	// Provider and Subject identify an OAuth account, Verified tells whether the provider has verified its email
+	Provider string `json:"provider,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Verified bool   `json:"email_verified,omitempty"`
 }
 
 type CredentialsResult struct {
@@ -21,29 +29,45 @@ type CredentialsResult struct {
 
 func GetCredentials(r *http.Request) Credentials {
 	return Credentials{
-		Name:     r.FormValue("name"),
-		Email:    r.FormValue("email"),
-		Password: r.FormValue("password"),
+		Name:       r.FormValue("name"),
+		Email:      r.FormValue("email"),
+		Password:   r.FormValue("password"),
+		RememberMe: r.FormValue("remember_me") != "",
 	}
 }
 
 func (c Credentials) ValidateSignUp(confirm_password string) (bool, string) {
 	if !validName(c.Name) {
 		return false, "Invalid name format\nName should be at least 5 symbols long and shouldn't contain empty space"
-	} else if !validEmail(c.Email) {
+	} else if !ValidEmail(c.Email) {
 		return false, "Invalid email format"
 	} else if !validPassword(c.Password) {
 		return false, "Invalid password format\nPassword should contain at least one number, one uppercase letter, one lowercase letter, one symbol or punctuation and at least 8 symbols"
 	} else if c.Password != confirm_password {
-		fmt.Println(c.Password)
-		fmt.Println(confirm_password)
+		return false, "Passwords don't match"
+	}
+	return true, ""
+}
+
+// PasswordReset carries the token from the reset link and the new password
+type PasswordReset struct {
+	Token    string `json:"token,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func (p PasswordReset) Validate(confirm_password string) (bool, string) {
+	if p.Token == "" {
+		return false, "Invalid or expired token"
+	} else if !validPassword(p.Password) {
+		return false, "Invalid password format\nPassword should contain at least one number, one uppercase letter, one lowercase letter, one symbol or punctuation and at least 8 symbols"
+	} else if p.Password != confirm_password {
 		return false, "Passwords don't match"
 	}
 	return true, ""
 }
 
 func (c Credentials) ValidateSignIn() (bool, string) {
-	if !validEmail(c.Email) {
+	if !ValidEmail(c.Email) {
 		return false, "Invalid email format"
 	} else if !validPassword(c.Password) {
 		return false, "Invalid password format\nPassword should contain at least one number, one uppercase letter, one lowercase letter, one symbol or punctuation and at least 8 symbols"
@@ -63,7 +87,7 @@ func validName(name string) bool {
 	return true
 }
 
-func validEmail(email string) bool {
+func ValidEmail(email string) bool {
 	return regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`).MatchString(email)
 }
 
@@ -10,4 +10,16 @@ var (
 	ErrEmailExists     = errors.New("Email already exists")
 	ErrBadRequest      = errors.New("Bad Request")
 	ErrEmptyComment    = errors.New("Empty comment")
+	ErrForbidden       = errors.New("Forbidden")
+	ErrInvalidToken    = errors.New("Invalid or expired token")
+	// identity errors, they carry the messages of forum_auth
+	ErrIdentityConflict = errors.New("Email belongs to another account")
+	ErrIdentityLinked   = errors.New("Identity is linked to another account")
+	ErrLastIdentity     = errors.New("Last way to sign in")
+	// sign in errors, forum_auth doesn't tell unknown emails and wrong passwords apart
+	ErrInvalidCredentials = errors.New("Invalid email or password")
+	ErrLockedOut          = errors.New("Too many failed sign in attempts")
+	// two-factor errors
+	ErrInvalidCode      = errors.New("Invalid code")
+	ErrTwoFactorEnabled = errors.New("Two-factor authentication is already enabled")
 )
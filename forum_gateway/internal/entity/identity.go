@@ -0,0 +1,8 @@
+package entity
+
+// IdentityRequest links the OAuth account of Credentials to the owner of Token, or unlinks the one of Provider
+type IdentityRequest struct {
+	Token       string      `json:"token,omitempty"`
+	Provider    string      `json:"provider,omitempty"`
+	Credentials Credentials `json:"credentials,omitempty"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type ModerationAction struct {
+	Moderator User   `json:"moderator,omitempty"`
+	Target    string `json:"target,omitempty"`
+	TargetId  int    `json:"target_id,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func GetModerationAction(r *http.Request) (ModerationAction, error) {
+	action := ModerationAction{
+		Target: r.FormValue("target"),
+		Action: r.FormValue("action"),
+		Reason: strings.TrimSpace(r.FormValue("reason")),
+	}
+	action.Moderator.Id, _ = r.Context().Value("user_id").(int64)
+	var err error
+	action.TargetId, err = strconv.Atoi(r.FormValue("target_id"))
+	if err != nil {
+		return ModerationAction{}, errors.New("Invalid target")
+	}
+	if action.Reason == "" {
+		return ModerationAction{}, errors.New("Reason is required")
+	}
+	return action, nil
+}
+
+type RoleUpdate struct {
+	AdminId int64  `json:"admin_id,omitempty"`
+	UserId  int    `json:"user_id,omitempty"`
+	Role    string `json:"role,omitempty"`
+}
+
+func GetRoleUpdate(r *http.Request) (RoleUpdate, error) {
+	update := RoleUpdate{Role: r.FormValue("role")}
+	update.AdminId, _ = r.Context().Value("user_id").(int64)
+	var err error
+	update.UserId, err = strconv.Atoi(r.FormValue("user_id"))
+	if err != nil || !ValidRole(update.Role) {
+		return RoleUpdate{}, ErrBadRequest
+	}
+	return update, nil
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// NotificationRead marks one notification of the user as read, or all of them when Id is 0
+type NotificationRead struct {
+	UserId int64 `json:"user_id,omitempty"`
+	Id     int   `json:"id,omitempty"`
+}
+
+// GetNotificationRead reads the forms of the notifications page, all=1 marks every notification
+func GetNotificationRead(r *http.Request) (NotificationRead, error) {
+	read := NotificationRead{}
+	read.UserId, _ = r.Context().Value("user_id").(int64)
+	if r.FormValue("all") == "1" {
+		return read, nil
+	}
+	var err error
+	if read.Id, err = strconv.Atoi(r.FormValue("id")); err != nil || read.Id <= 0 {
+		return NotificationRead{}, ErrBadRequest
+	}
+	return read, nil
+}
+
+type UnreadResult struct {
+	Unread int
+	Err    error
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Page holds the listing parameters forwarded to forum_app, which validates them
+type Page struct {
+	Limit  string
+	Cursor string
+	Sort   string
+}
+
+func GetPage(r *http.Request) Page {
+	query := r.URL.Query()
+	return Page{
+		Limit:  query.Get("limit"),
+		Cursor: query.Get("cursor"),
+		Sort:   query.Get("sort"),
+	}
+}
+
+func (p Page) Query() string {
+	values := url.Values{}
+	if p.Limit != "" {
+		values.Set("limit", p.Limit)
+	}
+	if p.Cursor != "" {
+		values.Set("cursor", p.Cursor)
+	}
+	if p.Sort != "" {
+		values.Set("sort", p.Sort)
+	}
+	return values.Encode()
+}
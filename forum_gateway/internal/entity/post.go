@@ -44,3 +44,13 @@ func GetPost(r *http.Request) (Post, error) {
 	post.Content = r.FormValue("content")
 	return post, nil
 }
+
+func GetPostUpdate(r *http.Request) (Post, error) {
+	post := Post{}
+	post.Title = r.FormValue("title")
+	if strings.TrimSpace(post.Title) == "" {
+		return Post{}, errors.New("Empty title")
+	}
+	post.Content = r.FormValue("content")
+	return post, nil
+}
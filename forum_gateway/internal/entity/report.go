@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var ErrAlreadyReported = errors.New("Already reported")
+
+type Report struct {
+	Reporter User   `json:"reporter,omitempty"`
+	Target   string `json:"target,omitempty"`
+	TargetId int    `json:"target_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Details  string `json:"details,omitempty"`
+}
+
+// GetReport reads a report form, post_id is where the reporter returns to
+func GetReport(r *http.Request) (Report, int, error) {
+	report := Report{
+		Target:  r.FormValue("target"),
+		Reason:  r.FormValue("reason"),
+		Details: strings.TrimSpace(r.FormValue("details")),
+	}
+	report.Reporter.Id, _ = r.Context().Value("user_id").(int64)
+	var err error
+	if report.TargetId, err = strconv.Atoi(r.FormValue("target_id")); err != nil {
+		return Report{}, 0, ErrBadRequest
+	}
+	postId, err := strconv.Atoi(r.FormValue("post_id"))
+	if err != nil || (report.Target != "post" && report.Target != "comment") || report.Reason == "" {
+		return Report{}, 0, ErrBadRequest
+	}
+	return report, postId, nil
+}
+
+type ReportResult struct {
+	Hidden bool
+	Err    error
+}
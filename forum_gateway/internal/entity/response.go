@@ -5,5 +5,7 @@ type Response struct {
 	UserId       int64       `json:"user_id,omitempty"`
 	ErrorMessage string      `json:"error,omitempty"`
 	AuthStatus   bool        `json:"authorised,omitempty"`
+	Role         string      `json:"role,omitempty"`
+	Unread       int         `json:"unread,omitempty"`
 	Body         interface{} `json:"body,omitempty"`
 }
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// forum_app marks matched terms in search snippets with these control characters
+const (
+	SnippetStart = "\x02"
+	SnippetEnd   = "\x03"
+)
+
+var searchParams = []string{"q", "category", "author", "from", "to", "offset"}
+
+// GetSearchQuery keeps the search parameters forwarded to forum_app, which validates them
+func GetSearchQuery(r *http.Request) url.Values {
+	query := url.Values{}
+	for _, key := range searchParams {
+		if value := strings.TrimSpace(r.URL.Query().Get(key)); value != "" {
+			query.Set(key, value)
+		}
+	}
+	return query
+}
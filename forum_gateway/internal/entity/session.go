@@ -5,14 +5,32 @@ import (
 )
 
 type Session struct {
+	Id         int64     `json:"id,omitempty"`
 	Token      string    `json:"token,omitempty"`
 	UserId     int64     `json:"user_id,omitempty"`
+	Role       string    `json:"role,omitempty"`
 	ExpiryTime time.Time `json:"expiry_time,omitempty"`
+	Persistent bool      `json:"persistent,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	LastSeen   time.Time `json:"last_seen,omitempty"`
+	Current    bool      `json:"current,omitempty"`
 }
 
+// SessionRevoke signs out the session with Id, the request is made on behalf of the owner of Token
+type SessionRevoke struct {
+	Token string `json:"token,omitempty"`
+	Id    int64  `json:"id,omitempty"`
+}
+
+// SessionResult has a Pending sign in instead of a Session when the user still has to enter a code,
+// LockedUntil says when to retry after ErrLockedOut
 type SessionResult struct {
-	Session Session
-	Err     error
+	Session     Session
+	Pending     PendingSignIn
+	LockedUntil time.Time
+	Err         error
 }
 
 type AuthStatus int
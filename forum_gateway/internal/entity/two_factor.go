@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// PendingSignIn is a sign in forum_auth holds until the user enters the code of their authenticator app
+type PendingSignIn struct {
+	Token      string    `json:"two_factor_token,omitempty"`
+	ExpiryTime time.Time `json:"expiry_time,omitempty"`
+}
+
+// TwoFactorRequest carries a code on behalf of the owner of Token, the token of a session or of a pending
+// sign in; admins reset the two-factor authentication of UserId
+type TwoFactorRequest struct {
+	Token     string `json:"token,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Password  string `json:"password,omitempty"`
+	UserId    int64  `json:"user_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
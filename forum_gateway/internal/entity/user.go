@@ -4,6 +4,45 @@ type User struct {
 	Id int64 `json:"id,omitempty"`
 }
 
+// PublicUser is what the API shows of anyone's profile, the email is only in the owner's /me
+type PublicUser struct {
+	Id                   int64         `json:"id"`
+	Name                 string        `json:"name"`
+	RegDate              string        `json:"registration_date,omitempty"`
+	Role                 string        `json:"role,omitempty"`
+	Posts                []interface{} `json:"posts,omitempty"`
+	TotalPosts           int           `json:"total_posts,omitempty"`
+	Comments             []interface{} `json:"comments,omitempty"`
+	TotalComments        int           `json:"total_comments,omitempty"`
+	PostLikes            []interface{} `json:"post_likes,omitempty"`
+	TotalPostLikes       int           `json:"total_post_likes,omitempty"`
+	PostDislikes         []interface{} `json:"post_dislikes,omitempty"`
+	TotalPostDislikes    int           `json:"total_post_dislikes,omitempty"`
+	CommentLikes         []interface{} `json:"comment_likes,omitempty"`
+	TotalCommentLikes    int           `json:"total_comment_likes,omitempty"`
+	CommentDislikes      []interface{} `json:"comment_dislikes,omitempty"`
+	TotalCommentDislikes int           `json:"total_comment_dislikes,omitempty"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleRanks orders the roles, every role has the powers of the roles below it
+var roleRanks = map[string]int{RoleUser: 1, RoleModerator: 2, RoleAdmin: 3}
+
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether role has at least the powers of min
+func HasRole(role, min string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[min]
+}
+
 type Result struct {
 	Id  int
 	Err error
@@ -4,18 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"forum_gateway/internal/client"
 	"forum_gateway/internal/entity"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type AuthUsecase struct {
+	auth    *client.Auth
 	errLog  *log.Logger
 	infoLog *log.Logger
 }
 
-func NewAuthUsecase(errLog, infoLog *log.Logger) *AuthUsecase {
-	return &AuthUsecase{errLog: errLog, infoLog: infoLog}
+func NewAuthUsecase(auth *client.Auth, errLog, infoLog *log.Logger) *AuthUsecase {
+	return &AuthUsecase{auth: auth, errLog: errLog, infoLog: infoLog}
+}
+
+func (au *AuthUsecase) Ready(ctx context.Context, errChan chan error) {
+	errChan <- au.auth.Ready(ctx)
 }
 
 func (au *AuthUsecase) SignUp(ctx context.Context, credentials entity.Credentials, errChan chan error) {
@@ -24,7 +33,7 @@ func (au *AuthUsecase) SignUp(ctx context.Context, credentials entity.Credential
 		errChan <- entity.ErrInternalServer
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8081/sign_up", requestBody)
+	response, err := au.auth.Do(ctx, http.MethodPost, "/sign_up", requestBody)
 	if err != nil {
 		errChan <- entity.ErrInternalServer
 		return
@@ -59,29 +68,29 @@ func (au *AuthUsecase) SignIn(ctx context.Context, credentials entity.Credential
 		sessionChan <- entity.SessionResult{Err: err}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8081/sign_in", requestBody)
+	response, err := au.auth.Do(ctx, http.MethodPost, "/sign_in", requestBody)
 	if err != nil {
 		au.errLog.Println(err)
 		sessionChan <- entity.SessionResult{Err: entity.ErrInternalServer}
 		return
 	}
 	switch response.StatusCode {
-	case 500:
+	case 500, 400:
 		sessionChan <- entity.SessionResult{Err: entity.ErrInternalServer}
 		return
-	case 404:
-		sessionChan <- entity.SessionResult{Err: entity.ErrNotFound}
+	case 401:
+		sessionChan <- entity.SessionResult{Err: entity.ErrInvalidCredentials}
+		return
+	case 429:
+		sessionChan <- entity.SessionResult{LockedUntil: lockedUntil(response), Err: entity.ErrLockedOut}
 		return
 	case 408:
 		sessionChan <- entity.SessionResult{Err: entity.ErrRequestTimeout}
 		return
-	case 400:
-		r, _ := getResponse(response.Body) // err checking omitted because it returns Internal Server Error anyway
-		if r.ErrorMessage == "Invalid Password" {
-			sessionChan <- entity.SessionResult{Err: entity.ErrInvalidPassword}
-			return
-		}
-		sessionChan <- entity.SessionResult{Err: entity.ErrInternalServer}
+	}
+	if response.StatusCode == 202 {
+		pending, err := getPendingSignIn(response.Body)
+		sessionChan <- entity.SessionResult{Pending: pending, Err: err}
 		return
 	}
 	session, err := getSession(response.Body)
@@ -93,7 +102,7 @@ func (au *AuthUsecase) Authenticate(ctx context.Context, token string, authChan
 		authChan <- entity.AuthStatusResult{Status: entity.NonAuthorised}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodGet, "http://localhost:8081/authenticate", []byte(fmt.Sprintf(`{"token":"%s"}`, token)))
+	response, err := au.auth.Do(ctx, http.MethodGet, "/authenticate", []byte(fmt.Sprintf(`{"token":"%s"}`, token)))
 	if err != nil {
 		authChan <- entity.AuthStatusResult{Status: entity.NonAuthorised}
 		return
@@ -114,7 +123,7 @@ func (au *AuthUsecase) SignOut(ctx context.Context, session entity.Session, errC
 		errChan <- entity.ErrInternalServer
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodDelete, "http://localhost:8081/sign_out", requestBody)
+	response, err := au.auth.Do(ctx, http.MethodDelete, "/sign_out", requestBody)
 	if err != nil {
 		errChan <- entity.ErrInternalServer
 		return
@@ -139,7 +148,7 @@ func (au *AuthUsecase) OAuth(ctx context.Context, credentials entity.Credentials
 		sessionChan <- entity.SessionResult{Err: err}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8081/oauth_signin", requestBody)
+	response, err := au.auth.Do(ctx, http.MethodPost, "/oauth_signin", requestBody)
 	if err != nil {
 		au.errLog.Println(err)
 		sessionChan <- entity.SessionResult{Err: entity.ErrInternalServer}
@@ -155,6 +164,9 @@ func (au *AuthUsecase) OAuth(ctx context.Context, credentials entity.Credentials
 	case 408:
 		sessionChan <- entity.SessionResult{Err: entity.ErrRequestTimeout}
 		return
+	case 409:
+		sessionChan <- entity.SessionResult{Err: getIdentityError(response.Body)}
+		return
 	case 400:
 		r, err := getResponse(response.Body)
 		if err != nil {
@@ -168,6 +180,276 @@ func (au *AuthUsecase) OAuth(ctx context.Context, credentials entity.Credentials
 		sessionChan <- entity.SessionResult{Err: entity.ErrInternalServer}
 		return
 	}
+	if response.StatusCode == 202 {
+		pending, err := getPendingSignIn(response.Body)
+		sessionChan <- entity.SessionResult{Pending: pending, Err: err}
+		return
+	}
+	session, err := getSession(response.Body)
+	sessionChan <- entity.SessionResult{Session: session, Err: err}
+}
+
+func (au *AuthUsecase) ForgotPassword(ctx context.Context, email string, errChan chan error) {
+	errChan <- au.postAccount(ctx, "/password/forgot", entity.Credentials{Email: email})
+}
+
+func (au *AuthUsecase) ResetPassword(ctx context.Context, reset entity.PasswordReset, errChan chan error) {
+	errChan <- au.postAccount(ctx, "/password/reset", reset)
+}
+
+func (au *AuthUsecase) SendVerification(ctx context.Context, userId int64, errChan chan error) {
+	errChan <- au.postAccount(ctx, "/email/send_verification", map[string]int64{"user_id": userId})
+}
+
+func (au *AuthUsecase) VerifyEmail(ctx context.Context, token string, errChan chan error) {
+	errChan <- au.postAccount(ctx, "/email/verify", map[string]string{"token": token})
+}
+
+// postAccount sends one of the password reset and email verification requests to forum_auth
+func (au *AuthUsecase) postAccount(ctx context.Context, path string, request interface{}) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	response, err := au.auth.Do(ctx, http.MethodPost, path, requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		return entity.ErrInternalServer
+	}
+	switch response.StatusCode {
+	case 204:
+		return nil
+	case 400:
+		r, _ := getResponse(response.Body)
+		if r.ErrorMessage == "Invalid or expired token" {
+			return entity.ErrInvalidToken
+		}
+		return entity.ErrBadRequest
+	case 404:
+		return entity.ErrNotFound
+	case 408:
+		return entity.ErrRequestTimeout
+	default:
+		return entity.ErrInternalServer
+	}
+}
+
+func (au *AuthUsecase) FetchSessions(ctx context.Context, token string, responseChan chan entity.Response) {
+	requestBody, err := json.Marshal(entity.Session{Token: token})
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	response, err := au.auth.Do(ctx, http.MethodGet, "/sessions", requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	if response.StatusCode != 200 {
+		responseChan <- entity.Response{Err: getSessionStatus(response.StatusCode)}
+		return
+	}
+	result, err := getResponse(response.Body)
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	responseChan <- result
+}
+
+func (au *AuthUsecase) RevokeSession(ctx context.Context, revoke entity.SessionRevoke, errChan chan error) {
+	errChan <- au.deleteSessions(ctx, "/sessions/revoke", revoke)
+}
+
+func (au *AuthUsecase) RevokeOtherSessions(ctx context.Context, token string, errChan chan error) {
+	errChan <- au.deleteSessions(ctx, "/sessions/revoke_others", entity.Session{Token: token})
+}
+
+func (au *AuthUsecase) deleteSessions(ctx context.Context, path string, request interface{}) error {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	response, err := au.auth.Do(ctx, http.MethodDelete, path, requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		return entity.ErrInternalServer
+	}
+	return getSessionStatus(response.StatusCode)
+}
+
+// lockedUntil reads when a lockout ends from the Retry-After of forum_auth
+func lockedUntil(response *http.Response) time.Time {
+	seconds, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+func getSessionStatus(statusCode int) error {
+	switch statusCode {
+	case 200, 204:
+		return nil
+	case 401:
+		return entity.ErrForbidden
+	case 404:
+		return entity.ErrNotFound
+	case 408:
+		return entity.ErrRequestTimeout
+	default:
+		return entity.ErrInternalServer
+	}
+}
+
+func (au *AuthUsecase) FetchIdentities(ctx context.Context, token string, responseChan chan entity.Response) {
+	requestBody, err := json.Marshal(entity.Session{Token: token})
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	response, err := au.auth.Do(ctx, http.MethodGet, "/identities", requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	if response.StatusCode != 200 {
+		responseChan <- entity.Response{Err: getSessionStatus(response.StatusCode)}
+		return
+	}
+	result, err := getResponse(response.Body)
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	responseChan <- result
+}
+
+func (au *AuthUsecase) LinkIdentity(ctx context.Context, req entity.IdentityRequest, errChan chan error) {
+	errChan <- au.identityRequest(ctx, http.MethodPost, "/identities/link", req)
+}
+
+func (au *AuthUsecase) UnlinkIdentity(ctx context.Context, req entity.IdentityRequest, errChan chan error) {
+	errChan <- au.identityRequest(ctx, http.MethodDelete, "/identities/unlink", req)
+}
+
+func (au *AuthUsecase) identityRequest(ctx context.Context, method, path string, req entity.IdentityRequest) error {
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	response, err := au.auth.Do(ctx, method, path, requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		return entity.ErrInternalServer
+	}
+	if response.StatusCode == 409 {
+		return getIdentityError(response.Body)
+	}
+	return getSessionStatus(response.StatusCode)
+}
+
+// getIdentityError tells the conflicts of linking identities apart by the message of forum_auth
+func getIdentityError(body io.ReadCloser) error {
+	r, err := getResponse(body)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	for _, e := range []error{entity.ErrIdentityConflict, entity.ErrIdentityLinked, entity.ErrLastIdentity} {
+		if r.ErrorMessage == e.Error() {
+			return e
+		}
+	}
+	return entity.ErrInternalServer
+}
+
+// VerifyTwoFactor finishes the pending sign in of the request with its code
+func (au *AuthUsecase) VerifyTwoFactor(ctx context.Context, req entity.TwoFactorRequest, sessionChan chan entity.SessionResult) {
+	response, err := au.twoFactorRequest(ctx, http.MethodPost, "/sign_in/two_factor", req)
+	if err == nil && response.StatusCode == 429 {
+		sessionChan <- entity.SessionResult{LockedUntil: lockedUntil(response), Err: entity.ErrLockedOut}
+		return
+	}
+	if err == nil && response.StatusCode != 201 {
+		err = getTwoFactorError(response)
+	}
+	if err != nil {
+		sessionChan <- entity.SessionResult{Err: err}
+		return
+	}
 	session, err := getSession(response.Body)
 	sessionChan <- entity.SessionResult{Session: session, Err: err}
 }
+
+func (au *AuthUsecase) FetchTwoFactor(ctx context.Context, token string, responseChan chan entity.Response) {
+	responseChan <- au.twoFactorResponse(ctx, http.MethodGet, "/two_factor", entity.TwoFactorRequest{Token: token})
+}
+
+func (au *AuthUsecase) SetupTwoFactor(ctx context.Context, token string, responseChan chan entity.Response) {
+	responseChan <- au.twoFactorResponse(ctx, http.MethodPost, "/two_factor/setup", entity.TwoFactorRequest{Token: token})
+}
+
+func (au *AuthUsecase) EnableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, responseChan chan entity.Response) {
+	responseChan <- au.twoFactorResponse(ctx, http.MethodPost, "/two_factor/enable", req)
+}
+
+func (au *AuthUsecase) DisableTwoFactor(ctx context.Context, req entity.TwoFactorRequest, errChan chan error) {
+	errChan <- au.twoFactorResponse(ctx, http.MethodDelete, "/two_factor/disable", req).Err
+}
+
+func (au *AuthUsecase) ResetTwoFactor(ctx context.Context, req entity.TwoFactorRequest, errChan chan error) {
+	errChan <- au.twoFactorResponse(ctx, http.MethodDelete, "/admin/two_factor/reset", req).Err
+}
+
+func (au *AuthUsecase) twoFactorRequest(ctx context.Context, method, path string, req entity.TwoFactorRequest) (*http.Response, error) {
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, entity.ErrInternalServer
+	}
+	response, err := au.auth.Do(ctx, method, path, requestBody)
+	if err != nil {
+		au.errLog.Println(err)
+		return nil, entity.ErrInternalServer
+	}
+	return response, nil
+}
+
+// twoFactorResponse decodes the body of a 200 answer, 204 has none
+func (au *AuthUsecase) twoFactorResponse(ctx context.Context, method, path string, req entity.TwoFactorRequest) entity.Response {
+	response, err := au.twoFactorRequest(ctx, method, path, req)
+	if err != nil {
+		return entity.Response{Err: err}
+	}
+	switch response.StatusCode {
+	case 200:
+		result, err := getResponse(response.Body)
+		if err != nil {
+			return entity.Response{Err: entity.ErrInternalServer}
+		}
+		return result
+	case 204:
+		return entity.Response{}
+	}
+	return entity.Response{Err: getTwoFactorError(response)}
+}
+
+// getTwoFactorError tells wrong codes and expired sign ins apart by the message of forum_auth
+func getTwoFactorError(response *http.Response) error {
+	switch response.StatusCode {
+	case 400:
+		r, _ := getResponse(response.Body)
+		switch r.ErrorMessage {
+		case entity.ErrInvalidCode.Error():
+			return entity.ErrInvalidCode
+		case entity.ErrInvalidToken.Error():
+			return entity.ErrInvalidToken
+		case entity.ErrInvalidCredentials.Error():
+			return entity.ErrInvalidCredentials
+		}
+		return entity.ErrBadRequest
+	case 409:
+		return entity.ErrTwoFactorEnabled
+	case 429:
+		return entity.ErrLockedOut
+	}
+	return getSessionStatus(response.StatusCode)
+}
@@ -4,21 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"forum_gateway/internal/client"
 	"forum_gateway/internal/entity"
 	"log"
 	"net/http"
+	"net/url"
 )
 
 type ForumUsecase struct {
+	app    *client.App
 	errLog *log.Logger
 }
 
-func NewForumUsecase(errLog *log.Logger) *ForumUsecase {
-	return &ForumUsecase{errLog: errLog}
+func NewForumUsecase(app *client.App, errLog *log.Logger) *ForumUsecase {
+	return &ForumUsecase{app: app, errLog: errLog}
 }
 
-func (f *ForumUsecase) FetchPosts(ctx context.Context, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, "http://localhost:8080/posts", []byte{})
+func (f *ForumUsecase) Ready(ctx context.Context, errChan chan error) {
+	errChan <- f.app.Ready(ctx)
+}
+
+func (f *ForumUsecase) FetchPosts(ctx context.Context, page entity.Page, responseChan chan entity.Response) {
+	response, err := f.app.Do(ctx, http.MethodGet, "/posts?"+page.Query(), []byte{})
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -26,6 +33,8 @@ func (f *ForumUsecase) FetchPosts(ctx context.Context, responseChan chan entity.
 	switch response.StatusCode {
 	case 408:
 		responseChan <- entity.Response{Err: entity.ErrRequestTimeout}
+	case 400:
+		responseChan <- entity.Response{Err: entity.ErrBadRequest}
 	case 200:
 		result, err := getResponse(response.Body)
 		if err != nil {
@@ -39,7 +48,7 @@ func (f *ForumUsecase) FetchPosts(ctx context.Context, responseChan chan entity.
 }
 
 func (f *ForumUsecase) FetchPost(ctx context.Context, id int, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/post?id=%d", id), []byte{})
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/post?id=%d", id), []byte{})
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -67,7 +76,7 @@ func (f *ForumUsecase) StorePost(ctx context.Context, post entity.Post, resChan
 		resChan <- entity.Result{Err: entity.ErrInternalServer}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/post/save", body)
+	response, err := f.app.Do(ctx, http.MethodPost, "/post/save", body)
 	if err != nil {
 		resChan <- entity.Result{Err: entity.ErrInternalServer}
 		return
@@ -90,13 +99,58 @@ func (f *ForumUsecase) StorePost(ctx context.Context, post entity.Post, resChan
 	}
 }
 
+func (f *ForumUsecase) UpdatePost(ctx context.Context, post entity.Post, errChan chan error) {
+	body, err := json.Marshal(post)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPut, "/post/update", body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
+
+func (f *ForumUsecase) DeletePost(ctx context.Context, post entity.Post, errChan chan error) {
+	body, err := json.Marshal(post)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodDelete, "/post/delete", body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
+
+func getPostStatus(code int) error {
+	switch code {
+	case 204:
+		return nil
+	case 400:
+		return entity.ErrBadRequest
+	case 403:
+		return entity.ErrForbidden
+	case 404:
+		return entity.ErrNotFound
+	case 408:
+		return entity.ErrRequestTimeout
+	default:
+		return entity.ErrInternalServer
+	}
+}
+
 func (f *ForumUsecase) StoreComment(ctx context.Context, comment entity.Comment, resChan chan entity.Result) {
 	body, err := json.Marshal(comment)
 	if err != nil {
 		resChan <- entity.Result{Err: entity.ErrInternalServer}
 		return
 	}
-	response, err := getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/comments/save", body)
+	response, err := f.app.Do(ctx, http.MethodPost, "/comments/save", body)
 	if err != nil {
 		resChan <- entity.Result{Err: entity.ErrInternalServer}
 		return
@@ -106,6 +160,10 @@ func (f *ForumUsecase) StoreComment(ctx context.Context, comment entity.Comment,
 		resChan <- entity.Result{Err: entity.ErrRequestTimeout}
 	case 400:
 		resChan <- entity.Result{Err: entity.ErrBadRequest}
+	case 403:
+		resChan <- entity.Result{Err: entity.ErrForbidden}
+	case 404:
+		resChan <- entity.Result{Err: entity.ErrNotFound}
 	case 201:
 		resComment := getComment(response.Body)
 		if resComment.Err != nil {
@@ -120,7 +178,7 @@ func (f *ForumUsecase) StoreComment(ctx context.Context, comment entity.Comment,
 }
 
 func (f *ForumUsecase) FetchUsers(ctx context.Context, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, "http://localhost:8080/users", []byte{})
+	response, err := f.app.Do(ctx, http.MethodGet, "/users", []byte{})
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -141,7 +199,7 @@ func (f *ForumUsecase) FetchUsers(ctx context.Context, responseChan chan entity.
 }
 
 func (f *ForumUsecase) FetchUser(ctx context.Context, id int, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/user?id=%d", id), []byte{})
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/user?id=%d", id), []byte{})
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -163,8 +221,12 @@ func (f *ForumUsecase) FetchUser(ctx context.Context, id int, responseChan chan
 	}
 }
 
-func (f *ForumUsecase) FetchCategories(ctx context.Context, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, "http://localhost:8080/categories", []byte{})
+func (f *ForumUsecase) FetchCategories(ctx context.Context, archived bool, responseChan chan entity.Response) {
+	path := "/categories"
+	if archived {
+		path += "?archived=1"
+	}
+	response, err := f.app.Do(ctx, http.MethodGet, path, []byte{})
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -184,8 +246,8 @@ func (f *ForumUsecase) FetchCategories(ctx context.Context, responseChan chan en
 	}
 }
 
-func (f *ForumUsecase) FetchCategory(ctx context.Context, id int, responseChan chan entity.Response) {
-	response, err := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/category?id=%d", id), nil)
+func (f *ForumUsecase) FetchCategory(ctx context.Context, id int, page entity.Page, responseChan chan entity.Response) {
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/category?id=%d&%s", id, page.Query()), nil)
 	if err != nil {
 		responseChan <- entity.Response{Err: entity.ErrInternalServer}
 		return
@@ -193,6 +255,8 @@ func (f *ForumUsecase) FetchCategory(ctx context.Context, id int, responseChan c
 	switch response.StatusCode {
 	case 408:
 		responseChan <- entity.Response{Err: entity.ErrRequestTimeout}
+	case 400:
+		responseChan <- entity.Response{Err: entity.ErrBadRequest}
 	case 200:
 		result, err := getResponse(response.Body)
 		if err != nil {
@@ -208,7 +272,7 @@ func (f *ForumUsecase) FetchCategory(ctx context.Context, id int, responseChan c
 }
 
 func (f *ForumUsecase) PostReaction(ctx context.Context, reaction entity.PostReaction, errorChan chan error) {
-	response, _ := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/post_reactions?id=%d", reaction.Post.Id), nil)
+	response, _ := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/post_reactions?id=%d", reaction.Post.Id), nil)
 	res := getReactions(response.Body)
 	body, err := json.Marshal(reaction)
 	if err != nil {
@@ -218,10 +282,10 @@ func (f *ForumUsecase) PostReaction(ctx context.Context, reaction entity.PostRea
 	for _, i := range res.Reactions {
 		if i.User.Id == reaction.Reaction.User.Id {
 			if i.Like == reaction.Reaction.Like {
-				response, _ = getAPIResponse(ctx, http.MethodDelete, "http://localhost:8080/post_reactions/delete", body)
+				response, _ = f.app.Do(ctx, http.MethodDelete, "/post_reactions/delete", body)
 
 			} else {
-				response, err = getAPIResponse(ctx, http.MethodPut, "http://localhost:8080/post_reactions/update", body)
+				response, err = f.app.Do(ctx, http.MethodPut, "/post_reactions/update", body)
 			}
 			switch response.StatusCode {
 			case 408:
@@ -236,7 +300,7 @@ func (f *ForumUsecase) PostReaction(ctx context.Context, reaction entity.PostRea
 			return
 		}
 	}
-	response, _ = getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/post_reactions/save", body)
+	response, _ = f.app.Do(ctx, http.MethodPost, "/post_reactions/save", body)
 	switch response.StatusCode {
 	case 408:
 		errorChan <- entity.ErrRequestTimeout
@@ -250,7 +314,7 @@ func (f *ForumUsecase) PostReaction(ctx context.Context, reaction entity.PostRea
 }
 
 func (f *ForumUsecase) CommentReaction(ctx context.Context, reaction entity.CommentReaction, errorChan chan error) {
-	response, _ := getAPIResponse(ctx, http.MethodGet, fmt.Sprintf("http://localhost:8080/comment_reactions?id=%d", reaction.Comment.Id), nil)
+	response, _ := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/comment_reactions?id=%d", reaction.Comment.Id), nil)
 	res := getReactions(response.Body)
 	body, err := json.Marshal(reaction)
 	if err != nil {
@@ -260,10 +324,10 @@ func (f *ForumUsecase) CommentReaction(ctx context.Context, reaction entity.Comm
 	for _, i := range res.Reactions {
 		if i.User.Id == reaction.Reaction.User.Id {
 			if i.Like == reaction.Reaction.Like {
-				response, _ = getAPIResponse(ctx, http.MethodDelete, "http://localhost:8080/comment_reactions/delete", body)
+				response, _ = f.app.Do(ctx, http.MethodDelete, "/comment_reactions/delete", body)
 
 			} else {
-				response, err = getAPIResponse(ctx, http.MethodPut, "http://localhost:8080/comment_reactions/update", body)
+				response, err = f.app.Do(ctx, http.MethodPut, "/comment_reactions/update", body)
 			}
 			switch response.StatusCode {
 			case 408:
@@ -278,7 +342,7 @@ func (f *ForumUsecase) CommentReaction(ctx context.Context, reaction entity.Comm
 			return
 		}
 	}
-	response, _ = getAPIResponse(ctx, http.MethodPost, "http://localhost:8080/comment_reactions/save", body)
+	response, _ = f.app.Do(ctx, http.MethodPost, "/comment_reactions/save", body)
 	switch response.StatusCode {
 	case 408:
 		errorChan <- entity.ErrRequestTimeout
@@ -290,3 +354,258 @@ func (f *ForumUsecase) CommentReaction(ctx context.Context, reaction entity.Comm
 		errorChan <- entity.ErrInternalServer
 	}
 }
+
+func (f *ForumUsecase) Search(ctx context.Context, query url.Values, responseChan chan entity.Response) {
+	form := map[string]string{}
+	for key := range query {
+		form[key] = query.Get(key)
+	}
+	body := map[string]interface{}{"form": form}
+	categoriesChan := make(chan entity.Response)
+	go f.FetchCategories(ctx, false, categoriesChan)
+	categories := <-categoriesChan
+	if categories.Err != nil {
+		responseChan <- entity.Response{Err: categories.Err}
+		return
+	}
+	body["categories"] = categories.Body
+	if query.Get("q") == "" {
+		responseChan <- entity.Response{Body: body}
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodGet, "/search?"+query.Encode(), nil)
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	switch response.StatusCode {
+	case 408:
+		responseChan <- entity.Response{Err: entity.ErrRequestTimeout}
+	case 400:
+		responseChan <- entity.Response{Err: entity.ErrBadRequest}
+	case 200:
+		result, err := getResponse(response.Body)
+		if err != nil {
+			responseChan <- entity.Response{Err: entity.ErrInternalServer}
+			return
+		}
+		body["search"] = result.Body
+		if search, ok := result.Body.(map[string]interface{}); ok && search["next_offset"] != nil {
+			query.Set("offset", fmt.Sprint(search["next_offset"]))
+			body["next"] = "/search?" + query.Encode()
+		}
+		responseChan <- entity.Response{Body: body}
+	default:
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+	}
+}
+
+func (f *ForumUsecase) FetchModerationQueue(ctx context.Context, moderatorId int64, responseChan chan entity.Response) {
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/moderation/queue?moderator_id=%d", moderatorId), nil)
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	switch response.StatusCode {
+	case 408:
+		responseChan <- entity.Response{Err: entity.ErrRequestTimeout}
+	case 403:
+		responseChan <- entity.Response{Err: entity.ErrForbidden}
+	case 200:
+		result, err := getResponse(response.Body)
+		if err != nil {
+			responseChan <- entity.Response{Err: entity.ErrInternalServer}
+			return
+		}
+		responseChan <- result
+	default:
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+	}
+}
+
+func (f *ForumUsecase) Moderate(ctx context.Context, action entity.ModerationAction, errChan chan error) {
+	body, err := json.Marshal(action)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPost, "/moderation/action", body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
+
+func (f *ForumUsecase) UpdateRole(ctx context.Context, update entity.RoleUpdate, errChan chan error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPut, "/user/role", body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
+
+// Report reads a 400 as a repeated report, the form has been validated by then
+func (f *ForumUsecase) Report(ctx context.Context, report entity.Report, resChan chan entity.ReportResult) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		resChan <- entity.ReportResult{Err: entity.ErrInternalServer}
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPost, "/reports/save", body)
+	if err != nil {
+		resChan <- entity.ReportResult{Err: entity.ErrInternalServer}
+		return
+	}
+	switch response.StatusCode {
+	case 201:
+		result, err := getResponse(response.Body)
+		if err != nil {
+			resChan <- entity.ReportResult{Err: entity.ErrInternalServer}
+			return
+		}
+		outcome, _ := result.Body.(map[string]interface{})
+		hidden, _ := outcome["hidden"].(bool)
+		resChan <- entity.ReportResult{Hidden: hidden}
+	case 400:
+		resChan <- entity.ReportResult{Err: entity.ErrAlreadyReported}
+	default:
+		resChan <- entity.ReportResult{Err: getPostStatus(response.StatusCode)}
+	}
+}
+
+func (f *ForumUsecase) StoreCategory(ctx context.Context, change entity.CategoryChange, errChan chan error) {
+	body, err := json.Marshal(change)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPost, "/category/save", body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	if response.StatusCode == 201 {
+		errChan <- nil
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
+
+func (f *ForumUsecase) UpdateCategory(ctx context.Context, change entity.CategoryChange, errChan chan error) {
+	errChan <- f.putCategoryChange(ctx, "/category/update", change)
+}
+
+func (f *ForumUsecase) ArchiveCategory(ctx context.Context, change entity.CategoryChange, errChan chan error) {
+	errChan <- f.putCategoryChange(ctx, "/category/archive", change)
+}
+
+// MoveCategory moves the category one place up or down among its siblings
+func (f *ForumUsecase) MoveCategory(ctx context.Context, change entity.CategoryChange, up bool, errChan chan error) {
+	response, err := f.app.Do(ctx, http.MethodGet, "/categories?archived=1", nil)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	if response.StatusCode != 200 {
+		errChan <- getPostStatus(response.StatusCode)
+		return
+	}
+	result := struct {
+		Body []entity.CategoryNode `json:"body"`
+	}{}
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	parentId, order, ok := entity.MoveCategory(result.Body, change.Id, up)
+	if order == nil {
+		errChan <- entity.ErrNotFound
+		return
+	}
+	if !ok {
+		errChan <- nil
+		return
+	}
+	change.ParentId, change.Order = parentId, order
+	errChan <- f.putCategoryChange(ctx, "/category/reorder", change)
+}
+
+func (f *ForumUsecase) putCategoryChange(ctx context.Context, path string, change entity.CategoryChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	response, err := f.app.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return entity.ErrInternalServer
+	}
+	return getPostStatus(response.StatusCode)
+}
+
+func (f *ForumUsecase) FetchNotifications(ctx context.Context, userId int64, offset int, responseChan chan entity.Response) {
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/notifications?user_id=%d&offset=%d", userId, offset), nil)
+	if err != nil {
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+		return
+	}
+	switch response.StatusCode {
+	case 408:
+		responseChan <- entity.Response{Err: entity.ErrRequestTimeout}
+	case 200:
+		result, err := getResponse(response.Body)
+		if err != nil {
+			responseChan <- entity.Response{Err: entity.ErrInternalServer}
+			return
+		}
+		responseChan <- result
+	default:
+		responseChan <- entity.Response{Err: entity.ErrInternalServer}
+	}
+}
+
+func (f *ForumUsecase) CountUnread(ctx context.Context, userId int64, unreadChan chan entity.UnreadResult) {
+	response, err := f.app.Do(ctx, http.MethodGet, fmt.Sprintf("/notifications/unread?user_id=%d", userId), nil)
+	if err != nil {
+		unreadChan <- entity.UnreadResult{Err: entity.ErrInternalServer}
+		return
+	}
+	if response.StatusCode != 200 {
+		unreadChan <- entity.UnreadResult{Err: getPostStatus(response.StatusCode)}
+		return
+	}
+	result := struct {
+		Body struct {
+			Unread int `json:"unread"`
+		} `json:"body"`
+	}{}
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		unreadChan <- entity.UnreadResult{Err: entity.ErrInternalServer}
+		return
+	}
+	unreadChan <- entity.UnreadResult{Unread: result.Body.Unread}
+}
+
+func (f *ForumUsecase) MarkNotificationsRead(ctx context.Context, read entity.NotificationRead, errChan chan error) {
+	path := "/notifications/read"
+	if read.Id == 0 {
+		path = "/notifications/read_all"
+	}
+	body, err := json.Marshal(read)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	response, err := f.app.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		errChan <- entity.ErrInternalServer
+		return
+	}
+	errChan <- getPostStatus(response.StatusCode)
+}
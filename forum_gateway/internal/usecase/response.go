@@ -1,23 +1,11 @@
 package usecase
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"forum_gateway/internal/entity"
 	"io"
-	"net/http"
 )
 
-func getAPIResponse(ctx context.Context, method string, url string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	client := http.Client{}
-	return client.Do(req)
-}
-
 func getResponse(response io.ReadCloser) (entity.Response, error) {
 	result := entity.Response{}
 	err := json.NewDecoder(response).Decode(&result)
@@ -38,6 +26,20 @@ func getSession(response io.ReadCloser) (entity.Session, error) {
 	return session, err
 }
 
+func getPendingSignIn(response io.ReadCloser) (entity.PendingSignIn, error) {
+	temp, err := getResponse(response)
+	if err != nil {
+		return entity.PendingSignIn{}, err
+	}
+	jsonPending, err := json.Marshal(temp.Body)
+	if err != nil {
+		return entity.PendingSignIn{}, err
+	}
+	pending := entity.PendingSignIn{}
+	err = json.Unmarshal(jsonPending, &pending)
+	return pending, err
+}
+
 func getPost(response io.ReadCloser) entity.PostResult {
 	temp, err := getResponse(response)
 	if err != nil {
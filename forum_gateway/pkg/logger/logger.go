@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level orders entries by importance, a Logger drops the entries below its level
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Logger writes entries as JSON lines, with the time, the level, the message, the caller and the request ID
+// of the context before the fields of the entry
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	min    Level
+	fields []interface{}
+}
+
+func New(out io.Writer, min Level) *Logger {
+	return &Logger{mu: &sync.Mutex{}, out: out, min: min}
+}
+
+// With returns a Logger that adds the key value pairs kv to every entry
+func (l *Logger) With(kv ...interface{}) *Logger {
+	with := *l
+	with.fields = append(append([]interface{}{}, l.fields...), kv...)
+	return &with
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	l.Output(ctx, 2, LevelDebug, msg, kv...)
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	l.Output(ctx, 2, LevelInfo, msg, kv...)
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	l.Output(ctx, 2, LevelWarn, msg, kv...)
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	l.Output(ctx, 2, LevelError, msg, kv...)
+}
+
+// Output writes an entry, calldepth counts the frames up to the caller like in log.Output
+func (l *Logger) Output(ctx context.Context, calldepth int, level Level, msg string, kv ...interface{}) {
+	if level < l.min {
+		return
+	}
+	caller := ""
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		caller = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	l.write(level, strings.TrimRight(msg, "\n"), caller, RequestID(ctx), kv)
+}
+
+func (l *Logger) write(level Level, msg, caller, requestId string, kv []interface{}) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	writeField(buf, "time", time.Now().UTC().Format(time.RFC3339Nano))
+	writeField(buf, "level", level.String())
+	writeField(buf, "msg", msg)
+	if caller != "" {
+		writeField(buf, "caller", caller)
+	}
+	if requestId != "" {
+		writeField(buf, "request_id", requestId)
+	}
+	fields := append(append([]interface{}{}, l.fields...), kv...)
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		if i+1 == len(fields) {
+			writeField(buf, "!BADKEY", key)
+			break
+		}
+		writeField(buf, key, fields[i+1])
+	}
+	buf.WriteString("}\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(v)
+}
+
+// Std returns a log.Logger that writes its lines as entries of level, for the code that has no context
+func (l *Logger) Std(level Level) *log.Logger {
+	return log.New(stdWriter{l, level}, "", log.Lshortfile)
+}
+
+type stdWriter struct {
+	l     *Logger
+	level Level
+}
+
+// Write splits the "file.go:12: " prefix of log.Lshortfile off into the caller
+func (w stdWriter) Write(p []byte) (int, error) {
+	if w.level < w.l.min {
+		return len(p), nil
+	}
+	msg, caller := string(p), ""
+	if i := strings.Index(msg, ": "); i > 0 {
+		caller, msg = msg[:i], msg[i+2:]
+	}
+	w.l.write(w.level, strings.TrimRight(msg, "\n"), caller, "", nil)
+	return len(p), nil
+}
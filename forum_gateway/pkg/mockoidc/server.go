@@ -0,0 +1,262 @@
+// Package mockoidc is a minimal OpenID Connect provider for trying out and testing the sign in of the gateway
+// without a real provider. It approves every authorization request for its single user.
+package mockoidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// User is who signs in through the server
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type Server struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	User         User
+	mu           sync.Mutex
+	key          *rsa.PrivateKey
+	keyId        string
+	rotations    int
+	codes        map[string]authRequest
+	tokens       map[string]bool
+}
+
+type authRequest struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	expires     time.Time
+}
+
+func New(issuer, clientId, clientSecret string, user User) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		User:         user,
+		key:          key,
+		keyId:        "mockoidc",
+		codes:        map[string]authRequest{},
+		tokens:       map[string]bool{},
+	}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/userinfo", s.userinfo)
+	return mux
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"userinfo_endpoint":                     s.Issuer + "/userinfo",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize approves the request straight away and sends the browser back with a code
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" || q.Get("client_id") != s.ClientId || q.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge") != "" && q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "unsupported code challenge method", http.StatusBadRequest)
+		return
+	}
+	code := randomHex()
+	s.mu.Lock()
+	s.codes[code] = authRequest{
+		redirectURI: q.Get("redirect_uri"),
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		expires:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+	v := redirect.Query()
+	v.Set("code", code)
+	v.Set("state", q.Get("state"))
+	redirect.RawQuery = v.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+	r.ParseForm()
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != s.ClientId || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	s.mu.Lock()
+	req, found := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+	if !found || time.Now().After(req.expires) || req.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if req.challenge != "" {
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	}
+	idToken, err := s.Sign(s.Claims(req.nonce))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken := randomHex()
+	s.mu.Lock()
+	s.tokens[accessToken] = true
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	pub, kid := s.key.PublicKey, s.keyId
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	valid := s.tokens[token]
+	s.mu.Unlock()
+	if !valid {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.claims())
+}
+
+func (s *Server) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":            s.Issuer,
+		"sub":            s.User.Subject,
+		"email":          s.User.Email,
+		"email_verified": s.User.EmailVerified,
+		"name":           s.User.Name,
+	}
+}
+
+// Claims are those of the ID token of the user for the client, valid for 5 minutes. Tests change them to sign
+// tokens that must be refused
+func (s *Server) Claims(nonce string) map[string]interface{} {
+	now := time.Now()
+	claims := s.claims()
+	claims["aud"] = s.ClientId
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(5 * time.Minute).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	return claims
+}
+
+// RotateKey replaces the signing key by a new one with another key ID, the old one leaves the key set
+func (s *Server) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotations++
+	s.key, s.keyId = key, "mockoidc-"+strconv.Itoa(s.rotations)
+	return nil
+}
+
+// Sign makes an ID token of the claims with the current key
+func (s *Server) Sign(claims map[string]interface{}) (string, error) {
+	s.mu.Lock()
+	key, kid := s.key, s.keyId
+	s.mu.Unlock()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}